@@ -108,6 +108,12 @@ func findConfigserverHttpPort() int {
 	return findConfigserverRpcPort() + 1
 }
 
+// Compute the port number where a config server running
+// on this host should serve its REST API.
+func VespaConfigserverHttpPort() int {
+	return findConfigserverHttpPort()
+}
+
 // Find the RPC addresses to configservers that are configured.
 // Returns a list of RPC specs in the format tcp/{hostname}:{portnumber}
 func VespaConfigserverRpcAddrs() []string {
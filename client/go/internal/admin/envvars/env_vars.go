@@ -7,6 +7,7 @@ package envvars
 
 const (
 	ADDR_CONFIGSERVER                      = "addr_configserver"
+	ASAN_OPTIONS                           = "ASAN_OPTIONS"
 	CONFIGPROXY_RPC_PORT                   = "port_configproxy_rpc"
 	CONFIGSERVER_RPC_PORT                  = "port_configserver_rpc"
 	DEBUG_JVM_STARTUP                      = "DEBUG_JVM_STARTUP"
@@ -32,7 +33,11 @@ const (
 	TERM                                   = "TERM"
 	TRACE_JVM_STARTUP                      = "TRACE_JVM_STARTUP"
 	TRACE_STARTUP                          = "TRACE_STARTUP"
+	TSAN_OPTIONS                           = "TSAN_OPTIONS"
+	UBSAN_OPTIONS                          = "UBSAN_OPTIONS"
+	VESPA_AFFINITY_CPU_LIST                = "VESPA_AFFINITY_CPU_LIST"
 	VESPA_AFFINITY_CPU_SOCKET              = "VESPA_AFFINITY_CPU_SOCKET"
+	VESPA_AFFINITY_MEM_NODES               = "VESPA_AFFINITY_MEM_NODES"
 	VESPA_ALREADY_SWITCHED_USER_TO         = "VESPA_ALREADY_SWITCHED_USER_TO"
 	VESPA_CLI_API_KEY_FILE                 = "VESPA_CLI_API_KEY_FILE"
 	VESPA_CLI_API_KEY                      = "VESPA_CLI_API_KEY"
@@ -50,8 +55,10 @@ const (
 	VESPA_CONFIGSERVER_JVMARGS             = "VESPA_CONFIGSERVER_JVMARGS"
 	VESPA_CONFIGSERVER_MULTITENANT         = "VESPA_CONFIGSERVER_MULTITENANT"
 	VESPA_CONFIGSERVERS                    = "VESPA_CONFIGSERVERS"
+	VESPA_CONFIGSERVER_START_TIMEOUT       = "VESPA_CONFIGSERVER_START_TIMEOUT"
 	VESPA_CONTAINER_JVMARGS                = "VESPA_CONTAINER_JVMARGS"
 	VESPA_GROUP                            = "VESPA_GROUP"
+	VESPA_HEAPTRACK_OPT                    = "VESPA_HEAPTRACK_OPT"
 	VESPA_HOME                             = "VESPA_HOME"
 	VESPA_HOSTNAME                         = "VESPA_HOSTNAME"
 	VESPA_LOAD_CODE_AS_HUGEPAGES           = "VESPA_LOAD_CODE_AS_HUGEPAGES"
@@ -65,6 +72,7 @@ const (
 	VESPA_MALLOC_MADVISE_LIMIT             = "VESPA_MALLOC_MADVISE_LIMIT"
 	VESPA_NO_NUMACTL                       = "VESPA_NO_NUMACTL"
 	VESPA_ONLY_IP_V6_NETWORKING            = "VESPA_ONLY_IP_V6_NETWORKING"
+	VESPA_PERF_OPT                         = "VESPA_PERF_OPT"
 	VESPA_PORT_BASE                        = "VESPA_PORT_BASE"
 	VESPA_SERVICE_NAME                     = "VESPA_SERVICE_NAME"
 	VESPA_TIMER_HZ                         = "VESPA_TIMER_HZ"
@@ -75,11 +83,16 @@ const (
 	VESPA_TLS_HOSTNAME_VALIDATION_DISABLED = "VESPA_TLS_HOSTNAME_VALIDATION_DISABLED"
 	VESPA_TLS_INSECURE_MIXED_MODE          = "VESPA_TLS_INSECURE_MIXED_MODE"
 	VESPA_TLS_PRIVATE_KEY                  = "VESPA_TLS_PRIVATE_KEY"
+	VESPA_TRACE_FORMAT                     = "VESPA_TRACE_FORMAT"
+	VESPA_TRACE_LOG_FILE                   = "VESPA_TRACE_LOG_FILE"
+	VESPA_USE_HEAPTRACK                    = "VESPA_USE_HEAPTRACK"
 	VESPA_USE_HUGEPAGES_LIST               = "VESPA_USE_HUGEPAGES_LIST"
 	VESPA_USE_HUGEPAGES                    = "VESPA_USE_HUGEPAGES"
 	VESPA_USE_MADVISE_LIST                 = "VESPA_USE_MADVISE_LIST"
 	VESPA_USE_NO_VESPAMALLOC               = "VESPA_USE_NO_VESPAMALLOC"
+	VESPA_USE_PERF                         = "VESPA_USE_PERF"
 	VESPA_USER                             = "VESPA_USER"
+	VESPA_USE_SANITIZER                    = "VESPA_USE_SANITIZER"
 	VESPA_USE_VALGRIND                     = "VESPA_USE_VALGRIND"
 	VESPA_USE_VESPAMALLOC_DST              = "VESPA_USE_VESPAMALLOC_DST"
 	VESPA_USE_VESPAMALLOC_D                = "VESPA_USE_VESPAMALLOC_D"
@@ -0,0 +1,97 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+package prog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vespa-engine/vespa/client/go/internal/admin/trace"
+)
+
+type NumaNode struct {
+	Id   int
+	Cpus []int
+}
+
+// read NUMA topology from /sys/devices/system/node (under rootdir, if non-empty)
+func readNumaTopology(rootdir string) ([]NumaNode, error) {
+	nodeDir := rootdir + "/sys/devices/system/node"
+	dirs, err := filepath.Glob(nodeDir + "/node[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no NUMA nodes found in %s", nodeDir)
+	}
+	result := make([]NumaNode, 0, len(dirs))
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			trace.Debug("ignoring", dir, "=>", err)
+			continue
+		}
+		content, err := os.ReadFile(dir + "/cpulist")
+		if err != nil {
+			return nil, err
+		}
+		cpus, err := parseCpuList(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, fmt.Errorf("bad cpulist in %s: %w", dir, err)
+		}
+		result = append(result, NumaNode{Id: id, Cpus: cpus})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result, nil
+}
+
+// parse list in kernel "cpulist" format, for example "0-3,8,10-11"
+func parseCpuList(s string) ([]int, error) {
+	result := make([]int, 0)
+	if s == "" {
+		return result, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		lo, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, err
+		}
+		hi := lo
+		if isRange {
+			hi, err = strconv.Atoi(last)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if hi < lo {
+			return nil, fmt.Errorf("bad range '%s'", part)
+		}
+		for cpu := lo; cpu <= hi; cpu++ {
+			result = append(result, cpu)
+		}
+	}
+	return result, nil
+}
+
+// find the NUMA nodes containing any of the given CPUs, as a list usable by numactl
+func numaNodesFor(topology []NumaNode, cpus []int) string {
+	wanted := make(map[int]bool)
+	for _, cpu := range cpus {
+		wanted[cpu] = true
+	}
+	nodes := make([]string, 0, len(topology))
+	for _, node := range topology {
+		for _, cpu := range node.Cpus {
+			if wanted[cpu] {
+				nodes = append(nodes, strconv.Itoa(node.Id))
+				break
+			}
+		}
+	}
+	return strings.Join(nodes, ",")
+}
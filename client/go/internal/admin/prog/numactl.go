@@ -6,7 +6,6 @@ package prog
 import (
 	"fmt"
 	"strconv"
-	"strings"
 
 	"github.com/vespa-engine/vespa/client/go/internal/admin/envvars"
 	"github.com/vespa-engine/vespa/client/go/internal/admin/trace"
@@ -15,52 +14,87 @@ import (
 
 const (
 	NUMACTL_PROG = "numactl"
+	TASKSET_PROG = "taskset"
 )
 
 func (p *Spec) ConfigureNumaCtl() {
 	p.shouldUseNumaCtl = false
+	p.shouldUseTaskset = false
 	p.numaSocket = -1
+	p.cpuList = p.ValueFromListEnv(envvars.VESPA_AFFINITY_CPU_LIST)
+	p.memNodes = p.ValueFromListEnv(envvars.VESPA_AFFINITY_MEM_NODES)
 	if p.Getenv(envvars.VESPA_NO_NUMACTL) != "" {
 		return
 	}
-	backticks := osutil.BackTicksIgnoreStderr
-	out, err := backticks.Run(NUMACTL_PROG, "--hardware")
-	trace.Debug("numactl --hardware says:", out)
+	topology, err := readNumaTopology(p.numaRootDir)
 	if err != nil {
-		trace.Trace("numactl error:", err)
-		return
+		trace.Trace("cannot read NUMA topology:", err)
+	} else {
+		trace.Debug("NUMA topology:", topology)
 	}
+	backticks := osutil.BackTicksIgnoreStderr
 	outfoo, errfoo := backticks.Run(NUMACTL_PROG, "--interleave", "all", "echo", "foo")
-	if errfoo != nil {
-		trace.Trace("cannot run with numactl:", errfoo)
+	if errfoo != nil || outfoo != "foo\n" {
+		trace.Trace("cannot run with numactl:", errfoo, outfoo)
+		p.configureTaskset()
 		return
 	}
-	if outfoo != "foo\n" {
-		trace.Trace("bad numactl output:", outfoo)
+	p.shouldUseNumaCtl = true
+	if p.cpuList != "" && p.memNodes == "" {
+		cpus, err := parseCpuList(p.cpuList)
+		if err != nil {
+			trace.Warning("bad cpu list", p.cpuList, "in", envvars.VESPA_AFFINITY_CPU_LIST, "=>", err)
+			p.cpuList = ""
+		} else {
+			p.memNodes = numaNodesFor(topology, cpus)
+			trace.Debug("memory nodes for cpu list", p.cpuList, "=>", p.memNodes)
+		}
+	}
+	if p.cpuList != "" || p.memNodes != "" {
 		return
 	}
-	p.shouldUseNumaCtl = true
 	if affinity := p.Getenv(envvars.VESPA_AFFINITY_CPU_SOCKET); affinity != "" {
-		wantSocket, _ := strconv.Atoi(affinity)
+		wantSocket, err := strconv.Atoi(affinity)
+		if err != nil || wantSocket < 0 {
+			trace.Warning("bad cpu socket", affinity, "in", envvars.VESPA_AFFINITY_CPU_SOCKET, "=> ignored")
+			return
+		}
 		trace.Debug("want socket:", wantSocket)
-		parts := strings.Fields(out)
-		for idx := 0; idx+2 < len(parts); idx++ {
-			if parts[idx] == "available:" && parts[idx+2] == "nodes" {
-				numSockets, _ := strconv.Atoi(parts[idx+1])
-				trace.Debug("numSockets:", numSockets)
-				if numSockets > 1 {
-					p.numaSocket = (wantSocket % numSockets)
-					return
-				}
-			}
+		numSockets := len(topology)
+		trace.Debug("numSockets:", numSockets)
+		if numSockets > 1 {
+			p.numaSocket = topology[wantSocket%numSockets].Id
 		}
 	}
 }
 
+// without numactl, an explicit cpu list can still be applied with taskset
+func (p *Spec) configureTaskset() {
+	if p.cpuList == "" {
+		return
+	}
+	backticks := osutil.BackTicksIgnoreStderr
+	out, err := backticks.Run(TASKSET_PROG, "--cpu-list", p.cpuList, "echo", "foo")
+	if err != nil || out != "foo\n" {
+		trace.Warning("cannot run with taskset:", err, out)
+		return
+	}
+	p.shouldUseTaskset = true
+}
+
 func (p *Spec) prependNumaCtl(args []string) []string {
 	result := make([]string, 0, 5+len(args))
 	result = append(result, NUMACTL_PROG)
-	if p.numaSocket >= 0 {
+	if p.cpuList != "" || p.memNodes != "" {
+		if p.cpuList != "" {
+			result = append(result, fmt.Sprintf("--physcpubind=%s", p.cpuList))
+		}
+		if p.memNodes != "" {
+			result = append(result, fmt.Sprintf("--membind=%s", p.memNodes))
+		} else {
+			result = append(result, "--localalloc")
+		}
+	} else if p.numaSocket >= 0 {
 		result = append(result, fmt.Sprintf("--cpunodebind=%d", p.numaSocket))
 		result = append(result, fmt.Sprintf("--membind=%d", p.numaSocket))
 	} else {
@@ -72,3 +106,10 @@ func (p *Spec) prependNumaCtl(args []string) []string {
 	}
 	return result
 }
+
+func (p *Spec) prependTaskset(args []string) []string {
+	result := make([]string, 0, 3+len(args))
+	result = append(result, TASKSET_PROG, "--cpu-list", p.cpuList)
+	result = append(result, args...)
+	return result
+}
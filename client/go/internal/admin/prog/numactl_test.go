@@ -30,6 +30,7 @@ func TestNumaCtlDetection(t *testing.T) {
 	setup(t, tfn)
 	orig := []string{"/bin/myprog", "-c", "cfgid"}
 	spec := NewSpec(orig)
+	spec.numaRootDir = mockBinParent + "/mock-sysfs/two-nodes"
 
 	useMock("no-numactl", "numactl")
 	spec.ConfigureNumaCtl()
@@ -87,4 +88,80 @@ func TestNumaCtlDetection(t *testing.T) {
 	assert.Equal(t, true, spec.shouldUseNumaCtl)
 	assert.Equal(t, 0, spec.numaSocket)
 
+	// Invalid sockets are ignored
+	for _, socket := range []string{"-1", "-3", "foo"} {
+		t.Setenv("VESPA_AFFINITY_CPU_SOCKET", socket)
+		spec.ConfigureNumaCtl()
+		assert.Equal(t, true, spec.shouldUseNumaCtl)
+		assert.Equal(t, -1, spec.numaSocket, socket)
+		assert.Equal(t, []string{"numactl", "--interleave", "all", "/bin/myprog", "-c", "cfgid"}, spec.EffectiveArgs())
+	}
+}
+
+func TestNumaTopology(t *testing.T) {
+	_, tfn, _, _ := runtime.Caller(0)
+	dir := strings.TrimSuffix(tfn, "/numactl_test.go")
+	topology, err := readNumaTopology(dir + "/mock-sysfs/two-nodes")
+	assert.Nil(t, err)
+	assert.Equal(t, []NumaNode{
+		{Id: 0, Cpus: []int{0, 1, 2, 3, 8, 9, 10, 11}},
+		{Id: 1, Cpus: []int{4, 5, 6, 7, 12, 13, 14, 15}},
+	}, topology)
+	assert.Equal(t, "1", numaNodesFor(topology, []int{5, 6}))
+	assert.Equal(t, "0,1", numaNodesFor(topology, []int{3, 4}))
+	_, err = readNumaTopology(dir + "/mock-sysfs/nonexistent")
+	assert.NotNil(t, err)
+
+	cpus, err := parseCpuList("7,2-4")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{7, 2, 3, 4}, cpus)
+	_, err = parseCpuList("4-2")
+	assert.NotNil(t, err)
+	_, err = parseCpuList("a")
+	assert.NotNil(t, err)
+}
+
+func TestExplicitPlacement(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	_, tfn, _, _ := runtime.Caller(0)
+	setup(t, tfn)
+	orig := []string{"/bin/myprog", "-c", "cfgid"}
+	spec := NewSpec(orig)
+	spec.numaRootDir = mockBinParent + "/mock-sysfs/two-nodes"
+	useMock("good-numactl", "numactl")
+
+	t.Setenv("VESPA_AFFINITY_CPU_SOCKET", "1")
+	t.Setenv("VESPA_AFFINITY_CPU_LIST", "otherprog=0-3 myprog=4-5,12")
+	spec.ConfigureNumaCtl()
+	assert.Equal(t, true, spec.shouldUseNumaCtl)
+	assert.Equal(t, -1, spec.numaSocket)
+	assert.Equal(t, []string{"numactl", "--physcpubind=4-5,12", "--membind=1", "/bin/myprog", "-c", "cfgid"},
+		spec.EffectiveArgs())
+
+	t.Setenv("VESPA_AFFINITY_MEM_NODES", "all=0")
+	spec.ConfigureNumaCtl()
+	assert.Equal(t, []string{"numactl", "--physcpubind=4-5,12", "--membind=0", "/bin/myprog", "-c", "cfgid"},
+		spec.EffectiveArgs())
+
+	t.Setenv("VESPA_AFFINITY_CPU_LIST", "")
+	spec.ConfigureNumaCtl()
+	assert.Equal(t, []string{"numactl", "--membind=0", "/bin/myprog", "-c", "cfgid"},
+		spec.EffectiveArgs())
+
+	t.Setenv("VESPA_AFFINITY_MEM_NODES", "")
+	t.Setenv("VESPA_AFFINITY_CPU_LIST", "myprog=2")
+	useMock("no-numactl", "numactl")
+	useMock("good-taskset", "taskset")
+	spec.ConfigureNumaCtl()
+	assert.Equal(t, false, spec.shouldUseNumaCtl)
+	assert.Equal(t, true, spec.shouldUseTaskset)
+	assert.Equal(t, []string{"taskset", "--cpu-list", "2", "/bin/myprog", "-c", "cfgid"},
+		spec.EffectiveArgs())
+
+	t.Setenv("VESPA_AFFINITY_CPU_LIST", "")
+	spec.ConfigureNumaCtl()
+	assert.Equal(t, false, spec.shouldUseTaskset)
+	assert.Equal(t, orig, spec.EffectiveArgs())
 }
@@ -0,0 +1,152 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+package prog
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/vespa-engine/vespa/client/go/internal/admin/envvars"
+	"github.com/vespa-engine/vespa/client/go/internal/admin/trace"
+	"github.com/vespa-engine/vespa/client/go/internal/osutil"
+	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+)
+
+const (
+	PERF_PROG      = "perf"
+	HEAPTRACK_PROG = "heaptrack"
+)
+
+// directory for profiling output: $VESPA_HOME/tmp/<service>
+func (p *Spec) profilingOutputDir() string {
+	service := p.Getenv(envvars.VESPA_SERVICE_NAME)
+	if service == "" {
+		service = strings.TrimSuffix(p.BaseName, "-bin")
+	}
+	return fmt.Sprintf("%s/tmp/%s", vespa.FindHome(), service)
+}
+
+func (p *Spec) makeProfilingOutputDir() bool {
+	dir := p.profilingOutputDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		trace.Warning("cannot create directory for profiling output:", err)
+		return false
+	}
+	return true
+}
+
+func toolWorks(prog string) bool {
+	backticks := osutil.BackTicksWithStderr
+	out, err := backticks.Run(prog, "--version")
+	if err != nil {
+		trace.Trace("trial run of", prog, "fails:", err, "=>", out)
+		return false
+	}
+	return true
+}
+
+func (p *Spec) ConfigureProfiling() {
+	p.shouldUsePerf = false
+	p.shouldUseHeaptrack = false
+	p.profilingOutput = ""
+	if p.shouldUseValgrind {
+		trace.Trace("use valgrind, so no other profiling:", p.BaseName)
+		return
+	}
+	if p.MatchesListEnv(envvars.VESPA_USE_PERF) {
+		trace.Trace("using perf as", p.Program, "has basename in", envvars.VESPA_USE_PERF)
+		if toolWorks(PERF_PROG) && p.makeProfilingOutputDir() {
+			p.shouldUsePerf = true
+			p.profilingOutput = fmt.Sprintf("%s/perf.%s.data.%d", p.profilingOutputDir(), p.BaseName, os.Getpid())
+			trace.InfoKV("recording with perf", "program", p.BaseName, "output", p.profilingOutput)
+		}
+	} else if p.MatchesListEnv(envvars.VESPA_USE_HEAPTRACK) {
+		trace.Trace("using heaptrack as", p.Program, "has basename in", envvars.VESPA_USE_HEAPTRACK)
+		if toolWorks(HEAPTRACK_PROG) && p.makeProfilingOutputDir() {
+			p.shouldUseHeaptrack = true
+			p.profilingOutput = fmt.Sprintf("%s/heaptrack.%s.%d", p.profilingOutputDir(), p.BaseName, os.Getpid())
+			trace.InfoKV("recording with heaptrack", "program", p.BaseName, "output", p.profilingOutput)
+		}
+	}
+	p.configureSanitizer()
+}
+
+func sanitizerOptionsVar(kind string) string {
+	switch kind {
+	case "asan":
+		return envvars.ASAN_OPTIONS
+	case "tsan":
+		return envvars.TSAN_OPTIONS
+	case "ubsan":
+		return envvars.UBSAN_OPTIONS
+	}
+	return ""
+}
+
+func (p *Spec) configureSanitizer() {
+	p.shouldUseSanitizer = false
+	kind := p.ValueFromListEnv(envvars.VESPA_USE_SANITIZER)
+	if kind == "" {
+		return
+	}
+	optionsVar := sanitizerOptionsVar(kind)
+	if optionsVar == "" {
+		trace.Warning("unknown sanitizer", kind, "in", envvars.VESPA_USE_SANITIZER, "(valid: asan, tsan, ubsan)")
+		return
+	}
+	if p.shouldUsePerf || p.shouldUseHeaptrack {
+		trace.Warning("cannot combine sanitizer with other profiling:", p.BaseName)
+		return
+	}
+	if !p.makeProfilingOutputDir() {
+		return
+	}
+	altBinary := p.Program + "-" + kind
+	if path, err := exec.LookPath(altBinary); err == nil {
+		trace.Trace("using alternative binary:", path)
+		p.Program = altBinary
+		p.Args[0] = altBinary
+	} else {
+		trace.Trace("no alternative binary", altBinary, "assuming", p.Program, "is built with", kind)
+	}
+	p.profilingOutput = fmt.Sprintf("%s/%s.%s", p.profilingOutputDir(), kind, p.BaseName)
+	options := p.Getenv(optionsVar)
+	if !strings.Contains(options, "log_path=") {
+		if options != "" {
+			options += ":"
+		}
+		options += "log_path=" + p.profilingOutput
+	}
+	p.Setenv(optionsVar, options)
+	p.shouldUseSanitizer = true
+	trace.InfoKV("running with sanitizer", "program", p.BaseName, "sanitizer", kind, optionsVar, options)
+}
+
+func (p *Spec) perfOptions() []string {
+	if env := p.Getenv(envvars.VESPA_PERF_OPT); env != "" {
+		return strings.Fields(env)
+	}
+	return []string{"-g", "-F", "99"}
+}
+
+func (p *Spec) prependPerf(args []string) []string {
+	result := make([]string, 0, 10+len(args))
+	result = append(result, PERF_PROG, "record")
+	result = append(result, p.perfOptions()...)
+	result = append(result, "-o", p.profilingOutput, "--")
+	result = append(result, args...)
+	return result
+}
+
+func (p *Spec) prependHeaptrack(args []string) []string {
+	result := make([]string, 0, 10+len(args))
+	result = append(result, HEAPTRACK_PROG)
+	if env := p.Getenv(envvars.VESPA_HEAPTRACK_OPT); env != "" {
+		result = append(result, strings.Fields(env)...)
+	}
+	result = append(result, "-o", p.profilingOutput)
+	result = append(result, args...)
+	return result
+}
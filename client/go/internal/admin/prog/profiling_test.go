@@ -0,0 +1,108 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package prog
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vespa-engine/vespa/client/go/internal/admin/trace"
+)
+
+func setupProfiling(t *testing.T, testFileName string) string {
+	trace.AdjustVerbosity(1)
+	vespaHome := t.TempDir()
+	t.Setenv("VESPA_HOME", vespaHome)
+	t.Setenv("VESPA_SERVICE_NAME", "")
+	t.Setenv("VESPA_USE_VALGRIND", "")
+	mockBinParent = strings.TrimSuffix(testFileName, "/profiling_test.go")
+	tmpBin = t.TempDir() + "/mock.bin.profiling_test"
+	err := os.MkdirAll(tmpBin, 0755)
+	assert.Nil(t, err)
+	t.Setenv("PATH", fmt.Sprintf("%s:%s", tmpBin, os.Getenv("PATH")))
+	return vespaHome
+}
+
+func TestPerfAndHeaptrack(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	_, tfn, _, _ := runtime.Caller(0)
+	vespaHome := setupProfiling(t, tfn)
+	orig := []string{"/opt/vespa/sbin/vespa-proton-bin", "--identity", "foo"}
+	spec := NewSpec(orig)
+	useMock("has-perf", PERF_PROG)
+	useMock("has-heaptrack", HEAPTRACK_PROG)
+
+	t.Setenv("VESPA_USE_PERF", "")
+	t.Setenv("VESPA_USE_HEAPTRACK", "")
+	spec.ConfigureProfiling()
+	assert.Equal(t, false, spec.shouldUsePerf)
+	assert.Equal(t, false, spec.shouldUseHeaptrack)
+	assert.Equal(t, orig, spec.EffectiveArgs())
+
+	t.Setenv("VESPA_USE_PERF", "vespa-proton-bin")
+	spec.ConfigureProfiling()
+	assert.Equal(t, true, spec.shouldUsePerf)
+	outDir := vespaHome + "/tmp/vespa-proton"
+	assert.DirExists(t, outDir)
+	expected := fmt.Sprintf("%s/perf.vespa-proton-bin.data.%d", outDir, os.Getpid())
+	assert.Equal(t, append([]string{"perf", "record", "-g", "-F", "99", "-o", expected, "--"}, orig...),
+		spec.EffectiveArgs())
+
+	t.Setenv("VESPA_SERVICE_NAME", "searchnode")
+	t.Setenv("VESPA_PERF_OPT", "-e cycles")
+	spec.ConfigureProfiling()
+	expected = fmt.Sprintf("%s/tmp/searchnode/perf.vespa-proton-bin.data.%d", vespaHome, os.Getpid())
+	assert.Equal(t, append([]string{"perf", "record", "-e", "cycles", "-o", expected, "--"}, orig...),
+		spec.EffectiveArgs())
+
+	t.Setenv("VESPA_USE_PERF", "")
+	t.Setenv("VESPA_USE_HEAPTRACK", "all")
+	spec.ConfigureProfiling()
+	assert.Equal(t, false, spec.shouldUsePerf)
+	assert.Equal(t, true, spec.shouldUseHeaptrack)
+	expected = fmt.Sprintf("%s/tmp/searchnode/heaptrack.vespa-proton-bin.%d", vespaHome, os.Getpid())
+	assert.Equal(t, append([]string{"heaptrack", "-o", expected}, orig...), spec.EffectiveArgs())
+
+	useMock("no-valgrind", HEAPTRACK_PROG)
+	spec.ConfigureProfiling()
+	assert.Equal(t, false, spec.shouldUseHeaptrack)
+	assert.Equal(t, orig, spec.EffectiveArgs())
+}
+
+func TestSanitizer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	_, tfn, _, _ := runtime.Caller(0)
+	vespaHome := setupProfiling(t, tfn)
+	t.Setenv("VESPA_USE_PERF", "")
+	t.Setenv("VESPA_USE_HEAPTRACK", "")
+	t.Setenv("ASAN_OPTIONS", "")
+	t.Setenv("VESPA_USE_SANITIZER", "other=tsan myprog-bin=asan")
+	useMock("has-perf", "myprog-bin")
+	spec := NewSpec([]string{"myprog-bin", "arg"})
+	spec.ConfigureProfiling()
+	assert.Equal(t, true, spec.shouldUseSanitizer)
+	assert.Equal(t, "myprog-bin", spec.Program)
+	assert.Equal(t, "log_path="+vespaHome+"/tmp/myprog/asan.myprog-bin", spec.Getenv("ASAN_OPTIONS"))
+
+	useMock("has-perf", "myprog-bin-asan")
+	t.Setenv("ASAN_OPTIONS", "detect_leaks=1")
+	spec = NewSpec([]string{"myprog-bin", "arg"})
+	spec.ConfigureProfiling()
+	assert.Equal(t, true, spec.shouldUseSanitizer)
+	assert.Equal(t, []string{"myprog-bin-asan", "arg"}, spec.EffectiveArgs())
+	assert.Equal(t, "detect_leaks=1:log_path="+vespaHome+"/tmp/myprog/asan.myprog-bin", spec.Getenv("ASAN_OPTIONS"))
+	spec.ConfigureVespaMalloc()
+	assert.Equal(t, false, spec.shouldUseVespaMalloc)
+
+	t.Setenv("VESPA_USE_SANITIZER", "all=msan")
+	spec = NewSpec([]string{"myprog-bin", "arg"})
+	spec.ConfigureProfiling()
+	assert.Equal(t, false, spec.shouldUseSanitizer)
+}
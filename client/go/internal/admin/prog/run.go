@@ -8,16 +8,39 @@ import (
 	"github.com/vespa-engine/vespa/client/go/internal/osutil"
 )
 
-func (spec *Spec) Run() error {
-	prog := spec.Program
-	args := spec.Args
+func (spec *Spec) effectiveCommand() (prog string, args []string) {
+	prog = spec.Program
+	args = spec.Args
 	if spec.shouldUseValgrind {
 		args = spec.prependValgrind(args)
 		prog = args[0]
-	} else if spec.shouldUseNumaCtl {
+		return
+	}
+	if spec.shouldUsePerf {
+		args = spec.prependPerf(args)
+		prog = args[0]
+	} else if spec.shouldUseHeaptrack {
+		args = spec.prependHeaptrack(args)
+		prog = args[0]
+	}
+	if spec.shouldUseNumaCtl {
 		args = spec.prependNumaCtl(args)
 		prog = args[0]
+	} else if spec.shouldUseTaskset {
+		args = spec.prependTaskset(args)
+		prog = args[0]
 	}
+	return
+}
+
+// the command line that Run would execute, including any wrapper program
+func (spec *Spec) EffectiveArgs() []string {
+	_, args := spec.effectiveCommand()
+	return args
+}
+
+func (spec *Spec) Run() error {
+	prog, args := spec.effectiveCommand()
 	if spec.shouldUseVespaMalloc {
 		spec.Setenv(envvars.LD_PRELOAD, spec.vespaMallocPreload)
 	}
@@ -13,10 +13,18 @@ type Spec struct {
 	BaseName             string
 	Env                  map[string]string
 	numaSocket           int
+	numaRootDir          string
+	cpuList              string
+	memNodes             string
+	profilingOutput      string
 	shouldUseCallgrind   bool
 	shouldUseValgrind    bool
 	shouldUseNumaCtl     bool
+	shouldUseTaskset     bool
 	shouldUseVespaMalloc bool
+	shouldUsePerf        bool
+	shouldUseHeaptrack   bool
+	shouldUseSanitizer   bool
 	vespaMallocPreload   string
 }
 
@@ -32,6 +40,12 @@ func NewSpec(argv []string) *Spec {
 	return &p
 }
 
+// SetNumaRootDir sets the root directory under which the NUMA topology is read from
+// sys/devices/system/node; by default, this is the real /sys
+func (p *Spec) SetNumaRootDir(dir string) {
+	p.numaRootDir = dir
+}
+
 func baseNameOf(s string) string {
 	idx := strings.LastIndex(s, "/")
 	idx++
@@ -35,6 +35,10 @@ func (p *Spec) ConfigureVespaMalloc() {
 		trace.Trace("use valgrind, so no vespamalloc:", p.BaseName)
 		return
 	}
+	if p.shouldUseHeaptrack || p.shouldUseSanitizer {
+		trace.Trace("use heaptrack or sanitizer, so no vespamalloc:", p.BaseName)
+		return
+	}
 	var useFile string
 	if p.MatchesListEnv(envvars.VESPA_USE_VESPAMALLOC_DST) {
 		useFile = vespaMallocLib("libvespamallocdst16.so")
@@ -5,51 +5,218 @@
 package trace
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/vespa-engine/vespa/client/go/internal/admin/envvars"
 )
 
+type outputFormat int
+
+const (
+	formatVespa outputFormat = iota
+	formatJson
+)
+
+var (
+	envOnce        sync.Once
+	outputMutex    sync.Mutex
+	currentFormat  outputFormat   = formatVespa
+	currentOutput  io.Writer      = os.Stderr
+	logFileOutput  io.WriteCloser = nil
+	logFileService string         = ""
+)
+
+type logEntry struct {
+	Timestamp float64                `json:"timestamp"`
+	Hostname  string                 `json:"host"`
+	Pid       int                    `json:"pid"`
+	Service   string                 `json:"service"`
+	Component string                 `json:"component"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	keyvals   []interface{}
+}
+
 func getComponent() string {
 	s := os.Args[0]
 	parts := strings.Split(s, "/")
 	return parts[len(parts)-1]
 }
 
-// make a vespa-format log line
+// apply VESPA_TRACE_FORMAT and VESPA_TRACE_LOG_FILE, once
+func setupFromEnv() {
+	envOnce.Do(func() {
+		if f := os.Getenv(envvars.VESPA_TRACE_FORMAT); f != "" {
+			if err := setFormat(f); err != nil {
+				fmt.Fprintln(os.Stderr, "Warning:", err)
+			}
+		}
+		if fn := os.Getenv(envvars.VESPA_TRACE_LOG_FILE); fn != "" {
+			if err := logToFile(fn, os.Getenv(envvars.VESPA_SERVICE_NAME)); err != nil {
+				fmt.Fprintln(os.Stderr, "Warning:", err)
+			}
+		}
+	})
+}
 
-func logMessage(l outputLevel, msg string) {
-	out := os.Stderr
-	unixTime := float64(time.Now().UnixMicro()) * 1.0e-6
-	hostname := os.Getenv(envvars.VESPA_HOSTNAME)
-	pid := os.Getpid()
-	service := os.Getenv(envvars.VESPA_SERVICE_NAME)
-	if service == "" {
-		service = "-"
+func setFormat(name string) error {
+	switch name {
+	case "vespa":
+		currentFormat = formatVespa
+	case "json":
+		currentFormat = formatJson
+	default:
+		return fmt.Errorf("unknown trace format '%s' (valid: vespa, json)", name)
 	}
-	component := getComponent()
-	level := "error"
+	return nil
+}
+
+// select format for trace output: "vespa" (the default) or "json" (one JSON object per line)
+func SetFormat(name string) error {
+	setupFromEnv()
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+	return setFormat(name)
+}
+
+// redirect trace output (normally stderr) to the given writer
+func SetOutput(w io.Writer) {
+	setupFromEnv()
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+	currentOutput = w
+}
+
+func logToFile(fileName, service string) error {
+	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot write trace output to %s: %w", fileName, err)
+	}
+	if logFileOutput != nil {
+		logFileOutput.Close()
+	}
+	logFileOutput = f
+	logFileService = service
+	return nil
+}
+
+// in addition to normal trace output, append messages in vespa.log
+// format to the given file, using the given service name
+func LogToFile(fileName, service string) error {
+	setupFromEnv()
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+	return logToFile(fileName, service)
+}
+
+func levelName(l outputLevel) string {
 	switch l {
 	case levelWarning:
-		level = "warning"
+		return "warning"
 	case levelInfo:
-		level = "info"
+		return "info"
 	case levelTrace:
-		level = "info"
-		msg = fmt.Sprintf("[trace] %s", msg)
+		return "trace"
 	case levelDebug:
-		level = "debug"
+		return "debug"
 	case levelSpam:
-		level = "spam"
+		return "spam"
+	}
+	return "error"
+}
+
+func newLogEntry(l outputLevel, msg string, keyvals []interface{}) *logEntry {
+	service := os.Getenv(envvars.VESPA_SERVICE_NAME)
+	if service == "" {
+		service = "-"
+	}
+	e := &logEntry{
+		Timestamp: float64(time.Now().UnixMicro()) * 1.0e-6,
+		Hostname:  os.Getenv(envvars.VESPA_HOSTNAME),
+		Pid:       os.Getpid(),
+		Service:   service,
+		Component: getComponent(),
+		Level:     levelName(l),
+		Message:   strings.TrimSuffix(msg, "\n"),
+		keyvals:   keyvals,
+	}
+	if len(keyvals) > 0 {
+		e.Fields = make(map[string]interface{})
+		for i := 0; i < len(keyvals); i += 2 {
+			key := fmt.Sprint(keyvals[i])
+			if i+1 < len(keyvals) {
+				e.Fields[key] = keyvals[i+1]
+			} else {
+				e.Fields[key] = nil
+			}
+		}
+	}
+	return e
+}
+
+// escape message as required by the vespa.log format
+func escapeMessage(msg string) string {
+	r := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\t", "\\t")
+	return r.Replace(msg)
+}
+
+// make a vespa-format log line
+func (e *logEntry) vespaFormat() string {
+	level := e.Level
+	msg := e.Message
+	if level == "trace" {
+		level = "info"
+		msg = fmt.Sprintf("[trace] %s", msg)
+	}
+	for i := 0; i < len(e.keyvals); i += 2 {
+		if i+1 < len(e.keyvals) {
+			msg = fmt.Sprintf("%s %v=%v", msg, e.keyvals[i], e.keyvals[i+1])
+		} else {
+			msg = fmt.Sprintf("%s %v", msg, e.keyvals[i])
+		}
+	}
+	return fmt.Sprintf("%.6f\t%s\t%d\t%s\t%s\t%s\t%s\n",
+		e.Timestamp, e.Hostname, e.Pid, e.Service, e.Component, level, escapeMessage(msg))
+}
+
+func (e *logEntry) jsonFormat() string {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		// fields with values that cannot be marshalled
+		fields := e.Fields
+		e.Fields = make(map[string]interface{})
+		for k, v := range fields {
+			e.Fields[k] = fmt.Sprint(v)
+		}
+		buf, _ = json.Marshal(e)
+	}
+	return string(buf) + "\n"
+}
+
+func logMessage(l outputLevel, msg string, keyvals ...interface{}) {
+	setupFromEnv()
+	e := newLogEntry(l, msg, keyvals)
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+	switch currentFormat {
+	case formatJson:
+		io.WriteString(currentOutput, e.jsonFormat())
+	default:
+		io.WriteString(currentOutput, e.vespaFormat())
 	}
-	if !strings.HasSuffix(msg, "\n") {
-		msg = msg + "\n"
+	if logFileOutput != nil {
+		if logFileService != "" {
+			e.Service = logFileService
+		}
+		io.WriteString(logFileOutput, e.vespaFormat())
 	}
-	fmt.Fprintf(out, "%.6f\t%s\t%d\t%s\t%s\t%s\t%s",
-		unixTime, hostname, pid, service, component, level, msg)
 }
 
 func LogInfo(msg string) {
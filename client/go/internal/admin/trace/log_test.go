@@ -0,0 +1,78 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resetOutput(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	t.Cleanup(func() {
+		SetOutput(os.Stderr)
+		SetFormat("vespa")
+		if logFileOutput != nil {
+			logFileOutput.Close()
+			logFileOutput = nil
+		}
+	})
+	return &buf
+}
+
+func TestVespaFormat(t *testing.T) {
+	t.Setenv("VESPA_HOSTNAME", "myhost")
+	t.Setenv("VESPA_SERVICE_NAME", "")
+	buf := resetOutput(t)
+	Info("hello", "world")
+	WarningKV("disk\tfull", "free", 42)
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, 3, len(lines))
+	fields := strings.Split(lines[0], "\t")
+	assert.Equal(t, 7, len(fields))
+	assert.Equal(t, "myhost", fields[1])
+	assert.Equal(t, "-", fields[3])
+	assert.Equal(t, "info", fields[5])
+	assert.Equal(t, "hello world", fields[6])
+	fields = strings.Split(lines[1], "\t")
+	assert.Equal(t, "warning", fields[5])
+	assert.Equal(t, "disk\\tfull free=42", fields[6])
+}
+
+func TestJsonFormat(t *testing.T) {
+	t.Setenv("VESPA_HOSTNAME", "myhost")
+	t.Setenv("VESPA_SERVICE_NAME", "foo")
+	buf := resetOutput(t)
+	assert.NotNil(t, SetFormat("xml"))
+	assert.Nil(t, SetFormat("json"))
+	InfoKV("started", "port", 19071, "name", "bar")
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "myhost", entry["host"])
+	assert.Equal(t, "foo", entry["service"])
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "started", entry["message"])
+	assert.Equal(t, map[string]interface{}{"port": 19071.0, "name": "bar"}, entry["fields"])
+	assert.NotZero(t, entry["timestamp"])
+}
+
+func TestLogToFile(t *testing.T) {
+	t.Setenv("VESPA_SERVICE_NAME", "")
+	buf := resetOutput(t)
+	fileName := filepath.Join(t.TempDir(), "vespa.log")
+	assert.Nil(t, LogToFile(fileName, "configserver"))
+	Warning("multi\nline")
+	data, err := os.ReadFile(fileName)
+	assert.Nil(t, err)
+	fields := strings.Split(strings.TrimSuffix(string(data), "\n"), "\t")
+	assert.Equal(t, 7, len(fields))
+	assert.Equal(t, "configserver", fields[3])
+	assert.Equal(t, "multi\\nline", fields[6])
+	assert.True(t, strings.HasSuffix(buf.String(), "\t-\t"+fields[4]+"\twarning\tmulti\\nline\n"))
+}
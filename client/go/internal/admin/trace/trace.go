@@ -57,3 +57,29 @@ func SpamDebug(v ...interface{}) {
 func Warning(v ...interface{}) {
 	outputTracing(levelWarning, v...)
 }
+
+// variants with key/value pairs, as structured fields in JSON
+// output or appended as key=value to the message otherwise
+
+func outputTracingKV(l outputLevel, msg string, keyvals ...interface{}) {
+	if l > currentOutputLevel {
+		return
+	}
+	logMessage(l, msg, keyvals...)
+}
+
+func InfoKV(msg string, keyvals ...interface{}) {
+	outputTracingKV(levelInfo, msg, keyvals...)
+}
+
+func TraceKV(msg string, keyvals ...interface{}) {
+	outputTracingKV(levelTrace, msg, keyvals...)
+}
+
+func DebugKV(msg string, keyvals ...interface{}) {
+	outputTracingKV(levelDebug, msg, keyvals...)
+}
+
+func WarningKV(msg string, keyvals ...interface{}) {
+	outputTracingKV(levelWarning, msg, keyvals...)
+}
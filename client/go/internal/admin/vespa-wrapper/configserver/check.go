@@ -4,7 +4,9 @@
 package configserver
 
 import (
+	"errors"
 	"fmt"
+	"net"
 
 	"github.com/vespa-engine/vespa/client/go/internal/admin/defaults"
 	"github.com/vespa-engine/vespa/client/go/internal/admin/trace"
@@ -22,3 +24,25 @@ func checkIsConfigserver(myname string) {
 	trace.Warning("only these hosts should run a config server:", onlyHosts)
 	osutil.ExitMsg(fmt.Sprintf("this host [%s] should not run a config server", myname))
 }
+
+func checkConfigserverHostsResolve(hosts []string) error {
+	var errs []error
+	for _, hn := range hosts {
+		addrs, err := net.LookupHost(hn)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config server host %s does not resolve: %w", hn, err))
+			continue
+		}
+		trace.Debug("config server host", hn, "resolves to", addrs)
+	}
+	return errors.Join(errs...)
+}
+
+// checks to run before starting the config server
+func preStartChecks(vespaHome, myname string) error {
+	hosts := defaults.VespaConfigserverHosts()
+	return errors.Join(
+		checkConfigserverHostsResolve(hosts),
+		checkZooKeeperDataDir(vespaHome+"/"+ZOOKEEPER_DATA_DIR),
+		checkZooKeeperServerId(vespaHome, myname, hosts))
+}
@@ -0,0 +1,117 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+package configserver
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vespa-engine/vespa/client/go/internal/admin/defaults"
+	"github.com/vespa-engine/vespa/client/go/internal/admin/envvars"
+	"github.com/vespa-engine/vespa/client/go/internal/admin/trace"
+	"github.com/vespa-engine/vespa/client/go/internal/httputil"
+	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+)
+
+const (
+	DEFAULT_START_TIMEOUT = 300 * time.Second
+	HEALTH_POLL_INTERVAL  = 2 * time.Second
+)
+
+func startTimeout() time.Duration {
+	if env := os.Getenv(envvars.VESPA_CONFIGSERVER_START_TIMEOUT); env != "" {
+		secs, err := strconv.Atoi(env)
+		if err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+		trace.Warning("bad value for", envvars.VESPA_CONFIGSERVER_START_TIMEOUT, ":", env)
+	}
+	return DEFAULT_START_TIMEOUT
+}
+
+func healthUrl() string {
+	return fmt.Sprintf("http://localhost:%d/state/v1/health", defaults.VespaConfigserverHttpPort())
+}
+
+func newHealthClient() (httputil.Client, string, error) {
+	client := httputil.NewClient(10 * time.Second)
+	url := healthUrl()
+	tlsConfig, err := vespa.LoadTlsConfig()
+	if err != nil || tlsConfig == nil {
+		return client, url, err
+	}
+	url = "https:" + strings.TrimPrefix(url, "http:")
+	var certs []tls.Certificate
+	if tlsConfig.Files.Certificates != "" {
+		cert, err := tls.LoadX509KeyPair(tlsConfig.Files.Certificates, tlsConfig.Files.PrivateKey)
+		if err != nil {
+			return nil, "", err
+		}
+		certs = append(certs, cert)
+	}
+	var caCert []byte
+	if tlsConfig.Files.CaCertificates != "" {
+		caCert, err = os.ReadFile(tlsConfig.Files.CaCertificates)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	// we always connect to localhost, which is not the name in the certificate
+	httputil.ConfigureTLS(client, certs, caCert, true)
+	return client, url, nil
+}
+
+// returns the status code reported by the state API, for example "up" or "initializing"
+func getHealthStatus(client httputil.Client, url string) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := client.Do(req, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("got status %d from %s", response.StatusCode, url)
+	}
+	var health struct {
+		Status struct {
+			Code string `json:"code"`
+		} `json:"status"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&health); err != nil {
+		return "", err
+	}
+	return health.Status.Code, nil
+}
+
+func waitForConfigserverUp(client httputil.Client, url string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	lastStatus := ""
+	for {
+		status, err := getHealthStatus(client, url)
+		if status == "up" {
+			return nil
+		}
+		if err != nil {
+			trace.Debug("config server not ready:", err)
+		} else if status != lastStatus {
+			trace.Trace("config server status:", status)
+			lastStatus = status
+		}
+		if time.Now().Add(HEALTH_POLL_INTERVAL).After(deadline) {
+			if err != nil {
+				return fmt.Errorf("config server not up after %v: %w", timeout, err)
+			}
+			return fmt.Errorf("config server not up after %v, status is '%s'", timeout, status)
+		}
+		time.Sleep(HEALTH_POLL_INTERVAL)
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package configserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vespa-engine/vespa/client/go/internal/mock"
+)
+
+func TestWaitForConfigserverUp(t *testing.T) {
+	client := &mock.HTTPClient{}
+	url := "http://localhost:19071/state/v1/health"
+	client.NextResponseString(200, `{"status":{"code":"up"}}`)
+	assert.Nil(t, waitForConfigserverUp(client, url, 0))
+	assert.Equal(t, url, client.LastRequest.URL.String())
+
+	client.NextResponseString(200, `{"status":{"code":"initializing"}}`)
+	assert.EqualError(t, waitForConfigserverUp(client, url, 0), "config server not up after 0s, status is 'initializing'")
+
+	client.NextResponseString(503, "")
+	assert.EqualError(t, waitForConfigserverUp(client, url, 0), "config server not up after 0s: got status 503 from "+url)
+}
+
+func TestStartTimeout(t *testing.T) {
+	t.Setenv("VESPA_CONFIGSERVER_START_TIMEOUT", "")
+	assert.Equal(t, DEFAULT_START_TIMEOUT, startTimeout())
+	t.Setenv("VESPA_CONFIGSERVER_START_TIMEOUT", "0")
+	assert.Equal(t, 0.0, startTimeout().Seconds())
+	t.Setenv("VESPA_CONFIGSERVER_START_TIMEOUT", "foo")
+	assert.Equal(t, DEFAULT_START_TIMEOUT, startTimeout())
+}
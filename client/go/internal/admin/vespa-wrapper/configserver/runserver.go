@@ -46,7 +46,7 @@ func (rs *RunServer) WouldRun() bool {
 	return err == nil
 }
 
-func (rs *RunServer) Exec(prog string) {
+func (rs *RunServer) argv(prog string) list.ArrayList[string] {
 	argv := list.ArrayList[string]{
 		PROG_NAME,
 		"-s", rs.ServiceName,
@@ -56,6 +56,17 @@ func (rs *RunServer) Exec(prog string) {
 		prog,
 	}
 	argv.AppendAll(rs.Args...)
-	err := osutil.Execvp(rs.ProgPath(), argv)
+	return argv
+}
+
+func (rs *RunServer) Exec(prog string) {
+	err := osutil.Execvp(rs.ProgPath(), rs.argv(prog))
 	osutil.ExitErr(err)
 }
+
+// run vespa-runserver, which puts the service in the background and returns
+func (rs *RunServer) Start(prog string) error {
+	backticks := osutil.SystemCommand
+	_, err := backticks.Run(rs.ProgPath(), rs.argv(prog)[1:]...)
+	return err
+}
@@ -64,16 +64,40 @@ func JustStartConfigserver() int {
 }
 
 func runConfigserverWithRunserver() int {
-	commonPreChecks()
+	vespaHome := commonPreChecks()
 	vespa.CheckCorrectUser()
+	hname, _ := vespa.FindOurHostname()
+	if err := preStartChecks(vespaHome, hname); err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot start configserver:", err)
+		return 1
+	}
 	rs := RunServer{
 		ServiceName: SERVICE_NAME,
 		Args:        []string{"just-start-configserver"},
 	}
-	if rs.WouldRun() {
+	if !rs.WouldRun() {
+		return 0
+	}
+	timeout := startTimeout()
+	if timeout == 0 {
 		rs.Exec("libexec/vespa/vespa-wrapper")
 		return 1
 	}
+	if err := rs.Start("libexec/vespa/vespa-wrapper"); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not start configserver:", err)
+		return 1
+	}
+	client, url, err := newHealthClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot check configserver health:", err)
+		return 1
+	}
+	fmt.Printf("Waiting up to %v for configserver to be up\n", timeout)
+	if err := waitForConfigserverUp(client, url, timeout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println("Configserver is up")
 	return 0
 }
 
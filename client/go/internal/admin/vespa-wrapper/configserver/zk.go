@@ -4,19 +4,145 @@
 package configserver
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/vespa-engine/vespa/client/go/internal/admin/trace"
-	"github.com/vespa-engine/vespa/client/go/internal/osutil"
 )
 
 const (
 	ZOOKEEPER_LOG_FILE_PREFIX = "logs/vespa/zookeeper.configserver"
+	ZOOKEEPER_DATA_DIR        = "var/zookeeper"
+	ZOOKEEPER_CONFIG_FILE     = "var/zookeeper/conf/zookeeper.cfg"
 )
 
 func removeStaleZkLocks(vespaHome string) {
-	backticks := osutil.BackTicksIgnoreStderr
-	cmd := fmt.Sprintf("rm -f '%s/%s'*lck", vespaHome, ZOOKEEPER_LOG_FILE_PREFIX)
-	trace.Trace("cleaning locks:", cmd)
-	backticks.Run("/bin/sh", "-c", cmd)
+	pattern := fmt.Sprintf("%s/%s*lck", vespaHome, ZOOKEEPER_LOG_FILE_PREFIX)
+	trace.Trace("cleaning locks:", pattern)
+	matches, _ := filepath.Glob(pattern)
+	for _, fn := range matches {
+		if err := os.Remove(fn); err != nil {
+			trace.Warning("could not remove stale lock:", err)
+		}
+	}
+}
+
+// verify that existing ZooKeeper data looks sane; a missing data dir is fine (first start).
+// Empty log or snapshot files only give a warning, as ZooKeeper recovers from these,
+// e.g. after a crash right after creating a new log file.
+func checkZooKeeperDataDir(dataDir string) error {
+	st, err := os.Stat(dataDir)
+	if errors.Is(err, os.ErrNotExist) {
+		trace.Trace("no zookeeper data dir yet:", dataDir)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("zookeeper data dir %s is not a directory", dataDir)
+	}
+	versionDir := dataDir + "/version-2"
+	st, err = os.Stat(versionDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("zookeeper data %s is not a directory", versionDir)
+	}
+	entries, err := os.ReadDir(versionDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "snapshot.") && !strings.HasPrefix(name, "log.") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() == 0 {
+			trace.Warning("empty zookeeper data file", versionDir+"/"+name)
+		}
+	}
+	return nil
+}
+
+// returns the server ID from the "myid" file, or -1 if there is no such file
+func readZooKeeperMyId(dataDir string) (int, error) {
+	fn := dataDir + "/myid"
+	content, err := os.ReadFile(fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || id < 0 {
+		return -1, fmt.Errorf("bad zookeeper server id in %s: '%s'", fn, strings.TrimSpace(string(content)))
+	}
+	return id, nil
+}
+
+// parse "server.N=host:port:port..." lines from a generated zookeeper config file
+func readZooKeeperServers(cfgFile string) (map[string]int, error) {
+	f, err := os.Open(cfgFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	servers := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, found := strings.Cut(line, "=")
+		if !found || !strings.HasPrefix(key, "server.") {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(key, "server."))
+		if err != nil {
+			trace.Debug("ignoring line in", cfgFile, ":", line)
+			continue
+		}
+		host, _, _ := strings.Cut(value, ":")
+		servers[host] = id
+	}
+	return servers, scanner.Err()
+}
+
+// check that our "myid" matches what the ZooKeeper config (or the config server list) says
+func checkZooKeeperServerId(vespaHome, myHost string, configservers []string) error {
+	dataDir := vespaHome + "/" + ZOOKEEPER_DATA_DIR
+	myId, err := readZooKeeperMyId(dataDir)
+	if err != nil || myId < 0 {
+		return err
+	}
+	trace.Debug("zookeeper server id:", myId)
+	servers, err := readZooKeeperServers(vespaHome + "/" + ZOOKEEPER_CONFIG_FILE)
+	if err == nil && len(servers) > 0 {
+		if id, found := servers[myHost]; found && id != myId {
+			return fmt.Errorf("zookeeper server id %d in %s/myid does not match server.%d=%s in %s",
+				myId, dataDir, id, myHost, ZOOKEEPER_CONFIG_FILE)
+		}
+		return nil
+	}
+	if len(configservers) > 1 {
+		for idx, host := range configservers {
+			if host == myHost && idx != myId {
+				trace.Warning("zookeeper server id", myId, "differs from position", idx, "of", myHost, "in config server list")
+			}
+		}
+	}
+	return nil
 }
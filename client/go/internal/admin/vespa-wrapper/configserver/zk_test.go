@@ -0,0 +1,71 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package configserver
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/admin/trace"
+)
+
+func writeFile(t *testing.T, fileName, content string) {
+	require.Nil(t, os.WriteFile(fileName, []byte(content), 0644))
+}
+
+func TestCheckZooKeeperDataDir(t *testing.T) {
+	vespaHome := t.TempDir()
+	dataDir := vespaHome + "/" + ZOOKEEPER_DATA_DIR
+	assert.Nil(t, checkZooKeeperDataDir(dataDir))
+
+	require.Nil(t, os.MkdirAll(dataDir+"/version-2", 0755))
+	writeFile(t, dataDir+"/version-2/snapshot.100", "data")
+	writeFile(t, dataDir+"/version-2/acceptedEpoch", "")
+	assert.Nil(t, checkZooKeeperDataDir(dataDir))
+
+	var buf bytes.Buffer
+	trace.SetOutput(&buf)
+	defer trace.SetOutput(os.Stderr)
+	writeFile(t, dataDir+"/version-2/log.101", "")
+	assert.Nil(t, checkZooKeeperDataDir(dataDir))
+	assert.Contains(t, buf.String(), "empty zookeeper data file "+dataDir+"/version-2/log.101")
+
+	writeFile(t, vespaHome+"/notadir", "")
+	assert.ErrorContains(t, checkZooKeeperDataDir(vespaHome+"/notadir"), "is not a directory")
+}
+
+func TestCheckZooKeeperServerId(t *testing.T) {
+	vespaHome := t.TempDir()
+	dataDir := vespaHome + "/" + ZOOKEEPER_DATA_DIR
+	hosts := []string{"cfg1.example.com", "cfg2.example.com", "cfg3.example.com"}
+	assert.Nil(t, checkZooKeeperServerId(vespaHome, "cfg2.example.com", hosts))
+
+	require.Nil(t, os.MkdirAll(dataDir+"/conf", 0755))
+	writeFile(t, dataDir+"/myid", "x\n")
+	assert.ErrorContains(t, checkZooKeeperServerId(vespaHome, "cfg2.example.com", hosts), "bad zookeeper server id")
+
+	writeFile(t, dataDir+"/myid", "1\n")
+	assert.Nil(t, checkZooKeeperServerId(vespaHome, "cfg2.example.com", hosts))
+
+	writeFile(t, dataDir+"/conf/zookeeper.cfg", `tickTime=2000
+dataDir=/opt/vespa/var/zookeeper
+server.0=cfg1.example.com:2182:2183;2181
+server.2=cfg2.example.com:2182:2183;2181
+server.1=cfg3.example.com:2182:2183;2181
+`)
+	assert.ErrorContains(t, checkZooKeeperServerId(vespaHome, "cfg2.example.com", hosts),
+		"zookeeper server id 1 in "+dataDir+"/myid does not match server.2=cfg2.example.com")
+	assert.Nil(t, checkZooKeeperServerId(vespaHome, "cfg3.example.com", hosts))
+}
+
+func TestRemoveStaleZkLocks(t *testing.T) {
+	vespaHome := t.TempDir()
+	require.Nil(t, os.MkdirAll(vespaHome+"/logs/vespa", 0755))
+	writeFile(t, vespaHome+"/logs/vespa/zookeeper.configserver.0.log.lck", "")
+	writeFile(t, vespaHome+"/logs/vespa/zookeeper.configserver.0.log", "")
+	removeStaleZkLocks(vespaHome)
+	assert.NoFileExists(t, vespaHome+"/logs/vespa/zookeeper.configserver.0.log.lck")
+	assert.FileExists(t, vespaHome+"/logs/vespa/zookeeper.configserver.0.log")
+}
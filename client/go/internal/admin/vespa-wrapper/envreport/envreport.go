@@ -0,0 +1,129 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+// vespa-wrapper env-report command
+
+package envreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/vespa-engine/vespa/client/go/internal/admin/defaults"
+	"github.com/vespa-engine/vespa/client/go/internal/admin/trace"
+	"github.com/vespa-engine/vespa/client/go/internal/build"
+	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+)
+
+type Defaults struct {
+	VespaHome               string   `json:"vespa-home"`
+	VespaUser               string   `json:"vespa-user"`
+	Hostname                string   `json:"hostname"`
+	PortBase                int      `json:"port-base"`
+	ConfigserverRpcAddrs    []string `json:"configserver-rpc-addrs"`
+	ConfigserverRestUrls    []string `json:"configserver-rest-urls"`
+	ConfigproxyRpcAddr      string   `json:"configproxy-rpc-addr"`
+	ContainerWebServicePort int      `json:"web-service-port"`
+}
+
+type Report struct {
+	Environment []vespa.EnvReportEntry `json:"environment"`
+	Defaults    Defaults               `json:"defaults"`
+	Errors      []string               `json:"errors,omitempty"`
+}
+
+func NewEnvReportCmd() *cobra.Command {
+	var jsonOutput bool
+	cmd := &cobra.Command{
+		Use:   "env-report",
+		Short: "show the effective Vespa environment and where it comes from",
+		Long: `Shows environment variables as resolved from default-env.txt
+and the process environment, with the origin of each value,
+and the defaults computed from them`,
+		Version: build.Version,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report := makeReport()
+			if jsonOutput {
+				return writeJson(cmd.OutOrStdout(), report)
+			}
+			writeHuman(cmd.OutOrStdout(), report)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output in JSON format")
+	return cmd
+}
+
+func Run(args []string) int {
+	trace.AdjustVerbosity(-1)
+	cmd := NewEnvReportCmd()
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func makeReport() *Report {
+	report := &Report{}
+	env, err := vespa.DefaultEnvReport()
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("default-env.txt: %v", err))
+	}
+	report.Environment = env
+	// apply the environment, so defaults are computed as for other commands
+	_ = vespa.LoadDefaultEnv()
+	hostname, err := vespa.FindOurHostname()
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("hostname: %v", err))
+	}
+	report.Defaults = Defaults{
+		VespaHome:               defaults.VespaHome(),
+		VespaUser:               vespa.FindVespaUser(),
+		Hostname:                hostname,
+		PortBase:                defaults.VespaPortBase(),
+		ConfigserverRpcAddrs:    defaults.VespaConfigserverRpcAddrs(),
+		ConfigserverRestUrls:    defaults.VespaConfigserverRestUrls(),
+		ConfigproxyRpcAddr:      defaults.VespaConfigProxyRpcAddr(),
+		ContainerWebServicePort: defaults.VespaContainerWebServicePort(),
+	}
+	return report
+}
+
+func writeJson(w io.Writer, report *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func writeHuman(w io.Writer, report *Report) {
+	fmt.Fprintln(w, "Environment:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, entry := range report.Environment {
+		source := entry.Source
+		if entry.IgnoredFallback != "" {
+			source = fmt.Sprintf("%s (ignored fallback: %s)", source, entry.IgnoredFallback)
+		}
+		fmt.Fprintf(tw, "  %s\t[%s]\t%s\n", entry.Name, source, entry.Value)
+	}
+	tw.Flush()
+	d := report.Defaults
+	fmt.Fprintln(w, "Defaults:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  VESPA_HOME\t%s\n", d.VespaHome)
+	fmt.Fprintf(tw, "  user\t%s\n", d.VespaUser)
+	fmt.Fprintf(tw, "  hostname\t%s\n", d.Hostname)
+	fmt.Fprintf(tw, "  port base\t%d\n", d.PortBase)
+	fmt.Fprintf(tw, "  config server RPC\t%s\n", strings.Join(d.ConfigserverRpcAddrs, " "))
+	fmt.Fprintf(tw, "  config server REST\t%s\n", strings.Join(d.ConfigserverRestUrls, " "))
+	fmt.Fprintf(tw, "  config proxy RPC\t%s\n", d.ConfigproxyRpcAddr)
+	fmt.Fprintf(tw, "  web service port\t%d\n", d.ContainerWebServicePort)
+	tw.Flush()
+	for _, msg := range report.Errors {
+		fmt.Fprintln(os.Stderr, "Warning:", msg)
+	}
+}
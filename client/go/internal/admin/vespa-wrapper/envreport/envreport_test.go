@@ -0,0 +1,86 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package envreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+)
+
+func testReport() *Report {
+	return &Report{
+		Environment: []vespa.EnvReportEntry{
+			{Name: "VESPA_CLI_API_KEY", Value: vespa.EnvRedacted, Source: vespa.EnvFromProcess},
+			{Name: "VESPA_HOME", Value: "/opt/vespa", Source: vespa.EnvFromProcess, IgnoredFallback: "/usr/local/vespa"},
+			{Name: "VESPA_USER", Value: "vespa", Source: vespa.EnvFromOverride},
+		},
+		Defaults: Defaults{
+			VespaHome:               "/opt/vespa",
+			VespaUser:               "vespa",
+			Hostname:                "host1.example.com",
+			PortBase:                19000,
+			ConfigserverRpcAddrs:    []string{"tcp/cfg1:19070", "tcp/cfg2:19070"},
+			ConfigserverRestUrls:    []string{"http://cfg1:19071", "http://cfg2:19071"},
+			ConfigproxyRpcAddr:      "tcp/localhost:19090",
+			ContainerWebServicePort: 8080,
+		},
+	}
+}
+
+func TestWriteHuman(t *testing.T) {
+	var buf bytes.Buffer
+	writeHuman(&buf, testReport())
+	assert.Equal(t, `Environment:
+  VESPA_CLI_API_KEY  [process]                                       (redacted)
+  VESPA_HOME         [process (ignored fallback: /usr/local/vespa)]  /opt/vespa
+  VESPA_USER         [override]                                      vespa
+Defaults:
+  VESPA_HOME          /opt/vespa
+  user                vespa
+  hostname            host1.example.com
+  port base           19000
+  config server RPC   tcp/cfg1:19070 tcp/cfg2:19070
+  config server REST  http://cfg1:19071 http://cfg2:19071
+  config proxy RPC    tcp/localhost:19090
+  web service port    8080
+`, buf.String())
+}
+
+func TestWriteJson(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, writeJson(&buf, testReport()))
+	var report Report
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &report))
+	assert.Equal(t, *testReport(), report)
+	assert.Contains(t, buf.String(), `"ignored-fallback": "/usr/local/vespa"`)
+	assert.Contains(t, buf.String(), `"web-service-port": 8080`)
+}
+
+func TestEnvReportRedactsSecrets(t *testing.T) {
+	vespaHome := t.TempDir()
+	require.Nil(t, os.MkdirAll(vespaHome+"/conf/vespa", 0755))
+	require.Nil(t, os.WriteFile(vespaHome+"/conf/vespa/default-env.txt", []byte("override VESPA_SECRET_STORE_TOKEN \"token from file\"\n"), 0644))
+	t.Setenv("VESPA_HOME", vespaHome)
+	t.Setenv("VESPA_CLI_API_KEY", "my api key")
+	t.Setenv("VESPA_CLI_DATA_PLANE_KEY", "my data plane key")
+	t.Setenv("VESPA_SECRET_STORE_TOKEN", "")
+	for _, args := range [][]string{{}, {"--json"}} {
+		var stdout bytes.Buffer
+		cmd := NewEnvReportCmd()
+		cmd.SetArgs(args)
+		cmd.SetOut(&stdout)
+		require.Nil(t, cmd.Execute())
+		output := stdout.String()
+		assert.Contains(t, output, "VESPA_CLI_API_KEY")
+		assert.Contains(t, output, "VESPA_SECRET_STORE_TOKEN")
+		assert.Contains(t, output, vespa.EnvRedacted)
+		assert.NotContains(t, output, "my api key")
+		assert.NotContains(t, output, "my data plane key")
+		assert.NotContains(t, output, "token from file")
+	}
+}
@@ -32,6 +32,10 @@ func NewLogfmtCmd() *cobra.Command {
 and converts it to something human-readable`,
 		Version: build.Version,
 		Run: func(cmd *cobra.Command, args []string) {
+			if curOptions.Merge && !cmd.Flags().Changed("show") {
+				// show origin of merged lines
+				curOptions.ShowFields.shown["host"] = true
+			}
 			RunLogfmt(&curOptions, args)
 		},
 	}
@@ -50,7 +54,18 @@ and converts it to something human-readable`,
 	cmd.Flags().StringVarP(&curOptions.OnlyHostname, "host", "H", "", "select only one host")
 	cmd.Flags().StringVarP(&curOptions.OnlyPid, "pid", "p", "", "select only one process ID")
 	cmd.Flags().StringVarP(&curOptions.OnlyService, "service", "S", "", "select only one service")
-	cmd.Flags().VarP(&curOptions.Format, "format", "F", "select logfmt output format, vespa (default), json or raw are supported. The json output format is not stable, and will change in the future.")
+	cmd.Flags().VarP(&curOptions.Format, "format", "F", "select logfmt output format: vespa (default), raw, json, json-v1, logfmt, csv or otlp. The json output format is not stable, and will change in the future; json-v1 is stable.")
+	cmd.Flags().Var(&curOptions.FromTime, "from", "select only messages at or after this time (absolute, or relative like 2h for two hours ago)")
+	cmd.Flags().Var(&curOptions.ToTime, "to", "select only messages before this time (absolute, or relative like 30m for 30 minutes ago)")
+	cmd.Flags().BoolVarP(&curOptions.IncludeRotated, "rotated", "r", false, "also read rotated (and compressed) siblings of the default vespa.log")
+	cmd.Flags().BoolVar(&curOptions.Merge, "merge", false, "merge multiple files (for example from several hosts) into one stream ordered by time")
+	cmd.Flags().BoolVar(&curOptions.Stats, "stats", false, "print counts for the selected messages instead of the messages (json with --format json or json-v1)")
+	cmd.Flags().IntVar(&curOptions.StatsTop, "stats-top", curOptions.StatsTop, "number of most frequent messages to include in statistics")
+	cmd.Flags().DurationVar(&curOptions.StatsBucket, "stats-bucket", curOptions.StatsBucket, "size of time buckets used for message rates in statistics")
+	cmd.Flags().StringVar(&curOptions.ShipUrl, "ship", "", "follow vespa.log and POST selected messages as json-v1 lines to this URL")
+	cmd.Flags().StringVar(&curOptions.ShipPositionFile, "ship-position", "", "file storing how far shipping has come (default $VESPA_HOME/var/db/vespa/logfmt-ship.position)")
+	cmd.Flags().IntVar(&curOptions.ShipBatchSize, "ship-batch-size", curOptions.ShipBatchSize, "maximum number of messages per POST when shipping")
+	cmd.Flags().DurationVar(&curOptions.ShipInterval, "ship-interval", curOptions.ShipInterval, "maximum time to hold back messages before shipping them")
 	cmd.Flags().MarkHidden("tc")
 	cmd.Flags().MarkHidden("ts")
 	cmd.Flags().MarkHidden("dequotenewlines")
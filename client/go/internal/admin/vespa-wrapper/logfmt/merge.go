@@ -0,0 +1,220 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+// vespa logfmt command
+
+package logfmt
+
+import (
+	"bufio"
+	"container/heap"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how long lines from followed files are held back to be merged in timestamp order
+const followMergeDelay = 500 * time.Millisecond
+
+// a Tail over a complete input; the channel is closed at end of input
+type readerTail struct {
+	lines chan Line
+}
+
+func (t *readerTail) Lines() chan Line { return t.lines }
+
+func newReaderTail(input io.Reader) Tail {
+	t := &readerTail{lines: make(chan Line, 20)}
+	go func() {
+		defer close(t.lines)
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			t.lines <- Line{Text: scanner.Text()}
+		}
+	}()
+	return t
+}
+
+// short names for the inputs: the file names without their common trailing path
+// components, so "host1/vespa.log" and "host2/vespa.log" become "host1" and "host2"
+func originNames(fileNames []string) []string {
+	split := make([][]string, len(fileNames))
+	minLen := -1
+	for i, fn := range fileNames {
+		split[i] = strings.Split(filepath.ToSlash(filepath.Clean(fn)), "/")
+		if minLen < 0 || len(split[i]) < minLen {
+			minLen = len(split[i])
+		}
+	}
+	common := 0
+	for common < minLen-1 {
+		last := split[0][len(split[0])-1-common]
+		same := true
+		for _, parts := range split[1:] {
+			if parts[len(parts)-1-common] != last {
+				same = false
+			}
+		}
+		if !same {
+			break
+		}
+		common++
+	}
+	result := make([]string, len(fileNames))
+	for i, parts := range split {
+		result[i] = strings.Join(parts[:len(parts)-common], "/")
+	}
+	return result
+}
+
+// host names which do not identify where a line was logged
+var placeholderHosts = map[string]bool{"": true, "-": true, "localhost": true}
+
+// tags placeholder hosts with the origin of the line; other hosts
+// already identify the origin, so all lines are tagged the same way
+// regardless of which inputs have been read so far
+func tagOrigin(line, origin string) string {
+	fields := strings.SplitN(line, "\t", 3)
+	if len(fields) < 3 || !placeholderHosts[fields[1]] {
+		return line
+	}
+	fields[1] = fields[1] + "[" + origin + "]"
+	return strings.Join(fields, "\t")
+}
+
+func lineTimestamp(line string) (float64, bool) {
+	ts, _, _ := strings.Cut(line, "\t")
+	secs, err := strconv.ParseFloat(ts, 64)
+	return secs, err == nil
+}
+
+type mergeItem struct {
+	text    string
+	origin  string
+	ts      float64
+	seq     int64
+	arrival time.Time
+	source  int
+}
+
+type mergeHeap []*mergeItem
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].ts != h[j].ts {
+		return h[i].ts < h[j].ts
+	}
+	// keep lines with equal timestamps from one input together,
+	// so continuation lines stay next to the line they belong to
+	if h[i].source != h[j].source {
+		return h[i].source < h[j].source
+	}
+	return h[i].seq < h[j].seq
+}
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*mergeItem)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// keeps lines without a valid timestamp next to the preceding line from the same input
+type timestampTracker []float64
+
+func (tt timestampTracker) timestampFor(source int, line string) float64 {
+	if ts, ok := lineTimestamp(line); ok {
+		tt[source] = ts
+	}
+	return tt[source]
+}
+
+// k-way merge of complete inputs into one Tail, ordered by timestamp
+func mergeTails(sources []Tail, origins []string) Tail {
+	result := &readerTail{lines: make(chan Line, 20)}
+	go func() {
+		defer close(result.lines)
+		lastTs := make(timestampTracker, len(sources))
+		var seq int64
+		h := &mergeHeap{}
+		next := func(source int) {
+			line, ok := <-sources[source].Lines()
+			if !ok {
+				return
+			}
+			seq++
+			ts := lastTs.timestampFor(source, line.Text)
+			heap.Push(h, &mergeItem{text: line.Text, origin: origins[source], ts: ts, seq: seq, source: source})
+		}
+		for i := range sources {
+			next(i)
+		}
+		for h.Len() > 0 {
+			item := heap.Pop(h).(*mergeItem)
+			result.lines <- Line{Text: tagOrigin(item.text, item.origin)}
+			next(item.source)
+		}
+	}()
+	return result
+}
+
+// merge of followed inputs: lines are held back for a short while
+// so that lines arriving close in time can be output in order
+func mergeFollowedTails(sources []Tail, origins []string, delay time.Duration) Tail {
+	result := &readerTail{lines: make(chan Line, 20)}
+	incoming := make(chan *mergeItem, 20)
+	done := make(chan int)
+	for i, src := range sources {
+		go func(source int, src Tail) {
+			for line := range src.Lines() {
+				incoming <- &mergeItem{text: line.Text, origin: origins[source], source: source, arrival: time.Now()}
+			}
+			done <- source
+		}(i, src)
+	}
+	go func() {
+		defer close(result.lines)
+		lastTs := make(timestampTracker, len(sources))
+		var seq int64
+		h := &mergeHeap{}
+		ticker := time.NewTicker(delay / 5)
+		defer ticker.Stop()
+		add := func(item *mergeItem) {
+			seq++
+			item.seq = seq
+			item.ts = lastTs.timestampFor(item.source, item.text)
+			heap.Push(h, item)
+		}
+		flush := func(all bool) {
+			limit := time.Now().Add(-delay)
+			for h.Len() > 0 && (all || !(*h)[0].arrival.After(limit)) {
+				item := heap.Pop(h).(*mergeItem)
+				result.lines <- Line{Text: tagOrigin(item.text, item.origin)}
+			}
+		}
+		active := len(sources)
+		for active > 0 {
+			select {
+			case item := <-incoming:
+				add(item)
+			case <-done:
+				active--
+			case <-ticker.C:
+				flush(false)
+			}
+		}
+		for {
+			select {
+			case item := <-incoming:
+				add(item)
+			default:
+				flush(true)
+				return
+			}
+		}
+	}()
+	return result
+}
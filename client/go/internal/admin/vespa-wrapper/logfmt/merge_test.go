@@ -0,0 +1,73 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package logfmt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func collect(t Tail) []string {
+	result := make([]string, 0)
+	for line := range t.Lines() {
+		result = append(result, line.Text)
+	}
+	return result
+}
+
+func TestOriginNames(t *testing.T) {
+	assert.Equal(t, []string{"host1", "host2"}, originNames([]string{"host1/vespa.log", "host2/vespa.log"}))
+	assert.Equal(t, []string{"/tmp/a.log", "/tmp/b.log"}, originNames([]string{"/tmp/a.log", "/tmp/b.log"}))
+	assert.Equal(t, []string{"x/a", "a"}, originNames([]string{"x/a/vespa.log", "a/vespa.log"}))
+}
+
+func TestMergeTails(t *testing.T) {
+	first := strings.Join([]string{
+		"1.0\thost1\t1\tsvc\tcomp\tinfo\tone",
+		"3.0\thost1\t1\tsvc\tcomp\tinfo\tthree",
+		"continuation without timestamp",
+		"5.0\tlocalhost\t1\tsvc\tcomp\tinfo\tfive",
+	}, "\n")
+	second := strings.Join([]string{
+		"2.0\thost2\t1\tsvc\tcomp\tinfo\ttwo",
+		"3.0\thost2\t1\tsvc\tcomp\tinfo\tthree again",
+		"4.0\thost1\t1\tsvc\tcomp\tinfo\tfour",
+	}, "\n")
+	merged := mergeTails([]Tail{newReaderTail(strings.NewReader(first)), newReaderTail(strings.NewReader(second))},
+		[]string{"a", "b"})
+	assert.Equal(t, []string{
+		"1.0\thost1\t1\tsvc\tcomp\tinfo\tone",
+		"2.0\thost2\t1\tsvc\tcomp\tinfo\ttwo",
+		"3.0\thost1\t1\tsvc\tcomp\tinfo\tthree",
+		"continuation without timestamp",
+		"3.0\thost2\t1\tsvc\tcomp\tinfo\tthree again",
+		"4.0\thost1\t1\tsvc\tcomp\tinfo\tfour",
+		"5.0\tlocalhost[a]\t1\tsvc\tcomp\tinfo\tfive",
+	}, collect(merged))
+}
+
+type chanTail struct {
+	lines chan Line
+}
+
+func (t *chanTail) Lines() chan Line { return t.lines }
+
+func TestMergeFollowedTails(t *testing.T) {
+	a := &chanTail{lines: make(chan Line, 10)}
+	b := &chanTail{lines: make(chan Line, 10)}
+	merged := mergeFollowedTails([]Tail{a, b}, []string{"a", "b"}, 50*time.Millisecond)
+	b.lines <- Line{Text: "2.0\thost2\t1\tsvc\tcomp\tinfo\ttwo"}
+	a.lines <- Line{Text: "1.0\thost1\t1\tsvc\tcomp\tinfo\tone"}
+	assert.Equal(t, "1.0\thost1\t1\tsvc\tcomp\tinfo\tone", (<-merged.Lines()).Text)
+	assert.Equal(t, "2.0\thost2\t1\tsvc\tcomp\tinfo\ttwo", (<-merged.Lines()).Text)
+	a.lines <- Line{Text: "3.0\thost2\t1\tsvc\tcomp\tinfo\tthree"}
+	b.lines <- Line{Text: "4.0\tlocalhost\t1\tsvc\tcomp\tinfo\tfour"}
+	close(a.lines)
+	close(b.lines)
+	assert.Equal(t, []string{
+		"3.0\thost2\t1\tsvc\tcomp\tinfo\tthree",
+		"4.0\tlocalhost[b]\t1\tsvc\tcomp\tinfo\tfour",
+	}, collect(merged))
+}
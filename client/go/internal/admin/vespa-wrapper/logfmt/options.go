@@ -7,6 +7,9 @@ package logfmt
 import (
 	"fmt"
 	"os"
+	"time"
+
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
 )
 
 // options designed for compatibility with perl version of vespa-logfmt
@@ -24,25 +27,60 @@ type Options struct {
 	TruncateComponent bool
 	ComponentFilter   regexFlag
 	MessageFilter     regexFlag
-	Format            OutputFormat
+	Format            vespalog.Format
+	FromTime          timeFlag
+	ToTime            timeFlag
+	IncludeRotated    bool
+	Merge             bool
+	Stats             bool
+	StatsTop          int
+	StatsBucket       time.Duration
+	ShipUrl           string
+	ShipPositionFile  string
+	ShipBatchSize     int
+	ShipInterval      time.Duration
+	stats             *logStats
 }
 
 func NewOptions() (ret Options) {
 	ret.ShowLevels.levels = defaultLevelFlags()
 	ret.ShowFields.shown = defaultShowFlags()
+	ret.StatsTop = 10
+	ret.StatsBucket = time.Hour
+	ret.ShipBatchSize = 1000
+	ret.ShipInterval = 5 * time.Second
 	return
 }
 
-func (o *Options) showField(field string) bool {
-	return o.ShowFields.shown[field]
+func (o *Options) filter() vespalog.Filter {
+	f := vespalog.Filter{
+		Levels:       o.ShowLevels.levels,
+		Host:         o.OnlyHostname,
+		Pid:          o.OnlyPid,
+		Service:      o.OnlyService,
+		OnlyInternal: o.OnlyInternal,
+		Component:    o.ComponentFilter.regex,
+		Message:      o.MessageFilter.regex,
+		UnknownLevel: func(level string) {
+			o.ShowLevels.levels[level] = true
+			fmt.Fprintf(os.Stderr, "Warnings: unknown level '%s' in input\n", level)
+		},
+	}
+	if o.FromTime.isSet() {
+		f.From = o.FromTime.t
+	}
+	if o.ToTime.isSet() {
+		f.To = o.ToTime.t
+	}
+	return f
 }
 
-func (o *Options) showLevel(level string) bool {
-	rv, ok := o.ShowLevels.levels[level]
-	if !ok {
-		o.ShowLevels.levels[level] = true
-		fmt.Fprintf(os.Stderr, "Warnings: unknown level '%s' in input\n", level)
-		return true
+func (o *Options) formatter() vespalog.Formatter {
+	return vespalog.Formatter{
+		Format:            o.Format,
+		Show:              o.ShowFields.shown,
+		TruncateService:   o.TruncateService,
+		TruncateComponent: o.TruncateComponent,
+		DequoteNewlines:   o.DequoteNewlines,
 	}
-	return rv
 }
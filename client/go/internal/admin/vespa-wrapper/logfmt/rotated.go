@@ -0,0 +1,153 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+// vespa logfmt command
+
+package logfmt
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
+)
+
+// logd renames vespa.log to vespa.log-YYYY-MM-DD.HH-MM-SS (UTC) when rotating,
+// and the rotated files may be compressed afterwards
+var rotatedSuffix = regexp.MustCompile(`-(\d{4}-\d{2}-\d{2}\.\d{2}-\d{2}-\d{2})(\.gz|\.zst)?$`)
+
+type rotatedFile struct {
+	name      string
+	rotatedAt time.Time
+}
+
+// find rotated siblings of the given log file, oldest first
+func rotatedSiblings(fn string) []rotatedFile {
+	matches, _ := filepath.Glob(fn + "-*")
+	result := make([]rotatedFile, 0, len(matches))
+	for _, match := range matches {
+		m := rotatedSuffix.FindStringSubmatch(strings.TrimPrefix(match, fn))
+		if m == nil {
+			continue
+		}
+		t, err := time.Parse("2006-01-02.15-04-05", m[1])
+		if err != nil {
+			continue
+		}
+		result = append(result, rotatedFile{name: match, rotatedAt: t})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].rotatedAt.Before(result[j].rotatedAt) })
+	return result
+}
+
+// the log files to read for the given (current) log file, in chronological order,
+// skipping rotated files that cannot contain anything in the selected time range
+func withRotatedFiles(opts *Options, fn string) []string {
+	if !opts.IncludeRotated && !opts.FromTime.isSet() {
+		return []string{fn}
+	}
+	result := make([]string, 0)
+	var prevRotation time.Time
+	for _, rf := range rotatedSiblings(fn) {
+		tooOld := opts.FromTime.isSet() && rf.rotatedAt.Before(opts.FromTime.t)
+		tooNew := opts.ToTime.isSet() && prevRotation.After(opts.ToTime.t)
+		if !tooOld && !tooNew {
+			result = append(result, rf.name)
+		}
+		prevRotation = rf.rotatedAt
+	}
+	if !opts.ToTime.isSet() || !prevRotation.After(opts.ToTime.t) {
+		result = append(result, fn)
+	}
+	return result
+}
+
+type compressedFile struct {
+	io.Reader
+	file   *os.File
+	closer func()
+}
+
+func (c *compressedFile) Close() error {
+	c.closer()
+	return c.file.Close()
+}
+
+// open a log file, decompressing transparently if it ends with .gz or .zst
+func openLogFile(fn string) (io.ReadCloser, error) {
+	file, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasSuffix(fn, ".gz"):
+		gz, err := gzip.NewReader(bufio.NewReader(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &compressedFile{Reader: gz, file: file, closer: func() { gz.Close() }}, nil
+	case strings.HasSuffix(fn, ".zst"):
+		zst, err := zstd.NewReader(bufio.NewReader(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &compressedFile{Reader: zst, file: file, closer: zst.Close}, nil
+	}
+	return file, nil
+}
+
+const seekMinSpan = 64 * 1024
+
+// read the timestamp of the first complete line at or after pos;
+// returns the position of that line, or -1 if there is none
+func firstTimestampAfter(file *os.File, pos int64) (int64, time.Time, bool) {
+	if _, err := file.Seek(pos, io.SeekStart); err != nil {
+		return -1, time.Time{}, false
+	}
+	reader := bufio.NewReader(file)
+	if pos > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err != nil {
+			return -1, time.Time{}, false
+		}
+		pos += int64(len(skipped))
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return -1, time.Time{}, false
+	}
+	ts, _, _ := strings.Cut(line, "\t")
+	t, err := vespalog.ParseTimestamp(ts)
+	return pos, t, err == nil
+}
+
+// position a plain, time-sorted log file near the first line at or after "from",
+// using binary search; the time filter is still applied to each line afterwards
+func seekToTime(file *os.File, from time.Time) error {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	lo, hi := int64(0), size
+	for hi-lo > seekMinSpan {
+		mid := lo + (hi-lo)/2
+		linePos, t, ok := firstTimestampAfter(file, mid)
+		if linePos < 0 || linePos >= hi {
+			hi = mid
+		} else if ok && t.Before(from) {
+			lo = linePos
+		} else {
+			hi = mid
+		}
+	}
+	_, err = file.Seek(lo, io.SeekStart)
+	return err
+}
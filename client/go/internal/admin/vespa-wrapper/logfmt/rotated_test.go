@@ -0,0 +1,112 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package logfmt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
+)
+
+func logLine(ts int) string {
+	return fmt.Sprintf("%d.000000\thost\t1\tsvc\tcomp\tinfo\tmessage %d\n", ts, ts)
+}
+
+func TestRotatedSiblings(t *testing.T) {
+	dir := t.TempDir()
+	fn := dir + "/vespa.log"
+	for _, name := range []string{
+		"vespa.log",
+		"vespa.log-2024-01-02.00-00-00.zst",
+		"vespa.log-2024-01-01.00-00-00.gz",
+		"vespa.log-2024-01-03.00-00-00",
+		"vespa.log-garbage",
+	} {
+		require.Nil(t, os.WriteFile(dir+"/"+name, nil, 0644))
+	}
+	opts := NewOptions()
+	assert.Equal(t, []string{fn}, withRotatedFiles(&opts, fn))
+	opts.IncludeRotated = true
+	assert.Equal(t, []string{
+		fn + "-2024-01-01.00-00-00.gz",
+		fn + "-2024-01-02.00-00-00.zst",
+		fn + "-2024-01-03.00-00-00",
+		fn,
+	}, withRotatedFiles(&opts, fn))
+
+	opts.IncludeRotated = false
+	require.Nil(t, opts.FromTime.Set("2024-01-01T12:00:00Z"))
+	assert.Equal(t, []string{
+		fn + "-2024-01-02.00-00-00.zst",
+		fn + "-2024-01-03.00-00-00",
+		fn,
+	}, withRotatedFiles(&opts, fn))
+
+	require.Nil(t, opts.ToTime.Set("2024-01-02T12:00:00Z"))
+	assert.Equal(t, []string{
+		fn + "-2024-01-02.00-00-00.zst",
+		fn + "-2024-01-03.00-00-00",
+	}, withRotatedFiles(&opts, fn))
+}
+
+func TestOpenCompressedLogFile(t *testing.T) {
+	dir := t.TempDir()
+	content := logLine(1) + logLine(2)
+
+	var gzBuf bytes.Buffer
+	gz := gzip.NewWriter(&gzBuf)
+	gz.Write([]byte(content))
+	gz.Close()
+	require.Nil(t, os.WriteFile(dir+"/vespa.log-2024-01-01.00-00-00.gz", gzBuf.Bytes(), 0644))
+
+	var zstBuf bytes.Buffer
+	zst, _ := zstd.NewWriter(&zstBuf)
+	zst.Write([]byte(content))
+	zst.Close()
+	require.Nil(t, os.WriteFile(dir+"/vespa.log-2024-01-02.00-00-00.zst", zstBuf.Bytes(), 0644))
+
+	for _, name := range []string{"vespa.log-2024-01-01.00-00-00.gz", "vespa.log-2024-01-02.00-00-00.zst"} {
+		input, err := openLogFile(dir + "/" + name)
+		require.Nil(t, err)
+		data, err := io.ReadAll(input)
+		assert.Nil(t, err)
+		assert.Equal(t, content, string(data))
+		assert.Nil(t, input.Close())
+	}
+}
+
+func TestSeekToTime(t *testing.T) {
+	var buf strings.Builder
+	for ts := 1000000; ts < 1050000; ts++ {
+		buf.WriteString(logLine(ts))
+	}
+	fn := t.TempDir() + "/vespa.log"
+	require.Nil(t, os.WriteFile(fn, []byte(buf.String()), 0644))
+	file, err := os.Open(fn)
+	require.Nil(t, err)
+	defer file.Close()
+
+	from := time.Unix(1040000, 0)
+	require.Nil(t, seekToTime(file, from))
+	pos, _ := file.Seek(0, io.SeekCurrent)
+	rest, _ := io.ReadAll(file)
+	assert.Greater(t, pos, int64(0))
+	assert.Less(t, len(rest), len(logLine(1040000))*10000+seekMinSpan)
+	assert.True(t, strings.HasPrefix(buf.String()[pos-1:], "\n"), "positioned at start of line")
+	firstTs, _ := vespalog.ParseTimestamp(strings.Split(string(rest), "\t")[0])
+	assert.True(t, firstTs.Before(from))
+	assert.Contains(t, string(rest), logLine(1040000))
+
+	require.Nil(t, seekToTime(file, time.Unix(0, 0)))
+	pos, _ = file.Seek(0, io.SeekCurrent)
+	assert.Equal(t, int64(0), pos)
+}
@@ -7,9 +7,11 @@ package logfmt
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
 )
 
 func inputIsPipe() bool {
@@ -27,16 +29,56 @@ func inputIsPipe() bool {
 // main entry point for vespa-logfmt
 
 func RunLogfmt(opts *Options, args []string) {
+	if opts.ShipUrl != "" {
+		if len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "Must have at most 1 file for 'ship' option, got %d\n", len(args))
+			return
+		}
+		logFile := vespa.FindHome() + "/logs/vespa/vespa.log"
+		if len(args) == 1 {
+			logFile = args[0]
+		}
+		if err := shipFile(opts, logFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return
+	}
+	if opts.Stats {
+		if opts.FollowTail {
+			fmt.Fprintln(os.Stderr, "Cannot use 'stats' option together with 'follow'")
+			return
+		}
+		opts.stats = newLogStats(opts.StatsTop, opts.StatsBucket)
+		defer func() {
+			if err := opts.stats.write(os.Stdout, opts.Format); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	} else {
+		formatter := opts.formatter()
+		os.Stdout.WriteString(formatter.Header())
+	}
 	if len(args) == 0 {
 		if !inputIsPipe() {
-			args = append(args, vespa.FindHome()+"/logs/vespa/vespa.log")
+			logFile := vespa.FindHome() + "/logs/vespa/vespa.log"
+			if opts.FollowTail {
+				args = append(args, logFile)
+			} else {
+				args = withRotatedFiles(opts, logFile)
+			}
 		} else {
 			formatFile(opts, os.Stdin)
 		}
 	}
 	if opts.FollowTail {
+		if opts.Merge && len(args) > 1 {
+			if err := tailMergedFiles(opts, args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			return
+		}
 		if len(args) != 1 {
-			fmt.Fprintf(os.Stderr, "Must have exact 1 file for 'follow' option, got %d\n", len(args))
+			fmt.Fprintf(os.Stderr, "Must have exact 1 file for 'follow' option without 'merge', got %d\n", len(args))
 			return
 		}
 		if err := tailFile(opts, args[0]); err != nil {
@@ -45,18 +87,38 @@ func RunLogfmt(opts *Options, args []string) {
 		}
 		return
 	}
+	if opts.Merge && len(args) > 1 {
+		formatMergedFiles(opts, args)
+		return
+	}
 	for _, arg := range args {
-		file, err := os.Open(arg)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Cannot open '%s': %v\n", arg, err)
-		} else {
-			formatFile(opts, file)
-			file.Close()
+		input := openInput(opts, arg)
+		if input != nil {
+			formatFile(opts, input)
+			input.Close()
+		}
+	}
+}
+
+func openInput(opts *Options, fn string) io.ReadCloser {
+	input, err := openLogFile(fn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot open '%s': %v\n", fn, err)
+		return nil
+	}
+	if file, isPlain := input.(*os.File); isPlain && opts.FromTime.isSet() {
+		if err := seekToTime(file, opts.FromTime.t); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot seek in '%s': %v\n", fn, err)
 		}
 	}
+	return input
 }
 
 func formatLine(opts *Options, line string) {
+	if opts.stats != nil {
+		countLine(opts, line)
+		return
+	}
 	output, err := handleLine(opts, line)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "bad log line:", err)
@@ -65,6 +127,15 @@ func formatLine(opts *Options, line string) {
 	}
 }
 
+func countLine(opts *Options, line string) {
+	entry, err := vespalog.Parse(line)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bad log line:", err)
+	} else if selectEntry(opts, entry) {
+		opts.stats.add(entry)
+	}
+}
+
 func tailFile(opts *Options, fn string) error {
 	tailed, err := FollowFile(fn)
 	if err != nil {
@@ -76,7 +147,41 @@ func tailFile(opts *Options, fn string) error {
 	return nil
 }
 
-func formatFile(opts *Options, arg *os.File) {
+func tailMergedFiles(opts *Options, fileNames []string) error {
+	tails := make([]Tail, 0, len(fileNames))
+	for _, fn := range fileNames {
+		tailed, err := FollowFile(fn)
+		if err != nil {
+			return err
+		}
+		tails = append(tails, tailed)
+	}
+	merged := mergeFollowedTails(tails, originNames(fileNames), followMergeDelay)
+	for line := range merged.Lines() {
+		formatLine(opts, line.Text)
+	}
+	return nil
+}
+
+func formatMergedFiles(opts *Options, fileNames []string) {
+	names := originNames(fileNames)
+	tails := make([]Tail, 0, len(fileNames))
+	origins := make([]string, 0, len(fileNames))
+	for i, fn := range fileNames {
+		input := openInput(opts, fn)
+		if input == nil {
+			continue
+		}
+		defer input.Close()
+		tails = append(tails, newReaderTail(input))
+		origins = append(origins, names[i])
+	}
+	for line := range mergeTails(tails, origins).Lines() {
+		formatLine(opts, line.Text)
+	}
+}
+
+func formatFile(opts *Options, arg io.Reader) {
 	input := bufio.NewScanner(arg)
 	input.Buffer(make([]byte, 64*1024), 4*1024*1024)
 	for input.Scan() {
@@ -0,0 +1,224 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+// vespa logfmt command
+
+package logfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vespa-engine/vespa/client/go/internal/httputil"
+	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
+)
+
+// follow vespa.log and POST selected messages, as json-v1 lines, to an HTTP endpoint
+
+const (
+	shipRequestTimeout = 30 * time.Second
+	shipMinBackoff     = 1 * time.Second
+	shipMaxBackoff     = 60 * time.Second
+)
+
+type shipper struct {
+	opts          *Options
+	url           string
+	client        httputil.Client
+	positionFile  string
+	batchSize     int
+	flushInterval time.Duration
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+
+	batch    bytes.Buffer
+	count    int
+	position *TailPosition // after the last line in the batch
+	saved    *TailPosition // stored in the position file
+}
+
+func defaultShipPositionFile() string {
+	return vespa.FindHome() + "/var/db/vespa/logfmt-ship.position"
+}
+
+func newShipper(opts *Options, client httputil.Client) *shipper {
+	s := &shipper{
+		opts:          opts,
+		url:           opts.ShipUrl,
+		client:        client,
+		positionFile:  opts.ShipPositionFile,
+		batchSize:     opts.ShipBatchSize,
+		flushInterval: opts.ShipInterval,
+		minBackoff:    shipMinBackoff,
+		maxBackoff:    shipMaxBackoff,
+	}
+	if s.positionFile == "" {
+		s.positionFile = defaultShipPositionFile()
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = 1
+	}
+	if s.flushInterval <= 0 {
+		s.flushInterval = time.Second
+	}
+	return s
+}
+
+// the saved position, or nil if there is none
+func readShipPosition(fn string) (*TailPosition, error) {
+	data, err := os.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pos TailPosition
+	if err := json.Unmarshal(data, &pos); err != nil {
+		return nil, fmt.Errorf("bad position file %s: %w", fn, err)
+	}
+	return &pos, nil
+}
+
+func writeShipPosition(fn string, pos *TailPosition) error {
+	data, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return err
+	}
+	tmp := fn + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+type shipError struct {
+	status    int
+	retryable bool
+}
+
+func (e *shipError) Error() string {
+	return fmt.Sprintf("log shipping endpoint returned status %d", e.status)
+}
+
+func (s *shipper) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	response, err := s.client.Do(req, shipRequestTimeout)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	if response.StatusCode/100 == 2 {
+		return nil
+	}
+	retryable := response.StatusCode/100 == 5 || response.StatusCode == http.StatusTooManyRequests
+	return &shipError{status: response.StatusCode, retryable: retryable}
+}
+
+// post with retries; gives up only on errors that will not go away by retrying
+func (s *shipper) postWithRetry(body []byte) error {
+	backoff := s.minBackoff
+	for {
+		err := s.post(body)
+		if err == nil {
+			return nil
+		}
+		if shipErr, ok := err.(*shipError); ok && !shipErr.retryable {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Shipping %d log messages failed, retrying in %v: %v\n", s.count, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, s.maxBackoff)
+	}
+}
+
+func (s *shipper) flush() error {
+	if s.count > 0 {
+		if err := s.postWithRetry(s.batch.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "Dropping %d log messages: %v\n", s.count, err)
+		}
+		s.batch.Reset()
+		s.count = 0
+	}
+	if s.position != nil && (s.saved == nil || *s.saved != *s.position) {
+		if err := writeShipPosition(s.positionFile, s.position); err != nil {
+			return err
+		}
+		saved := *s.position
+		s.saved = &saved
+	}
+	return nil
+}
+
+func (s *shipper) add(line Line) error {
+	pos := line.Position
+	s.position = &pos
+	entry, err := vespalog.Parse(line.Text)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bad log line:", err)
+		return nil
+	}
+	if !selectEntry(s.opts, entry) {
+		return nil
+	}
+	formatter := vespalog.Formatter{Format: vespalog.FormatJSONV1}
+	rendered, err := formatter.Render(entry)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bad log line:", err)
+		return nil
+	}
+	s.batch.WriteString(rendered)
+	s.count++
+	if s.count >= s.batchSize {
+		return s.flush()
+	}
+	return nil
+}
+
+// ship lines until the tail ends
+func (s *shipper) run(tail Tail) error {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case line, ok := <-tail.Lines():
+			if !ok {
+				return s.flush()
+			}
+			if err := s.add(line); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := s.flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func shipFile(opts *Options, fn string) error {
+	s := newShipper(opts, httputil.NewClient(shipRequestTimeout))
+	from, err := readShipPosition(s.positionFile)
+	if err != nil {
+		return err
+	}
+	s.saved = from
+	tailed, err := FollowFileFrom(fn, from)
+	if err != nil {
+		return err
+	}
+	return s.run(tailed)
+}
@@ -0,0 +1,110 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package logfmt
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/httputil"
+)
+
+type shipReceiver struct {
+	mu       sync.Mutex
+	statuses []int // returned for the first requests, then 200
+	bodies   []string
+	requests int
+}
+
+func (r *shipReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	r.requests++
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	r.bodies = append(r.bodies, string(body))
+}
+
+func testShipper(t *testing.T, url string) *shipper {
+	opts := NewOptions()
+	opts.ShipUrl = url
+	opts.ShipPositionFile = filepath.Join(t.TempDir(), "ship.position")
+	opts.ShipBatchSize = 2
+	opts.ShipInterval = time.Hour
+	s := newShipper(&opts, httputil.NewClient(time.Second))
+	s.minBackoff = time.Millisecond
+	s.maxBackoff = time.Millisecond
+	return s
+}
+
+func shipLines(s *shipper, lines ...Line) error {
+	tail := &chanTail{lines: make(chan Line, len(lines))}
+	for _, line := range lines {
+		tail.lines <- line
+	}
+	close(tail.lines)
+	return s.run(tail)
+}
+
+func TestShipWithRetry(t *testing.T) {
+	receiver := &shipReceiver{statuses: []int{503, 429}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	s := testShipper(t, server.URL)
+	err := shipLines(s,
+		Line{Text: "1700000000.0\thost1\t1\tcontainer\tcomp\tinfo\tfirst", Position: TailPosition{Inode: 7, Offset: 10}},
+		Line{Text: "1700000001.0\thost1\t1\tcontainer\tcomp\tdebug\tnot selected", Position: TailPosition{Inode: 7, Offset: 20}},
+		Line{Text: "1700000002.0\thost1\t1\tcontainer\tcomp\twarning\tsecond", Position: TailPosition{Inode: 7, Offset: 30}},
+		Line{Text: "1700000003.0\thost1\t1\tcontainer\tcomp\terror\tthird", Position: TailPosition{Inode: 7, Offset: 40}},
+		Line{Text: "1700000004.0\thost1\t1\tcontainer\tcomp\tdebug\tnot selected", Position: TailPosition{Inode: 7, Offset: 50}})
+	require.Nil(t, err)
+	assert.Equal(t, 4, receiver.requests)
+	require.Equal(t, 2, len(receiver.bodies))
+	lines := strings.Split(strings.TrimSuffix(receiver.bodies[0], "\n"), "\n")
+	require.Equal(t, 2, len(lines))
+	assert.Contains(t, lines[0], `"message":"first"`)
+	assert.Contains(t, lines[1], `"message":"second"`)
+	assert.Contains(t, receiver.bodies[1], `"message":"third"`)
+
+	pos, err := readShipPosition(s.positionFile)
+	require.Nil(t, err)
+	assert.Equal(t, &TailPosition{Inode: 7, Offset: 50}, pos)
+}
+
+func TestShipDropsRejectedBatch(t *testing.T) {
+	receiver := &shipReceiver{statuses: []int{400}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	s := testShipper(t, server.URL)
+	err := shipLines(s,
+		Line{Text: "1700000000.0\thost1\t1\tcontainer\tcomp\tinfo\trejected", Position: TailPosition{Inode: 7, Offset: 10}},
+		Line{Text: "1700000001.0\thost1\t1\tcontainer\tcomp\tinfo\trejected", Position: TailPosition{Inode: 7, Offset: 20}},
+		Line{Text: "1700000002.0\thost1\t1\tcontainer\tcomp\tinfo\taccepted", Position: TailPosition{Inode: 7, Offset: 30}})
+	require.Nil(t, err)
+	assert.Equal(t, 2, receiver.requests)
+	require.Equal(t, 1, len(receiver.bodies))
+	assert.Contains(t, receiver.bodies[0], `"message":"accepted"`)
+}
+
+func TestShipPositionFile(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "sub", "ship.position")
+	pos, err := readShipPosition(fn)
+	assert.Nil(t, err)
+	assert.Nil(t, pos)
+	require.Nil(t, writeShipPosition(fn, &TailPosition{Inode: 42, Offset: 4096}))
+	pos, err = readShipPosition(fn)
+	require.Nil(t, err)
+	assert.Equal(t, &TailPosition{Inode: 42, Offset: 4096}, pos)
+}
@@ -6,6 +6,8 @@ package logfmt
 
 import (
 	"strings"
+
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
 )
 
 // handle CLI flags for which fields to show when formatting a line
@@ -16,18 +18,7 @@ type flagValueForShow struct {
 }
 
 func defaultShowFlags() map[string]bool {
-	return map[string]bool{
-		"time":      true,
-		"fmttime":   true,
-		"msecs":     true,
-		"usecs":     false,
-		"host":      false,
-		"level":     true,
-		"pid":       false,
-		"service":   true,
-		"component": true,
-		"message":   true,
-	}
+	return vespalog.DefaultShowFields()
 }
 
 func (v *flagValueForShow) Type() string {
@@ -0,0 +1,212 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+// vespa logfmt command
+
+package logfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
+)
+
+// aggregated counts for selected log messages, for the --stats option
+
+type logStats struct {
+	total      int
+	first      time.Time
+	last       time.Time
+	levels     map[string]int
+	services   map[string]int
+	components map[string]int
+	hosts      map[string]int
+	templates  map[string]int
+	buckets    map[int64]int
+	bucketSize time.Duration
+	topN       int
+}
+
+func newLogStats(topN int, bucketSize time.Duration) *logStats {
+	if bucketSize <= 0 {
+		bucketSize = time.Hour
+	}
+	return &logStats{
+		levels:     make(map[string]int),
+		services:   make(map[string]int),
+		components: make(map[string]int),
+		hosts:      make(map[string]int),
+		templates:  make(map[string]int),
+		buckets:    make(map[int64]int),
+		bucketSize: bucketSize,
+		topN:       topN,
+	}
+}
+
+var (
+	templateUuid    = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	templateHexWord = regexp.MustCompile(`\b(0x[0-9a-fA-F]+|[0-9a-fA-F]{8,})\b`)
+	templateNumber  = regexp.MustCompile(`[0-9]+(\.[0-9]+)*`)
+)
+
+// make a template from a message, so that messages differing only
+// in numbers and identifiers are counted together
+func messageTemplate(msg string) string {
+	msg = templateUuid.ReplaceAllString(msg, "<id>")
+	msg = templateHexWord.ReplaceAllStringFunc(msg, func(word string) string {
+		// long hex words must mix digits and letters, to leave plain numbers and words alone
+		if strings.HasPrefix(word, "0x") || (strings.ContainsAny(word, "0123456789") && strings.ContainsAny(word, "abcdefABCDEF")) {
+			return "<id>"
+		}
+		return word
+	})
+	return templateNumber.ReplaceAllString(msg, "<num>")
+}
+
+func (s *logStats) add(entry *vespalog.Entry) {
+	timestamp := entry.Time
+	s.total++
+	if s.first.IsZero() || timestamp.Before(s.first) {
+		s.first = timestamp
+	}
+	if timestamp.After(s.last) {
+		s.last = timestamp
+	}
+	s.levels[entry.Level]++
+	s.services[entry.Service]++
+	s.components[entry.Component]++
+	s.hosts[entry.Host]++
+	s.templates[messageTemplate(entry.Message)]++
+	s.buckets[timestamp.Truncate(s.bucketSize).Unix()]++
+}
+
+type statsCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// counts sorted with the most frequent first
+func sortedCounts(counts map[string]int, limit int) []statsCount {
+	result := make([]statsCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, statsCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+type statsBucket struct {
+	Start         string  `json:"start"`
+	Count         int     `json:"count"`
+	RatePerMinute float64 `json:"ratePerMinute"`
+}
+
+func (s *logStats) sortedBuckets() []statsBucket {
+	starts := make([]int64, 0, len(s.buckets))
+	for start := range s.buckets {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	result := make([]statsBucket, 0, len(starts))
+	for _, start := range starts {
+		count := s.buckets[start]
+		result = append(result, statsBucket{
+			Start:         time.Unix(start, 0).Format(time.RFC3339),
+			Count:         count,
+			RatePerMinute: float64(count) / s.bucketSize.Minutes(),
+		})
+	}
+	return result
+}
+
+type statsJson struct {
+	Total      int           `json:"total"`
+	First      string        `json:"first,omitempty"`
+	Last       string        `json:"last,omitempty"`
+	Levels     []statsCount  `json:"levels"`
+	Services   []statsCount  `json:"services"`
+	Components []statsCount  `json:"components"`
+	Hosts      []statsCount  `json:"hosts"`
+	Messages   []statsCount  `json:"messages"`
+	BucketSize string        `json:"bucketSize"`
+	Buckets    []statsBucket `json:"buckets"`
+}
+
+func (s *logStats) writeJson(w io.Writer) error {
+	out := statsJson{
+		Total:      s.total,
+		Levels:     sortedCounts(s.levels, 0),
+		Services:   sortedCounts(s.services, 0),
+		Components: sortedCounts(s.components, 0),
+		Hosts:      sortedCounts(s.hosts, 0),
+		Messages:   sortedCounts(s.templates, s.topN),
+		BucketSize: s.bucketSize.String(),
+		Buckets:    s.sortedBuckets(),
+	}
+	if s.total > 0 {
+		out.First = s.first.Format(time.RFC3339Nano)
+		out.Last = s.last.Format(time.RFC3339Nano)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&out)
+}
+
+func (s *logStats) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Total messages:\t%d\n", s.total)
+	if s.total > 0 {
+		fmt.Fprintf(tw, "First:\t%s\n", s.first.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(tw, "Last:\t%s\n", s.last.Format("2006-01-02 15:04:05"))
+	}
+	sections := []struct {
+		title  string
+		counts []statsCount
+	}{
+		{"LEVEL", sortedCounts(s.levels, 0)},
+		{"SERVICE", sortedCounts(s.services, 0)},
+		{"COMPONENT", sortedCounts(s.components, 0)},
+		{"HOST", sortedCounts(s.hosts, 0)},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(tw, "\n%s\tCOUNT\n", section.title)
+		for _, c := range section.counts {
+			fmt.Fprintf(tw, "%s\t%d\n", c.Name, c.Count)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	top := sortedCounts(s.templates, s.topN)
+	fmt.Fprintf(w, "\nTop %d messages:\n", len(top))
+	for _, c := range top {
+		fmt.Fprintf(w, "%8d  %s\n", c.Count, c.Name)
+	}
+	fmt.Fprintf(w, "\nMessages per %s:\n", s.bucketSize)
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "START\tCOUNT\tPER MINUTE\t\n")
+	for _, b := range s.sortedBuckets() {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t\n", b.Start, b.Count, b.RatePerMinute)
+	}
+	return tw.Flush()
+}
+
+func (s *logStats) write(w io.Writer, format vespalog.Format) error {
+	if format == vespalog.FormatJSON || format == vespalog.FormatJSONV1 {
+		return s.writeJson(w)
+	}
+	return s.writeTable(w)
+}
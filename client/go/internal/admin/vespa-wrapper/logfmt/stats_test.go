@@ -0,0 +1,80 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package logfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
+)
+
+func TestMessageTemplate(t *testing.T) {
+	assert.Equal(t, "Connection to <num>:<num> lost after <num> ms",
+		messageTemplate("Connection to 10.0.0.1:19070 lost after 2.5 ms"))
+	assert.Equal(t, "Session <id> for document <id> failed",
+		messageTemplate("Session 0x1f3a for document 3f2504e0-4f89-11d3-9a0c-0305e82c3301 failed"))
+	assert.Equal(t, "Checksum <id> differs from deadbeef",
+		messageTemplate("Checksum 9e107d9d372bb6826bd81d3542a419d6 differs from deadbeef"))
+	assert.Equal(t, "node<num> is down", messageTemplate("node3 is down"))
+}
+
+func TestLogStats(t *testing.T) {
+	opts := NewOptions()
+	stats := newLogStats(2, 10*time.Minute)
+	lines := []string{
+		"1700000000.0\thost1\t1\tcontainer\tQrserver\twarning\tQuery 17 timed out after 500 ms",
+		"1700000060.0\thost1\t1\tcontainer\tQrserver\twarning\tQuery 42 timed out after 800 ms",
+		"1700000120.0\thost2\t2\tsearchnode\tproton\tinfo\tFlushed 3 documents",
+		"1700000700.0\thost2\t2\tsearchnode\tproton\tdebug\tnot selected by default",
+		"1700000900.0\thost2\t2\tsearchnode\tproton\terror\tDisk full",
+	}
+	for _, line := range lines {
+		entry, err := vespalog.Parse(line)
+		require.Nil(t, err)
+		if selectEntry(&opts, entry) {
+			stats.add(entry)
+		}
+	}
+	assert.Equal(t, 4, stats.total)
+	assert.Equal(t, []statsCount{{"warning", 2}, {"error", 1}, {"info", 1}}, sortedCounts(stats.levels, 0))
+	assert.Equal(t, []statsCount{{"Query <num> timed out after <num> ms", 2}, {"Disk full", 1}}, sortedCounts(stats.templates, stats.topN))
+	buckets := stats.sortedBuckets()
+	require.Equal(t, 2, len(buckets))
+	assert.Equal(t, 3, buckets[0].Count)
+	assert.Equal(t, 0.3, buckets[0].RatePerMinute)
+	assert.Equal(t, 1, buckets[1].Count)
+
+	var buf bytes.Buffer
+	require.Nil(t, stats.write(&buf, vespalog.FormatJSON))
+	var decoded statsJson
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, 4, decoded.Total)
+	assert.Equal(t, []statsCount{{"host1", 2}, {"host2", 2}}, decoded.Hosts)
+	assert.Equal(t, "10m0s", decoded.BucketSize)
+
+	buf.Reset()
+	require.Nil(t, stats.write(&buf, vespalog.FormatVespa))
+	out := buf.String()
+	assert.Contains(t, out, "Total messages:  4\n")
+	assert.Contains(t, out, "\nCOMPONENT  COUNT\nQrserver   2\nproton     2\n")
+	assert.True(t, strings.Contains(out, "       2  Query <num> timed out after <num> ms\n"), out)
+	assert.Contains(t, out, "\nTop 2 messages:\n")
+
+	// the stable json format gives the same output as json
+	var jsonV1 bytes.Buffer
+	require.Nil(t, stats.write(&jsonV1, vespalog.FormatJSONV1))
+	buf.Reset()
+	require.Nil(t, stats.write(&buf, vespalog.FormatJSON))
+	assert.Equal(t, buf.String(), jsonV1.String())
+
+	// the header shows how many messages there are, when fewer than requested
+	stats.topN = 10
+	buf.Reset()
+	require.Nil(t, stats.write(&buf, vespalog.FormatVespa))
+	assert.Contains(t, buf.String(), "\nTop 3 messages:\n")
+}
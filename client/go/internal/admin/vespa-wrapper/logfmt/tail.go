@@ -4,8 +4,15 @@
 
 package logfmt
 
+// where to resume reading a followed file: the file's inode and a byte offset in it
+type TailPosition struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
 type Line struct {
-	Text string
+	Text     string
+	Position TailPosition // just after this line, if known
 }
 
 type Tail interface {
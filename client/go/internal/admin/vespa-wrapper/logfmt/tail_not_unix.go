@@ -13,3 +13,7 @@ import (
 func FollowFile(fn string) (Tail, error) {
 	return nil, fmt.Errorf("tail is not supported on this platform")
 }
+
+func FollowFileFrom(fn string, from *TailPosition) (Tail, error) {
+	return nil, fmt.Errorf("tail is not supported on this platform")
+}
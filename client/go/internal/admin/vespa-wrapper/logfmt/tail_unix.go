@@ -27,6 +27,11 @@ type unixTail struct {
 	fn      string
 	reader  *bufio.Reader
 	curStat unix.Stat_t
+	offset  int64
+	// rotated files still to be read, oldest first, before following fn again
+	rotated []string
+	// closes the decompressor when reading a compressed rotated file
+	decoder func()
 }
 
 func (t *unixTail) Lines() chan Line { return t.lines }
@@ -44,7 +49,26 @@ func FollowFile(fn string) (Tail, error) {
 	return &res, nil
 }
 
+// API for following a log file from a known position, as given by Line.Position;
+// if the file was rotated since, the rest of the rotated file and all files
+// rotated after it are read first.
+// With no position, the file is read from the start.
+func FollowFileFrom(fn string, from *TailPosition) (Tail, error) {
+	res := unixTail{}
+	res.fn = fn
+	if err := res.openAt(from); err != nil {
+		return nil, err
+	}
+	res.lines = make(chan Line, 20)
+	go runTailWith(&res)
+	return &res, nil
+}
+
 func (t *unixTail) setFile(f *os.File) {
+	if t.decoder != nil {
+		t.decoder()
+		t.decoder = nil
+	}
 	if t.curFile != nil {
 		t.curFile.Close()
 	}
@@ -57,6 +81,7 @@ func (t *unixTail) setFile(f *os.File) {
 			return
 		}
 		t.reader = bufio.NewReaderSize(f, 1024*1024)
+		t.offset, _ = f.Seek(0, os.SEEK_CUR)
 	} else {
 		t.reader = nil
 	}
@@ -96,6 +121,104 @@ func (t *unixTail) openTail() {
 	}
 }
 
+func inodeOf(f *os.File) (uint64, error) {
+	var stat unix.Stat_t
+	if err := unix.Fstat(int(f.Fd()), &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Ino), nil
+}
+
+// seek to the given offset, or to the start if the file is shorter (truncated)
+func seekOrRestart(file *os.File, offset int64) {
+	if sz, err := file.Seek(0, os.SEEK_END); err == nil && sz >= offset {
+		file.Seek(offset, os.SEEK_SET)
+	} else {
+		file.Seek(0, os.SEEK_SET)
+	}
+}
+
+// open the log file (or the rotated file it was renamed to) at the given position
+func (t *unixTail) openAt(from *TailPosition) error {
+	file, err := os.Open(t.fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// wait for it to appear
+			return nil
+		}
+		return err
+	}
+	if from == nil {
+		t.setFile(file)
+		return nil
+	}
+	if ino, err := inodeOf(file); err == nil && ino == from.Inode {
+		seekOrRestart(file, from.Offset)
+		t.setFile(file)
+		return nil
+	}
+	siblings := rotatedSiblings(t.fn)
+	for i, rf := range siblings {
+		rotated, err := os.Open(rf.name)
+		if err != nil {
+			continue
+		}
+		ino, err := inodeOf(rotated)
+		rotated.Close()
+		if err != nil || ino != from.Inode {
+			continue
+		}
+		for _, newer := range siblings[i+1:] {
+			t.rotated = append(t.rotated, newer.name)
+		}
+		if t.openRotated(rf.name, from.Offset) || t.nextRotated() {
+			file.Close()
+			return nil
+		}
+		break
+	}
+	// position is gone, start over
+	file.Seek(0, os.SEEK_SET)
+	t.setFile(file)
+	return nil
+}
+
+// open a rotated file, which may be compressed, at the given offset into its content
+func (t *unixTail) openRotated(fn string, offset int64) bool {
+	rc, err := openLogFile(fn)
+	if err != nil {
+		return false
+	}
+	if file, ok := rc.(*os.File); ok {
+		seekOrRestart(file, offset)
+		t.setFile(file)
+		return true
+	}
+	compressed := rc.(*compressedFile)
+	t.setFile(compressed.file)
+	t.decoder = compressed.closer
+	t.reader = bufio.NewReaderSize(compressed.Reader, 1024*1024)
+	if skipped, err := io.CopyN(io.Discard, t.reader, offset); err == nil {
+		t.offset = skipped
+	} else {
+		// truncated, start over
+		t.openRotated(fn, 0)
+	}
+	return t.curFile != nil
+}
+
+// switch to the next rotated file to be read, if any
+func (t *unixTail) nextRotated() bool {
+	for len(t.rotated) > 0 {
+		fn := t.rotated[0]
+		t.rotated = t.rotated[1:]
+		if t.openRotated(fn, 0) {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *unixTail) reopen(cur *unix.Stat_t) {
 	for cnt := 0; cnt < 100; cnt++ {
 		file, err := os.Open(t.fn)
@@ -141,11 +264,22 @@ loop:
 		}
 		if err == nil {
 			ll := len(t.lineBuf) - 1
-			t.lines <- Line{Text: string(t.lineBuf[:ll])}
+			t.offset += int64(len(t.lineBuf))
+			t.lines <- Line{
+				Text:     string(t.lineBuf[:ll]),
+				Position: TailPosition{Inode: uint64(t.curStat.Ino), Offset: t.offset},
+			}
 			t.lineBuf = t.lineBuf[:0]
 			continue
 		}
 		if err == io.EOF {
+			if t.decoder != nil {
+				// compressed rotated files are complete
+				if !t.nextRotated() {
+					t.reopen(&t.curStat)
+				}
+				continue
+			}
 			pos, _ := t.curFile.Seek(0, os.SEEK_CUR)
 			for cnt := 0; cnt < 100; cnt++ {
 				time.Sleep(10 * time.Millisecond)
@@ -154,13 +288,16 @@ loop:
 					if sz < pos {
 						// truncation case
 						pos = 0
+						t.offset = 0
 					}
 					t.curFile.Seek(pos, os.SEEK_SET)
 					continue loop
 				}
 			}
-			// no change in file size, try reopening
-			t.reopen(&t.curStat)
+			// no change in file size, continue with the next rotated file or try reopening
+			if !t.nextRotated() {
+				t.reopen(&t.curStat)
+			}
 		} else {
 			fmt.Fprintf(os.Stderr, "error tailing '%s': %v\n", t.fn, err)
 			close(t.lines)
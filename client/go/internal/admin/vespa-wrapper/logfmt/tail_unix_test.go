@@ -0,0 +1,92 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+//go:build !windows
+
+package logfmt
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nextLine(t *testing.T, tail Tail) Line {
+	select {
+	case line := <-tail.Lines():
+		return line
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for line")
+	}
+	return Line{}
+}
+
+func TestFollowFileFrom(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "vespa.log")
+	require.Nil(t, os.WriteFile(fn, []byte("first\nsecond\n"), 0644))
+
+	tail, err := FollowFileFrom(fn, nil)
+	require.Nil(t, err)
+	first := nextLine(t, tail)
+	assert.Equal(t, "first", first.Text)
+	assert.Equal(t, int64(6), first.Position.Offset)
+	assert.Equal(t, "second", nextLine(t, tail).Text)
+
+	// resume after the first line
+	tail, err = FollowFileFrom(fn, &first.Position)
+	require.Nil(t, err)
+	second := nextLine(t, tail)
+	assert.Equal(t, "second", second.Text)
+	assert.Equal(t, TailPosition{Inode: first.Position.Inode, Offset: 13}, second.Position)
+}
+
+func TestFollowFileFromRotated(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "vespa.log")
+	require.Nil(t, os.WriteFile(fn, []byte("old first\nold second\n"), 0644))
+	tail, err := FollowFileFrom(fn, nil)
+	require.Nil(t, err)
+	first := nextLine(t, tail)
+	assert.Equal(t, "old first", first.Text)
+
+	// rotate, then resume from the position in the rotated file
+	require.Nil(t, os.Rename(fn, fn+"-2024-01-02.03-04-05"))
+	require.Nil(t, os.WriteFile(fn, []byte("new first\n"), 0644))
+	tail, err = FollowFileFrom(fn, &first.Position)
+	require.Nil(t, err)
+	assert.Equal(t, "old second", nextLine(t, tail).Text)
+	newFirst := nextLine(t, tail)
+	assert.Equal(t, "new first", newFirst.Text)
+	assert.NotEqual(t, first.Position.Inode, newFirst.Position.Inode)
+	assert.Equal(t, int64(10), newFirst.Position.Offset)
+}
+
+func TestFollowFileFromRotatedTwice(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "vespa.log")
+	require.Nil(t, os.WriteFile(fn, []byte("first\nsecond\n"), 0644))
+	tail, err := FollowFileFrom(fn, nil)
+	require.Nil(t, err)
+	first := nextLine(t, tail)
+	assert.Equal(t, "first", first.Text)
+
+	// rotate twice, the second rotated file being compressed, then resume from the position in the oldest one
+	require.Nil(t, os.Rename(fn, fn+"-2024-01-02.03-04-05"))
+	f, err := os.Create(fn + "-2024-01-02.04-04-05.gz")
+	require.Nil(t, err)
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write([]byte("third\nfourth\n"))
+	require.Nil(t, err)
+	require.Nil(t, gz.Close())
+	require.Nil(t, f.Close())
+	require.Nil(t, os.WriteFile(fn, []byte("fifth\n"), 0644))
+	tail, err = FollowFileFrom(fn, &first.Position)
+	require.Nil(t, err)
+	for _, want := range []string{"second", "third", "fourth", "fifth"} {
+		assert.Equal(t, want, nextLine(t, tail).Text)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+// vespa logfmt command
+
+package logfmt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// optional point in time, as a CLI flag; absolute or relative to now
+
+type timeFlag struct {
+	t   time.Time
+	set bool
+}
+
+var timeFlagLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// used for relative times; may be replaced in tests
+var timeFlagNow = time.Now
+
+func (v *timeFlag) Type() string {
+	return "time"
+}
+
+func (v *timeFlag) String() string {
+	if !v.set {
+		return ""
+	}
+	return v.t.Format(time.RFC3339)
+}
+
+func (v *timeFlag) Set(val string) error {
+	t, err := parseTimeFlag(val)
+	if err != nil {
+		return err
+	}
+	v.t = t
+	v.set = true
+	return nil
+}
+
+func (v *timeFlag) isSet() bool {
+	return v.set
+}
+
+// parse a duration like "90s", "1h30m" or "2d"
+func parseRelativeTime(val string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(val, "d"); found {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(val)
+}
+
+func parseTimeFlag(val string) (time.Time, error) {
+	val = strings.TrimSpace(val)
+	if val == "now" {
+		return timeFlagNow(), nil
+	}
+	// relative: "1h", "-1h" and "1h ago" all mean one hour before now
+	rel := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(val, "-"), "ago"))
+	if d, err := parseRelativeTime(rel); err == nil {
+		return timeFlagNow().Add(-d), nil
+	}
+	if secs, err := strconv.ParseFloat(val, 64); err == nil {
+		return time.Unix(0, int64(secs*1e9)), nil
+	}
+	for _, layout := range timeFlagLayouts {
+		if t, err := time.ParseInLocation(layout, val, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("not a valid time: '%s' (use for example 2024-01-31T12:00:00Z, 1706702400, or 2h for two hours ago)", val)
+}
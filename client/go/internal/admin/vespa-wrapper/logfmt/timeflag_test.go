@@ -0,0 +1,75 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package logfmt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
+)
+
+func TestTimeFlag(t *testing.T) {
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	timeFlagNow = func() time.Time { return now }
+	defer func() { timeFlagNow = time.Now }()
+	tests := []struct {
+		arg      string
+		expected time.Time
+	}{
+		{"now", now},
+		{"2h", now.Add(-2 * time.Hour)},
+		{"-90m", now.Add(-90 * time.Minute)},
+		{"1d ago", now.Add(-24 * time.Hour)},
+		{"1706702400", time.Unix(1706702400, 0)},
+		{"1706702400.5", time.Unix(1706702400, 500000000)},
+		{"2024-01-30T10:00:00Z", time.Date(2024, 1, 30, 10, 0, 0, 0, time.UTC)},
+		{"2024-01-30 10:00:00", time.Date(2024, 1, 30, 10, 0, 0, 0, time.Local)},
+		{"2024-01-30", time.Date(2024, 1, 30, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		var v timeFlag
+		assert.Nil(t, v.Set(tt.arg), tt.arg)
+		assert.True(t, v.isSet())
+		assert.True(t, tt.expected.Equal(v.t), "%s: expected %v, got %v", tt.arg, tt.expected, v.t)
+	}
+	var v timeFlag
+	assert.NotNil(t, v.Set("yesterday"))
+	assert.False(t, v.isSet())
+	assert.Equal(t, "time", v.Type())
+}
+
+func TestTimeFilter(t *testing.T) {
+	opts := NewOptions()
+	opts.ShowFields.shown = map[string]bool{"message": true}
+	opts.FromTime.Set("1700000010")
+	opts.ToTime.Set("1700000020")
+	check := func(ts string, expected string) {
+		out, err := handleLine(&opts, ts+"\thost\t1\tsvc\tcomp\tinfo\tmsg "+ts)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, out)
+	}
+	check("1700000009.999", "")
+	check("1700000010.000", "msg 1700000010.000\n")
+	check("1700000019.5", "msg 1700000019.5\n")
+	check("1700000020", "")
+	_, err := handleLine(&opts, "bad\thost\t1\tsvc\tcomp\tinfo\tmsg")
+	assert.NotNil(t, err)
+
+	// raw output passes lines with unparsable timestamps through unchanged
+	opts.Format = vespalog.FormatRaw
+	out, err := handleLine(&opts, "bad\thost\t1\tsvc\tcomp\tinfo\tmsg")
+	assert.Nil(t, err)
+	assert.Equal(t, "bad\thost\t1\tsvc\tcomp\tinfo\tmsg\n", out)
+	out, err = handleLine(&opts, "1700000009\thost\t1\tsvc\tcomp\tinfo\tmsg")
+	assert.Nil(t, err)
+	assert.Equal(t, "", out)
+	out, err = handleLine(&opts, "1700000010\thost\t1\tsvc\tcomp\tinfo\tmsg")
+	assert.Nil(t, err)
+	assert.Equal(t, "1700000010\thost\t1\tsvc\tcomp\tinfo\tmsg\n", out)
+	out, err = handleLine(&opts, "bad\thost\t1\tsvc\tcomp\tdebug\tmsg")
+	assert.Nil(t, err)
+	assert.Equal(t, "", out, "other filters still apply")
+	_, err = handleLine(&opts, "bad\thost")
+	assert.NotNil(t, err)
+}
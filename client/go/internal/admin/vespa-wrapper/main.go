@@ -13,6 +13,7 @@ import (
 	"github.com/vespa-engine/vespa/client/go/internal/admin/deploy"
 	"github.com/vespa-engine/vespa/client/go/internal/admin/jvm"
 	"github.com/vespa-engine/vespa/client/go/internal/admin/vespa-wrapper/configserver"
+	"github.com/vespa-engine/vespa/client/go/internal/admin/vespa-wrapper/envreport"
 	"github.com/vespa-engine/vespa/client/go/internal/admin/vespa-wrapper/logfmt"
 	"github.com/vespa-engine/vespa/client/go/internal/admin/vespa-wrapper/services"
 	"github.com/vespa-engine/vespa/client/go/internal/admin/vespa-wrapper/standalone"
@@ -62,6 +63,8 @@ func main() {
 		vespa.ExportDefaultEnvToSh()
 	case "security-env", "vespa-security-env":
 		vespa.ExportSecurityEnvToSh()
+	case "env-report":
+		os.Exit(envreport.Run(os.Args[1:]))
 	case "ipv6-only":
 		if vespa.HasOnlyIpV6() {
 			os.Exit(0)
@@ -92,7 +95,7 @@ func main() {
 			os.Exit(startcbinary.Run(os.Args))
 		}
 		fmt.Fprintf(os.Stderr, "unknown action '%s'\n", action)
-		fmt.Fprintln(os.Stderr, "actions: export-env, ipv6-only, security-env, detect-hostname")
+		fmt.Fprintln(os.Stderr, "actions: export-env, env-report, ipv6-only, security-env, detect-hostname")
 		fmt.Fprintln(os.Stderr, "(also: vespa-deploy, vespa-logfmt)")
 	}
 }
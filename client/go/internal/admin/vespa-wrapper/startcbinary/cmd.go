@@ -16,6 +16,11 @@ import (
 
 func Run(args []string) int {
 	trace.AdjustVerbosity(0)
+	printPlacement := false
+	if len(args) > 0 && args[0] == "--print-placement" {
+		printPlacement = true
+		args = args[1:]
+	}
 	if len(args) < 1 {
 		trace.Warning("missing program argument")
 		return 1
@@ -29,6 +34,9 @@ func Run(args []string) int {
 	if err != nil {
 		trace.Warning("could not detect hostname:", err, "; using fallback:", hostname)
 	}
+	if printPlacement {
+		return printCbinaryPlacement(os.Stdout, spec)
+	}
 	return startCbinary(spec)
 }
 
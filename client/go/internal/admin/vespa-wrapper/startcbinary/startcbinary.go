@@ -5,20 +5,30 @@ package startcbinary
 
 import (
 	"fmt"
+	"io"
 	"os"
 
+	"github.com/alessio/shellescape"
 	"github.com/vespa-engine/vespa/client/go/internal/admin/prog"
 )
 
-func startCbinary(spec *prog.Spec) int {
+// when only printing, profiling is skipped as it creates output directories
+func configureSpec(spec *prog.Spec, printOnly bool) {
 	configureCommonEnv(spec)
 	configurePath(spec)
-	configureTuning()
 	spec.ConfigureValgrind()
+	if !printOnly {
+		spec.ConfigureProfiling()
+	}
 	spec.ConfigureNumaCtl()
 	spec.ConfigureHugePages()
 	spec.ConfigureUseMadvise()
 	spec.ConfigureVespaMalloc()
+}
+
+func startCbinary(spec *prog.Spec) int {
+	configureSpec(spec, false)
+	configureTuning()
 	err := spec.Run()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -27,3 +37,10 @@ func startCbinary(spec *prog.Spec) int {
 		return 0
 	}
 }
+
+// show the command line that would be used, without running anything
+func printCbinaryPlacement(w io.Writer, spec *prog.Spec) int {
+	configureSpec(spec, true)
+	fmt.Fprintln(w, shellescape.QuoteCommand(spec.EffectiveArgs()))
+	return 0
+}
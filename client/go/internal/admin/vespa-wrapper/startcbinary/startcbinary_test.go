@@ -0,0 +1,82 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+package startcbinary
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vespa-engine/vespa/client/go/internal/admin/prog"
+	"github.com/vespa-engine/vespa/client/go/internal/admin/trace"
+)
+
+func useMockNumactl(t *testing.T, mock string) {
+	t.Helper()
+	tmpBin := t.TempDir()
+	_, testFile, _, _ := runtime.Caller(0)
+	mockBinDir := strings.TrimSuffix(testFile, "/startcbinary_test.go") + "/mockbin"
+	if err := os.Symlink(mockBinDir+"/"+mock, tmpBin+"/numactl"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", tmpBin+":"+os.Getenv("PATH"))
+}
+
+// spec reading the NUMA topology from the two-node sysfs fixture of the prog package
+func newFixtureSpec(args []string) *prog.Spec {
+	_, testFile, _, _ := runtime.Caller(0)
+	spec := NewProgSpec(args)
+	spec.SetNumaRootDir(strings.TrimSuffix(testFile, "/vespa-wrapper/startcbinary/startcbinary_test.go") + "/prog/mock-sysfs/two-nodes")
+	return spec
+}
+
+func TestPrintPlacement(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no numactl on windows")
+	}
+	trace.AdjustVerbosity(0)
+	vespaHome := t.TempDir()
+	t.Setenv("VESPA_HOME", vespaHome)
+	t.Setenv("VESPA_USE_VALGRIND", "")
+	t.Setenv("VESPA_NO_NUMACTL", "")
+	t.Setenv("VESPA_AFFINITY_CPU_SOCKET", "")
+	t.Setenv("VESPA_AFFINITY_CPU_LIST", "")
+	t.Setenv("VESPA_AFFINITY_MEM_NODES", "")
+	t.Setenv("VESPA_USE_SANITIZER", "all=asan")
+
+	var out strings.Builder
+	useMockNumactl(t, "good-numactl")
+	assert.Equal(t, 0, printCbinaryPlacement(&out, newFixtureSpec([]string{"vespa-proton", "--identity", "my id"})))
+	assert.Equal(t, "numactl --interleave all vespa-proton-bin --identity 'my id'\n", out.String())
+
+	out.Reset()
+	t.Setenv("VESPA_AFFINITY_CPU_SOCKET", "1")
+	assert.Equal(t, 0, printCbinaryPlacement(&out, newFixtureSpec([]string{"vespa-proton"})))
+	assert.Equal(t, "numactl --cpunodebind=1 --membind=1 vespa-proton-bin\n", out.String())
+
+	out.Reset()
+	t.Setenv("VESPA_AFFINITY_CPU_SOCKET", "2")
+	assert.Equal(t, 0, printCbinaryPlacement(&out, newFixtureSpec([]string{"vespa-proton"})))
+	assert.Equal(t, "numactl --cpunodebind=0 --membind=0 vespa-proton-bin\n", out.String())
+
+	out.Reset()
+	t.Setenv("VESPA_AFFINITY_CPU_SOCKET", "-1")
+	assert.Equal(t, 0, printCbinaryPlacement(&out, newFixtureSpec([]string{"vespa-proton"})))
+	assert.Equal(t, "numactl --interleave all vespa-proton-bin\n", out.String())
+
+	out.Reset()
+	t.Setenv("VESPA_AFFINITY_CPU_LIST", "all=4-5,12")
+	assert.Equal(t, 0, printCbinaryPlacement(&out, newFixtureSpec([]string{"vespa-proton"})))
+	assert.Equal(t, "numactl --physcpubind=4-5,12 --membind=1 vespa-proton-bin\n", out.String())
+
+	out.Reset()
+	t.Setenv("VESPA_AFFINITY_CPU_LIST", "")
+	useMockNumactl(t, "no-numactl")
+	assert.Equal(t, 0, printCbinaryPlacement(&out, newFixtureSpec([]string{"vespa-proton"})))
+	assert.Equal(t, "vespa-proton-bin\n", out.String())
+
+	// printing does not set up profiling output
+	assert.NoDirExists(t, vespaHome+"/tmp")
+}
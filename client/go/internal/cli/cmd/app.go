@@ -0,0 +1,164 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+	"github.com/vespa-engine/vespa/client/go/internal/vespa/xml"
+)
+
+func newAppCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "app",
+		Short: "Generate and describe services.xml and deployment.xml",
+		Long: `Generate and describe services.xml and deployment.xml.
+
+The clusters and production regions of an application can be written as a
+compact YAML spec:
+
+containers:
+  - id: default
+    search: true
+    document-api: true
+    components:
+      - id: e5
+        type: hugging-face-embedder
+    nodes: 2
+    resources: vcpu=4,memory=16Gb,disk=125Gb
+content:
+  - id: music
+    redundancy: 2
+    documents:
+      - music
+      - type: lyrics
+        mode: streaming
+    nodes: 4
+    groups: 2
+    resources: vcpu=8,memory=32Gb,disk=300Gb
+regions:
+  - aws-us-east-1c
+
+Node counts are given as a number, or as a range like "[2, 4]". Resources are
+given as in 'vespa prod init'. Nodes, resources and regions only apply to
+Vespa Cloud.`,
+		Example: `$ vespa app generate app.yaml myapp/
+$ vespa app describe myapp/`,
+		DisableAutoGenTag: true,
+		SilenceUsage:      false,
+		Args:              cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("invalid command: %s", args[0])
+		},
+	}
+}
+
+func newAppGenerateCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "generate spec-file [application-directory]",
+		Short: "Generate services.xml and deployment.xml from a YAML spec",
+		Long: `Generate services.xml and deployment.xml from a YAML spec.
+
+The spec is read from stdin if spec-file is "-". See 'vespa help app' for the
+format of the spec. deployment.xml is only generated if the spec lists any
+regions.
+
+Any existing services.xml and deployment.xml is backed up before being
+overwritten. Other elements in these files are not preserved, so the generated
+files are best used as a starting point for new applications.`,
+		Example: `$ vespa app generate app.yaml myapp/
+$ vespa app describe old-app/ | vespa app generate - new-app/`,
+		Args:              cobra.RangeArgs(1, 2),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec, err := readSpec(cli, args[0])
+			if err != nil {
+				return err
+			}
+			dir := "."
+			if len(args) > 1 {
+				dir = args[1]
+			}
+			services, err := spec.Services()
+			if err != nil {
+				return err
+			}
+			var deployment *xml.Deployment
+			if len(spec.Regions) > 0 {
+				d, err := spec.Deployment()
+				if err != nil {
+					return err
+				}
+				deployment = &d
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			pkg := vespa.ApplicationPackage{Path: dir}
+			if deployment != nil {
+				if err := writeWithBackup(cli.Stdout, pkg, "deployment.xml", deployment.String()); err != nil {
+					return err
+				}
+			}
+			return writeWithBackup(cli.Stdout, pkg, "services.xml", services.String())
+		},
+	}
+}
+
+func newAppDescribeCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe [application-directory]",
+		Short: "Describe services.xml and deployment.xml as a YAML spec",
+		Long: `Describe services.xml and deployment.xml as a YAML spec.
+
+Only the parts of services.xml and deployment.xml which can be expressed in a
+spec are described. See 'vespa help app' for the format of the spec.`,
+		Example:           `$ vespa app describe myapp/`,
+		Args:              cobra.MaximumNArgs(1),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pkg, err := cli.applicationPackageFrom(args, vespa.PackageOptions{SourceOnly: true})
+			if err != nil {
+				return err
+			}
+			if pkg.IsZip() {
+				return errHint(fmt.Errorf("cannot describe compressed application package '%s'", pkg.Path),
+					"Try running 'mvn clean' and run this command again")
+			}
+			services, err := readServicesXML(pkg)
+			if err != nil {
+				return fmt.Errorf("could not read services.xml: %w", err)
+			}
+			var deployment xml.Deployment
+			if pkg.HasDeploymentSpec() {
+				deployment, err = readDeploymentXML(pkg)
+				if err != nil {
+					return fmt.Errorf("could not read deployment.xml: %w", err)
+				}
+			}
+			return xml.WriteSpec(cli.Stdout, xml.Describe(services, deployment))
+		},
+	}
+}
+
+func readSpec(cli *CLI, name string) (xml.Spec, error) {
+	var r io.Reader = cli.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return xml.Spec{}, err
+		}
+		defer f.Close()
+		r = f
+	}
+	spec, err := xml.ReadSpec(r)
+	if err != nil {
+		return xml.Spec{}, fmt.Errorf("invalid spec %s: %w", name, err)
+	}
+	return spec, nil
+}
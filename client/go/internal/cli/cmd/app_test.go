@@ -0,0 +1,105 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppGenerateAndDescribe(t *testing.T) {
+	spec := `containers:
+  - id: default
+    search: true
+    document-api: true
+    nodes: 2
+content:
+  - id: music
+    redundancy: 2
+    documents:
+      - music
+    nodes: 4
+    groups: 2
+    resources: vcpu=8,memory=32Gb,disk=300Gb
+regions:
+  - aws-us-east-1c
+`
+	specFile := filepath.Join(t.TempDir(), "app.yaml")
+	require.Nil(t, os.WriteFile(specFile, []byte(spec), 0644))
+	appDir := filepath.Join(t.TempDir(), "myapp")
+
+	cli, stdout, _ := newTestCLI(t, "NO_COLOR=true")
+	require.Nil(t, cli.Run("app", "generate", specFile, appDir))
+	assert.Equal(t, "Writing "+filepath.Join(appDir, "deployment.xml")+"\nWriting "+filepath.Join(appDir, "services.xml")+"\n", stdout.String())
+	services, err := os.ReadFile(filepath.Join(appDir, "services.xml"))
+	require.Nil(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<services version="1.0">
+  <container id="default" version="1.0">
+    <document-api></document-api>
+    <search></search>
+    <nodes count="2"></nodes>
+  </container>
+  <content id="music" version="1.0">
+    <min-redundancy>2</min-redundancy>
+    <documents>
+      <document type="music" mode="index"></document>
+    </documents>
+    <nodes count="4" groups="2">
+      <resources vcpu="8" memory="32Gb" disk="300Gb"></resources>
+    </nodes>
+  </content>
+</services>
+`, string(services))
+	deployment, err := os.ReadFile(filepath.Join(appDir, "deployment.xml"))
+	require.Nil(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<deployment version="1.0">
+  <prod>
+    <region>aws-us-east-1c</region>
+  </prod>
+</deployment>
+`, string(deployment))
+
+	stdout.Reset()
+	require.Nil(t, cli.Run("app", "describe", appDir))
+	assert.Equal(t, strings.ReplaceAll(strings.ReplaceAll(spec, "nodes: 2", `nodes: "2"`), "nodes: 4", `nodes: "4"`), stdout.String())
+
+	// Generating from the same spec on stdin leaves files unchanged
+	cli, stdout, _ = newTestCLI(t, "NO_COLOR=true")
+	cli.Stdin = bytes.NewBufferString(spec)
+	require.Nil(t, cli.Run("app", "generate", "-", appDir))
+	assert.Equal(t, "Not writing deployment.xml: File is unchanged\nNot writing services.xml: File is unchanged\n", stdout.String())
+}
+
+func TestAppDescribeWithoutDeployment(t *testing.T) {
+	cli, stdout, _ := newTestCLI(t)
+	require.Nil(t, cli.Run("app", "describe", "testdata/applications/withSource/src/main/application"))
+	assert.Equal(t, `containers:
+  - id: text_search
+    search: true
+    document-api: true
+    components:
+      - id: com.yahoo.language.simple.SimpleLinguistics
+content:
+  - id: msmarco
+    redundancy: 2
+    documents:
+      - msmarco
+`, stdout.String())
+}
+
+func TestAppGenerateInvalidSpec(t *testing.T) {
+	specFile := filepath.Join(t.TempDir(), "app.yaml")
+	require.Nil(t, os.WriteFile(specFile, []byte("content:\n  - id: music\n"), 0644))
+	appDir := t.TempDir()
+	cli, _, stderr := newTestCLI(t, "NO_COLOR=true")
+	require.NotNil(t, cli.Run("app", "generate", specFile, appDir))
+	assert.Equal(t, "Error: invalid spec "+specFile+": content cluster music: at least one document type is required\n", stderr.String())
+	assert.NoFileExists(t, filepath.Join(appDir, "services.xml"))
+}
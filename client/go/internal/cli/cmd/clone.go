@@ -39,16 +39,21 @@ https://github.com/vespa-engine/sample-apps.
 
 By default sample applications are cached in the user's cache directory. This
 directory can be overriden by setting the VESPA_CLI_CACHE_DIR environment
-variable.`,
+variable. The list of sample applications, used for shell completion, is
+refreshed with --list.`,
 		Example:           "$ vespa clone album-recommendation my-app",
 		DisableAutoGenTag: true,
 		SilenceUsage:      true,
+		ValidArgsFunction: cli.completeSampleApps,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if listApps {
 				apps, err := listSampleApps(cli.httpClient)
 				if err != nil {
 					return fmt.Errorf("could not list sample applications: %w", err)
 				}
+				if err := writeSampleAppsList(cli.config.cacheDir, apps); err != nil {
+					cli.printWarning(fmt.Errorf("could not cache sample applications: %w", err))
+				}
 				for _, app := range apps {
 					log.Print(app)
 				}
@@ -4,6 +4,8 @@ package cmd
 import (
 	"encoding/json"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sort"
 	"time"
 
@@ -14,6 +16,43 @@ func listSampleApps(client httputil.Client) ([]string, error) {
 	return listSampleAppsAt("https://api.github.com/repos/vespa-engine/sample-apps/contents/", client)
 }
 
+// sampleAppsListFile is the name of the file in the cache directory holding the last listed sample applications.
+const sampleAppsListFile = "sample-apps.json"
+
+// readSampleAppsList returns the sample applications cached in cacheDir, if any.
+func readSampleAppsList(cacheDir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(cacheDir, sampleAppsListFile))
+	if err != nil {
+		return nil, err
+	}
+	var apps []string
+	if err := json.Unmarshal(data, &apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+// writeSampleAppsList atomically writes apps to a file in cacheDir.
+func writeSampleAppsList(cacheDir string, apps []string) error {
+	data, err := json.Marshal(apps)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(cacheDir, "sample-apps-tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(cacheDir, sampleAppsListFile))
+}
+
 func listSampleAppsAt(url string, client httputil.Client) ([]string, error) {
 	rfs, err := getRepositoryFiles(url, client)
 	if err != nil {
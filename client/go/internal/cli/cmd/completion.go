@@ -0,0 +1,141 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vespa-engine/vespa/client/go/internal/httputil"
+	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+)
+
+// completionTimeout is the maximum time spent on any request made while completing a value. Completion runs on every
+// press of tab, so it must rather return nothing than make the shell hang.
+const completionTimeout = 2 * time.Second
+
+// cappedTimeoutClient is a HTTP client which never waits longer than max for a response.
+type cappedTimeoutClient struct {
+	client httputil.Client
+	max    time.Duration
+}
+
+func (c *cappedTimeoutClient) Do(request *http.Request, timeout time.Duration) (*http.Response, error) {
+	if timeout <= 0 || timeout > c.max {
+		timeout = c.max
+	}
+	return c.client.Do(request, timeout)
+}
+
+// completionClient configures this CLI to use a HTTP client suitable for completion, and returns it.
+func (c *CLI) completionClient() httputil.Client {
+	if _, ok := c.httpClient.(*cappedTimeoutClient); !ok {
+		c.httpClient = &cappedTimeoutClient{client: c.httpClient, max: completionTimeout}
+	}
+	return c.httpClient
+}
+
+// registerCompletions registers completion functions for flags that have dynamic values.
+func (c *CLI) registerCompletions() {
+	c.cmd.RegisterFlagCompletionFunc(targetFlag, c.completeTargets)
+	c.cmd.RegisterFlagCompletionFunc(clusterFlag, c.completeClusters)
+	c.cmd.RegisterFlagCompletionFunc(zoneFlag, c.completeZones)
+	c.cmd.RegisterFlagCompletionFunc(colorFlag, cobra.FixedCompletions([]string{"auto", "never", "always"}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func (c *CLI) completeTargets(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{vespa.TargetLocal, vespa.TargetCloud, vespa.TargetHosted}, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeClusters completes the container clusters of the current deployment. Clusters are only suggested if the
+// target answers quickly.
+func (c *CLI) completeClusters(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c.completionClient()
+	target, err := c.target(targetOptions{noCertificate: true})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	services, err := target.ContainerServices(0)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var clusters []string
+	for _, s := range services {
+		if s.Name != "" {
+			clusters = append(clusters, s.Name)
+		}
+	}
+	return clusters, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeZones completes the zones known to exist in the system of the current target. Targets which are not
+// cloud targets complete zones in Vespa Cloud, as zones are only relevant there.
+func (c *CLI) completeZones(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	targetName := vespa.TargetCloud
+	if tt, err := c.targetType(anyTarget); err == nil && tt.name == vespa.TargetHosted {
+		targetName = tt.name
+	}
+	system, err := c.system(targetName)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var zones []string
+	for _, zone := range system.KnownZones() {
+		zones = append(zones, zone.String())
+	}
+	return zones, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSampleApps completes the name of a sample application in the first argument, and a directory in the second.
+// Sample applications are read from the list cached by 'vespa clone --list', which is refreshed if missing.
+func (c *CLI) completeSampleApps(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	}
+	apps, err := readSampleAppsList(c.config.cacheDir)
+	if err != nil {
+		apps, err = listSampleApps(c.completionClient())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		writeSampleAppsList(c.config.cacheDir, apps)
+	}
+	return apps, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigOption completes the name of a config option, followed by its value if completeValue is true.
+func (c *CLI) completeConfigOption(completeValue bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		switch len(args) {
+		case 0:
+			options := make([]string, 0, len(c.config.flags))
+			for name := range c.config.flags {
+				options = append(options, name)
+			}
+			sort.Strings(options)
+			return options, cobra.ShellCompDirectiveNoFileComp
+		case 1:
+			if completeValue {
+				return c.completeConfigValue(cmd, args[0], toComplete)
+			}
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func (c *CLI) completeConfigValue(cmd *cobra.Command, option, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch option {
+	case targetFlag:
+		return c.completeTargets(cmd, nil, toComplete)
+	case clusterFlag:
+		return c.completeClusters(cmd, nil, toComplete)
+	case zoneFlag:
+		return c.completeZones(cmd, nil, toComplete)
+	case colorFlag:
+		return []string{"auto", "never", "always"}, cobra.ShellCompDirectiveNoFileComp
+	case quietFlag:
+		return []string{"true", "false"}, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,114 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/mock"
+)
+
+// complete runs the hidden completion command and returns the completed values, excluding the directive
+func complete(t *testing.T, cli *CLI, stdout *bytes.Buffer, args ...string) []string {
+	t.Helper()
+	stdout.Reset()
+	cli.cmd.SetOut(stdout)
+	cli.cmd.SetErr(io.Discard)
+	require.Nil(t, cli.Run(append([]string{"__complete"}, args...)...))
+	var values []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		if !strings.HasPrefix(line, ":") {
+			values = append(values, line)
+		}
+	}
+	return values
+}
+
+func TestCompleteTargets(t *testing.T) {
+	cli, stdout, _ := newTestCLI(t)
+	assert.Equal(t, []string{"local", "cloud", "hosted"}, complete(t, cli, stdout, "status", "--target", ""))
+}
+
+func TestCompleteClusters(t *testing.T) {
+	cli, stdout, _ := newTestCLI(t)
+	client := &mock.HTTPClient{}
+	cli.httpClient = client
+	mockServiceStatus(client, "foo", "bar")
+	assert.Equal(t, []string{"bar", "foo"}, complete(t, cli, stdout, "query", "--cluster", ""))
+
+	cli, stdout, _ = newTestCLI(t)
+	client = &mock.HTTPClient{}
+	cli.httpClient = client
+	client.NextStatus(500)
+	assert.Nil(t, complete(t, cli, stdout, "query", "--cluster", ""))
+}
+
+func TestCompleteZones(t *testing.T) {
+	cli, stdout, _ := newTestCLI(t)
+	assert.Equal(t, []string{"dev.aws-us-east-1c", "dev.gcp-us-central1-f", "perf.aws-us-east-1c"},
+		complete(t, cli, stdout, "deploy", "--zone", ""))
+
+	cli, stdout, _ = newTestCLI(t)
+	assert.Equal(t, []string{"dev.us-east-1"}, complete(t, cli, stdout, "deploy", "-t", "hosted", "--zone", ""))
+}
+
+func TestCompleteSampleApps(t *testing.T) {
+	cli, stdout, _ := newTestCLI(t)
+	require.Nil(t, writeSampleAppsList(cli.config.cacheDir, []string{"album-recommendation", "text-search"}))
+	assert.Equal(t, []string{"album-recommendation", "text-search"}, complete(t, cli, stdout, "clone", ""))
+
+	assert.Nil(t, complete(t, cli, stdout, "clone", "text-search", ""))
+	assert.Equal(t, ":16\n", stdout.String()) // ShellCompDirectiveFilterDirs
+}
+
+func TestCompleteSampleAppsWithoutCache(t *testing.T) {
+	cli, stdout, _ := newTestCLI(t)
+	client := &mock.HTTPClient{}
+	cli.httpClient = client
+	client.NextResponseString(200, `[{"path": "album-recommendation", "name": "album-recommendation", "type": "dir"}]`)
+	assert.Equal(t, []string{"album-recommendation"}, complete(t, cli, stdout, "clone", ""))
+
+	apps, err := readSampleAppsList(cli.config.cacheDir)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"album-recommendation"}, apps)
+}
+
+func TestCompleteConfigOptions(t *testing.T) {
+	cli, stdout, _ := newTestCLI(t)
+	assert.Equal(t, []string{"application", "cluster", "color", "instance", "quiet", "target", "via", "zone"},
+		complete(t, cli, stdout, "config", "set", ""))
+
+	assert.Equal(t, []string{"auto", "never", "always"}, complete(t, cli, stdout, "config", "set", "color", ""))
+
+	assert.Equal(t, []string{"true", "false"}, complete(t, cli, stdout, "config", "set", "quiet", ""))
+
+	assert.Nil(t, complete(t, cli, stdout, "config", "set", "application", ""))
+
+	assert.Nil(t, complete(t, cli, stdout, "config", "unset", "color", ""))
+}
+
+type timeoutRecordingClient struct{ timeout time.Duration }
+
+func (c *timeoutRecordingClient) Do(request *http.Request, timeout time.Duration) (*http.Response, error) {
+	c.timeout = timeout
+	return nil, http.ErrHandlerTimeout
+}
+
+func TestCappedTimeoutClient(t *testing.T) {
+	recorder := &timeoutRecordingClient{}
+	client := &cappedTimeoutClient{client: recorder, max: time.Second}
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.Nil(t, err)
+	client.Do(req, time.Minute)
+	assert.Equal(t, time.Second, recorder.timeout)
+	client.Do(req, time.Millisecond)
+	assert.Equal(t, time.Millisecond, recorder.timeout)
+	client.Do(req, 0)
+	assert.Equal(t, time.Second, recorder.timeout)
+}
@@ -7,8 +7,10 @@ package cmd
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
@@ -20,11 +22,15 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/vespa-engine/vespa/client/go/internal/cli/config"
+	"github.com/vespa-engine/vespa/client/go/internal/sshtunnel"
 	"github.com/vespa-engine/vespa/client/go/internal/vespa"
 )
 
 const (
 	configFile = "config.yaml"
+	// endpointPrefix is the prefix of config keys holding the URLs of container clusters in a custom target. The URL
+	// of a cluster named foo is stored as endpoint.foo.
+	endpointPrefix = "endpoint."
 )
 
 func newConfigCmd() *cobra.Command {
@@ -49,9 +55,14 @@ Vespa CLI chooses the value for a given option in the following order, from
 most to least preferred:
 
 1. Flag value specified on the command line
-2. Local config value
-3. Global config value
-4. Default value
+2. Environment variable VESPA_CLI_<OPTION>, e.g. VESPA_CLI_TARGET for target
+3. Local config value
+4. Global config value
+5. Default value
+
+Use 'vespa config get --show-origin' to see where the value of each option
+comes from, and 'vespa config validate' to check configuration files and
+environment variables for unknown options and invalid values.
 
 The following global flags/options can be configured:
 
@@ -102,13 +113,43 @@ e.g. vespa deploy or vespa query. Possible values are:
           automatically discovered and can be selected with the cluster option.
 - hosted: Connect to hosted Vespa (reserved for internal use)
 - *url*:  Connect to a platform running at given URL. This instructs the command
-          you're running to target a concrete URL. The cluster option cannot be
-          used with this target.
+          you're running to target a concrete URL. The cluster option can only
+          be used with this target if cluster endpoints are configured, see
+          endpoint.<cluster> below.
+- k8s://<namespace>/<release>:
+          Connect to a Vespa platform installed in Kubernetes. Services of the
+          release are discovered through the Kubernetes API, using the
+          kubeconfig in $KUBECONFIG or ~/.kube/config, and are reached through
+          port forwarding. The config server is the service exposing port
+          19071. Container clusters are services exposing port 8080 or a port
+          named http, and are chosen with the cluster option. The namespace
+          defaults to that of the kubeconfig context, and a context can be
+          chosen with e.g. k8s://vespa/my-release?context=my-context.
 
 Authentication is configured automatically for the cloud and hosted targets. To
 set a custom private key and certificate, e.g. for use with a self-hosted Vespa
 installation configured with mTLS, see the documentation of 'vespa auth cert'.
 
+endpoint.<cluster>
+
+Specifies the URL of the container cluster named <cluster>, when the target is
+an URL. This allows using self-hosted Vespa installations where container
+clusters are served at different URLs, e.g. behind separate load balancers,
+without discovering them through the config server. The URL given as target is
+then only used for deployment. Container clusters are chosen with the cluster
+option. This can also be set with the VESPA_CLI_ENDPOINTS environment variable,
+which takes precedence. Example: vespa config set endpoint.feed https://feed.example.com
+
+via
+
+Specifies an SSH host, e.g. a bastion, to connect to the target through. This
+allows using Vespa installations which are only reachable from that host,
+without setting up port forwarding manually. The host is given as
+ssh://[user@]host[:port]. The host key must be present in ~/.ssh/known_hosts
+or /etc/ssh/ssh_known_hosts, and authentication uses the keys of the SSH agent
+given by SSH_AUTH_SOCK. Hosts and ports of the target are resolved on the SSH
+host. This has no default value. Example: ssh://deployer@bastion.example.com
+
 zone
 
 Specifies a custom zone to use when connecting to a Vespa Cloud application.
@@ -146,6 +187,7 @@ $ vespa config set --local zone perf.us-north-1`,
 		DisableAutoGenTag: true,
 		SilenceUsage:      true,
 		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: cli.completeConfigOption(true),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config := cli.config
 			if localArg {
@@ -182,6 +224,7 @@ $ vespa config unset --local application`,
 		DisableAutoGenTag: true,
 		SilenceUsage:      true,
 		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cli.completeConfigOption(false),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config := cli.config
 			if localArg {
@@ -200,8 +243,42 @@ $ vespa config unset --local application`,
 	return cmd
 }
 
+func newConfigValidateCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Validate configuration files and environment variables",
+		Long: `Validate configuration files and environment variables.
+
+This checks the global configuration, the local configuration of the
+application in the working directory and environment variables overriding
+options. Unknown options and invalid values are reported.`,
+		Example:           "$ vespa config validate",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		Args:              cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems := cli.config.validate()
+			if cli.config.local != nil {
+				problems = append(problems, cli.config.local.validate()...)
+			}
+			problems = append(problems, cli.config.validateEnvironment()...)
+			if len(problems) > 0 {
+				for _, problem := range problems {
+					fmt.Fprintln(cli.Stderr, problem)
+				}
+				return errHint(fmt.Errorf("invalid configuration"), "Use 'vespa config set' or 'vespa config unset' to correct options")
+			}
+			cli.printSuccess("Configuration is valid")
+			return nil
+		},
+	}
+}
+
 func newConfigGetCmd(cli *CLI) *cobra.Command {
-	var localArg bool
+	var (
+		localArg   bool
+		showOrigin bool
+	)
 	cmd := &cobra.Command{
 		Use:   "get [option-name]",
 		Short: "Show given configuration option, or all configuration options",
@@ -210,11 +287,16 @@ func newConfigGetCmd(cli *CLI) *cobra.Command {
 By default this command prints the effective configuration for the current
 application, i.e. it takes into account any local configuration located in
 [working-directory]/.vespa.
+
+With --show-origin, the source of each value is shown: a flag, an
+environment variable, the local or global config file, or the default value.
 `,
 		Example: `$ vespa config get
 $ vespa config get target
-$ vespa config get --local`,
+$ vespa config get --local
+$ vespa config get --show-origin`,
 		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: cli.completeConfigOption(false),
 		DisableAutoGenTag: true,
 		SilenceUsage:      true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -228,15 +310,16 @@ $ vespa config get --local`,
 			}
 			if len(args) == 0 { // Print all values
 				for _, option := range config.list(!localArg) {
-					config.printOption(option)
+					config.printOption(option, showOrigin)
 				}
 			} else {
-				return config.printOption(args[0])
+				return config.printOption(args[0], showOrigin)
 			}
 			return nil
 		},
 	}
 	cmd.Flags().BoolVarP(&localArg, "local", "l", false, "Show only local configuration, if any")
+	cmd.Flags().BoolVarP(&showOrigin, "show-origin", "", false, "Show where the value of each option comes from")
 	return cmd
 }
 
@@ -360,6 +443,11 @@ func (c *Config) application() (vespa.ApplicationID, error) {
 	return application, nil
 }
 
+func (c *Config) via() string {
+	via, _ := c.get(viaFlag)
+	return via
+}
+
 func (c *Config) cluster() string {
 	cluster, _ := c.get(clusterFlag)
 	return cluster
@@ -573,6 +661,55 @@ func (c *Config) writeSessionID(app vespa.ApplicationID, sessionID int64) error
 	return os.WriteFile(sessionPath, []byte(fmt.Sprintf("%d\n", sessionID)), 0600)
 }
 
+// deployedPackage describes an application package deployed to a config server.
+type deployedPackage struct {
+	Hash      string `json:"hash"`
+	SessionID int64  `json:"sessionId"`
+}
+
+// readDeployedPackage returns the application package last deployed by us to the config server at deployURL.
+func (c *Config) readDeployedPackage(app vespa.ApplicationID, deployURL string) (deployedPackage, bool) {
+	packages, err := c.readDeployedPackages(app)
+	if err != nil {
+		return deployedPackage{}, false
+	}
+	pkg, ok := packages[deployURL]
+	return pkg, ok
+}
+
+func (c *Config) writeDeployedPackage(app vespa.ApplicationID, deployURL string, pkg deployedPackage) error {
+	packages, err := c.readDeployedPackages(app)
+	if err != nil {
+		packages = make(map[string]deployedPackage)
+	}
+	packages[deployURL] = pkg
+	data, err := json.MarshalIndent(packages, "", "  ")
+	if err != nil {
+		return err
+	}
+	path, err := c.applicationFilePath(app, "deployed_packages.json")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+func (c *Config) readDeployedPackages(app vespa.ApplicationID) (map[string]deployedPackage, error) {
+	path, err := c.applicationFilePath(app, "deployed_packages.json")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var packages map[string]deployedPackage
+	if err := json.Unmarshal(data, &packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
 func (c *Config) applicationFilePath(app vespa.ApplicationID, name string) (string, error) {
 	appDir := filepath.Join(c.homeDir, app.String())
 	if err := os.MkdirAll(appDir, 0700); err != nil {
@@ -587,16 +724,47 @@ func (c *Config) isEmpty() bool { return len(c.config.Keys()) == 0 }
 // haven't been set.
 func (c *Config) list(includeUnset bool) []string {
 	if !includeUnset {
-		return c.config.Keys()
+		var options []string
+		for _, key := range c.config.Keys() {
+			if !strings.HasPrefix(key, aliasPrefix) {
+				options = append(options, key)
+			}
+		}
+		return options
 	}
 	var flags []string
 	for k := range c.flags {
 		flags = append(flags, k)
 	}
+	for cluster := range c.clusterURLs() {
+		flags = append(flags, endpointPrefix+cluster)
+	}
 	sort.Strings(flags)
 	return flags
 }
 
+// prefixed returns the values of all keys starting with prefix, including local ones which take precedence, keyed on
+// the remainder of the key.
+func (c *Config) prefixed(prefix string) map[string]string {
+	values := make(map[string]string)
+	for _, config := range []*Config{c, c.local} {
+		if config == nil {
+			continue
+		}
+		for _, key := range config.config.Keys() {
+			if name, ok := strings.CutPrefix(key, prefix); ok {
+				if value, _ := config.config.Get(key); value != "" {
+					values[name] = value
+				}
+			}
+		}
+	}
+	return values
+}
+
+// clusterURLs returns the configured URLs of container clusters, keyed on cluster name.
+func (c *Config) clusterURLs() map[string]string { return c.prefixed(endpointPrefix) }
+
 // flagValue returns the set value and default value of the named flag.
 func (c *Config) flagValue(name string) (string, string, bool) {
 	f, ok := c.flags[name]
@@ -615,100 +783,194 @@ func (c *Config) getNonEmpty(option string) (string, bool) {
 	return v, ok
 }
 
-// get returns the value associated with option, from the most preferred source in the following order: flag > local
-// config > global config.
+// get returns the value associated with option, from the most preferred source in the following order: flag >
+// environment > local config > global config.
 func (c *Config) get(option string) (string, bool) {
+	value, _, ok := c.lookup(option)
+	return value, ok
+}
+
+// optionEnv returns the environment variable which overrides option. Options with a prefix, like endpoint.<cluster>,
+// cannot be overridden by environment variables.
+func optionEnv(option string) (string, bool) {
+	if strings.HasPrefix(option, endpointPrefix) {
+		return "", false
+	}
+	return "VESPA_CLI_" + strings.ToUpper(option), true
+}
+
+// lookup returns the value associated with option, as in get, and a description of where the value came from.
+func (c *Config) lookup(option string) (string, string, bool) {
 	flagValue, flagDefault, changed := c.flagValue(option)
 	// explicit flag value always takes precedence over everything else
 	if changed {
-		return flagValue, true
+		return flagValue, "flag --" + option, true
+	}
+	// ... then environment
+	if env, ok := optionEnv(option); ok && c.environment[env] != "" {
+		return c.environment[env], "environment variable " + env, true
 	}
 	// ... then local config, if option is explicitly defined there
 	if c.local != nil {
 		if value, ok := c.local.getNonEmpty(option); ok {
-			return value, ok
+			return value, "local config " + c.local.configPath(), ok
 		}
 	}
 	// ... then global config
 	if v, ok := c.getNonEmpty(option); ok {
-		return v, ok
+		return v, "config " + c.configPath(), ok
 	}
 	// ... then finally default flag value, if any
-	return flagDefault, flagDefault != ""
+	return flagDefault, "default", flagDefault != ""
 }
 
+// configPath returns the path to the config file of this configuration.
+func (c *Config) configPath() string { return filepath.Join(c.homeDir, configFile) }
+
 func (c *Config) set(option, value string) error {
+	value, err := normalizeOption(option, value)
+	if err != nil {
+		return err
+	}
+	c.config.Set(option, value)
+	return nil
+}
+
+// normalizeOption validates value of option, and returns it in the form it should be stored.
+func normalizeOption(option, value string) (string, error) {
+	if cluster, ok := strings.CutPrefix(option, endpointPrefix); ok {
+		if !namePattern.MatchString(cluster) {
+			return "", fmt.Errorf("invalid cluster name: %q", cluster)
+		}
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("invalid endpoint of cluster %s: %q: must be an http or https URL", cluster, value)
+		}
+		return value, nil
+	}
 	switch option {
 	case targetFlag:
 		switch value {
 		case vespa.TargetLocal, vespa.TargetCloud, vespa.TargetHosted:
-			c.config.Set(option, value)
-			return nil
+			return value, nil
 		}
 		if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
-			c.config.Set(option, value)
-			return nil
+			return value, nil
+		}
+		if strings.HasPrefix(value, k8sScheme) {
+			if _, _, err := parseK8sTarget(value); err != nil {
+				return "", err
+			}
+			return value, nil
 		}
 	case applicationFlag:
 		app, err := vespa.ApplicationFromString(value)
 		if err != nil {
-			return err
+			return "", err
 		}
-		c.config.Set(option, app.String())
-		return nil
-	case instanceFlag:
-		c.config.Set(option, value)
-		return nil
-	case clusterFlag:
-		c.config.Set(clusterFlag, value)
-		return nil
+		return app.String(), nil
+	case instanceFlag, clusterFlag:
+		return value, nil
 	case colorFlag:
 		switch value {
 		case "auto", "never", "always":
-			c.config.Set(option, value)
-			return nil
+			return value, nil
 		}
 	case quietFlag:
 		switch value {
 		case "true", "false":
-			c.config.Set(option, value)
-			return nil
+			return value, nil
+		}
+	case viaFlag:
+		if _, err := sshtunnel.Parse(value); err != nil {
+			return "", err
 		}
+		return value, nil
 	case zoneFlag:
 		if _, err := vespa.ZoneFromString(value); err != nil {
-			return err
+			return "", err
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid option or value: %s = %s", option, value)
+}
+
+// validate returns the problems found in this configuration, i.e. unknown options and invalid values. Local
+// configuration is not included.
+func (c *Config) validate() []string {
+	var problems []string
+	for _, key := range c.config.Keys() {
+		value, _ := c.config.Get(key)
+		if name, ok := strings.CutPrefix(key, aliasPrefix); ok {
+			if !namePattern.MatchString(name) {
+				problems = append(problems, fmt.Sprintf("%s: invalid alias name: %s", c.configPath(), name))
+			} else if _, err := splitWords(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid alias %s: %s", c.configPath(), name, err))
+			}
+			continue
+		}
+		if err := c.checkOption(key); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: unknown option: %s", c.configPath(), key))
+			continue
+		}
+		if value == "" {
+			continue // Treated as unset
+		}
+		if _, err := normalizeOption(key, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid value of %s: %s", c.configPath(), key, err))
+		}
+	}
+	return problems
+}
+
+// validateEnvironment returns the problems found in environment variables overriding options.
+func (c *Config) validateEnvironment() []string {
+	var problems []string
+	for _, option := range c.list(true) {
+		env, ok := optionEnv(option)
+		if !ok || c.environment[env] == "" {
+			continue
+		}
+		if _, err := normalizeOption(option, c.environment[env]); err != nil {
+			problems = append(problems, fmt.Sprintf("environment variable %s: invalid value: %s", env, err))
 		}
-		c.config.Set(option, value)
-		return nil
 	}
-	return fmt.Errorf("invalid option or value: %s = %s", option, value)
+	return problems
 }
 
 func (c *Config) unset(option string) error {
 	if err := c.checkOption(option); err != nil {
-		return err
+		if _, ok := c.config.Get(option); !ok {
+			return err
+		}
+		// Allow removing unknown options, as reported by validate
 	}
 	c.config.Del(option)
 	return nil
 }
 
 func (c *Config) checkOption(option string) error {
+	if cluster, ok := strings.CutPrefix(option, endpointPrefix); ok && namePattern.MatchString(cluster) {
+		return nil
+	}
 	if _, ok := c.flags[option]; !ok {
 		return fmt.Errorf("invalid option: %s", option)
 	}
 	return nil
 }
 
-func (c *Config) printOption(option string) error {
+func (c *Config) printOption(option string, showOrigin bool) error {
 	if err := c.checkOption(option); err != nil {
 		return err
 	}
-	value, ok := c.get(option)
+	value, origin, ok := c.lookup(option)
+	faintColor := color.New(color.FgWhite, color.Faint)
 	if !ok {
-		faintColor := color.New(color.FgWhite, color.Faint)
 		value = faintColor.Sprint("<unset>")
 	} else {
 		value = color.CyanString(value)
+		if showOrigin {
+			value += faintColor.Sprint(" (" + origin + ")")
+		}
 	}
 	log.Printf("%s = %s", option, value)
 	return nil
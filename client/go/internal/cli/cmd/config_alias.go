@@ -0,0 +1,272 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+)
+
+// aliasPrefix is the prefix of config keys holding aliases. An alias named foo is stored as alias.foo.
+const aliasPrefix = "alias."
+
+// namePattern matches valid names of aliases and clusters.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func newConfigAliasCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "alias",
+		Short: "Manage aliases for query and curl arguments",
+		Long: `Manage aliases for query and curl arguments.
+
+An alias names a list of arguments to 'vespa query' or 'vespa curl'. When
+an argument of the form @name is given to 'vespa query', it is replaced by the
+arguments of the alias. Arguments following the alias override parameters set
+by the alias. For 'vespa curl', the path may be given as @name.
+
+Like other options, aliases can be set globally or locally for the application
+in the working directory. Local aliases take precedence over global ones.`,
+		Example: `$ vespa config alias set top "yql=select * from music where true" hits=3 ranking=popularity
+$ vespa query @top hits=5
+$ vespa config alias set status /ApplicationStatus
+$ vespa curl @status`,
+		DisableAutoGenTag: true,
+		SilenceUsage:      false,
+		Args:              cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("invalid command: %s", args[0])
+		},
+	}
+}
+
+func newConfigAliasSetCmd(cli *CLI) *cobra.Command {
+	var localArg bool
+	cmd := &cobra.Command{
+		Use:   "set alias-name arguments...",
+		Short: "Set an alias",
+		Long: `Set an alias.
+
+The arguments are stored as given. If only one argument is given, it is split
+into multiple arguments in the same way as a shell would, so that a complete
+invocation can be quoted.`,
+		Example: `$ vespa config alias set top "yql=select * from music where true" hits=3
+$ vespa config alias set top "'yql=select * from music where true' hits=3"
+$ vespa config alias set --local status /ApplicationStatus
+$ vespa config alias set -- verbose-status "-v /ApplicationStatus"`,
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		Args:              cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := cli.aliasConfig(localArg)
+			if err != nil {
+				return err
+			}
+			if err := config.setAlias(args[0], args[1:]); err != nil {
+				return err
+			}
+			return config.write()
+		},
+	}
+	cmd.Flags().BoolVarP(&localArg, "local", "l", false, "Write alias to local configuration, i.e. for the current application")
+	return cmd
+}
+
+func newConfigAliasUnsetCmd(cli *CLI) *cobra.Command {
+	var localArg bool
+	cmd := &cobra.Command{
+		Use:               "unset alias-name",
+		Short:             "Unset an alias",
+		Example:           "$ vespa config alias unset top",
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := cli.aliasConfig(localArg)
+			if err != nil {
+				return err
+			}
+			if _, ok := config.config.Get(aliasPrefix + args[0]); !ok {
+				return fmt.Errorf("no such alias: %s", args[0])
+			}
+			config.config.Del(aliasPrefix + args[0])
+			return config.write()
+		},
+	}
+	cmd.Flags().BoolVarP(&localArg, "local", "l", false, "Unset alias in local configuration, i.e. for the current application")
+	return cmd
+}
+
+func newConfigAliasListCmd(cli *CLI) *cobra.Command {
+	var localArg bool
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List aliases",
+		Long: `List aliases.
+
+By default this command lists the aliases available for the current
+application, i.e. both global aliases and local aliases set in
+[working-directory]/.vespa.`,
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		Args:              cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			aliases := cli.config.aliases()
+			if localArg {
+				aliases = cli.config.local.aliases()
+			}
+			names := make([]string, 0, len(aliases))
+			for name := range aliases {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				log.Printf("@%s = %s", name, color.CyanString(aliases[name]))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&localArg, "local", "l", false, "List only local aliases")
+	return cmd
+}
+
+// aliasConfig returns the configuration where aliases should be written.
+func (c *CLI) aliasConfig(local bool) (*Config, error) {
+	if !local {
+		return c.config, nil
+	}
+	// Need an application package in working directory to allow local configuration
+	if _, err := c.applicationPackageFrom(nil, vespa.PackageOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to write local configuration: %w", err)
+	}
+	return c.config.local, nil
+}
+
+// setAlias stores args under alias name. A single argument is split into words.
+func (c *Config) setAlias(name string, args []string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid alias name: %q: must consist of letters, digits, '-' and '_'", name)
+	}
+	if len(args) == 1 {
+		words, err := splitWords(args[0])
+		if err != nil {
+			return err
+		}
+		args = words
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("alias %s must have at least one argument", name)
+	}
+	c.config.Set(aliasPrefix+name, joinWords(args))
+	return nil
+}
+
+// aliases returns all aliases in this configuration, including local ones which take precedence.
+func (c *Config) aliases() map[string]string { return c.prefixed(aliasPrefix) }
+
+// expandAlias returns the arguments of the alias referenced by arg, if arg has the form @name.
+func (c *Config) expandAlias(arg string) ([]string, bool, error) {
+	name, ok := strings.CutPrefix(arg, "@")
+	if !ok {
+		return nil, false, nil
+	}
+	value, ok := c.aliases()[name]
+	if !ok {
+		return nil, false, errHint(fmt.Errorf("no such alias: %s", name), "Try 'vespa config alias list' to list aliases")
+	}
+	words, err := splitWords(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid alias %s: %w", name, err)
+	}
+	return words, true, nil
+}
+
+// expandAliases replaces all arguments of the form @name with the arguments of the alias.
+func (c *Config) expandAliases(args []string) ([]string, error) {
+	var expanded []string
+	for _, arg := range args {
+		words, ok, err := c.expandAlias(arg)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			expanded = append(expanded, words...)
+		} else {
+			expanded = append(expanded, arg)
+		}
+	}
+	return expanded, nil
+}
+
+// splitWords splits s into words like a POSIX shell, supporting single quotes, double quotes and backslash escapes.
+func splitWords(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if escaped {
+		return nil, fmt.Errorf("unterminated escape in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// joinWords joins words such that splitWords returns the same words.
+func joinWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		if w != "" && !strings.ContainsAny(w, " \t\n'\"\\") {
+			quoted[i] = w
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
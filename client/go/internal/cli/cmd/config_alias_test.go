@@ -0,0 +1,100 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/mock"
+)
+
+func TestSplitWords(t *testing.T) {
+	words, err := splitWords(`yql="select * from music where true" hits=3  'ranking=my profile' a\ b`)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"yql=select * from music where true", "hits=3", "ranking=my profile", "a b"}, words)
+
+	_, err = splitWords(`yql="select`)
+	assert.NotNil(t, err)
+
+	args := []string{"yql=select * from music where title contains 'foo'", "", `a"b\c`, "plain"}
+	words, err = splitWords(joinWords(args))
+	require.Nil(t, err)
+	assert.Equal(t, args, words)
+}
+
+func TestConfigAlias(t *testing.T) {
+	configHome := t.TempDir()
+	assertConfigCommand(t, configHome, "", "config", "alias", "set", "top", "yql=select * from music where true", "hits=3")
+	assertConfigCommand(t, configHome, "", "config", "alias", "set", "status", "/ApplicationStatus")
+	assertConfigCommand(t, configHome, "@status = /ApplicationStatus\n@top = 'yql=select * from music where true' hits=3\n",
+		"config", "alias", "list")
+
+	// Aliases are not config options
+	assertConfigCommand(t, configHome, `application = <unset>
+cluster = <unset>
+color = auto
+instance = <unset>
+quiet = false
+target = local
+via = <unset>
+zone = <unset>
+`, "config", "get")
+
+	assertConfigCommand(t, configHome, "", "config", "alias", "unset", "status")
+	assertConfigCommand(t, configHome, "@top = 'yql=select * from music where true' hits=3\n", "config", "alias", "list")
+
+	cli, _, stderr := newTestCLI(t, "VESPA_CLI_HOME="+configHome)
+	assert.NotNil(t, cli.Run("config", "alias", "unset", "status"))
+	assert.Equal(t, "Error: no such alias: status\n", stderr.String())
+	stderr.Reset()
+	assert.NotNil(t, cli.Run("config", "alias", "set", "no/slash", "hits=1"))
+	assert.Equal(t, "Error: invalid alias name: \"no/slash\": must consist of letters, digits, '-' and '_'\n", stderr.String())
+}
+
+func TestConfigAliasLocal(t *testing.T) {
+	configHome := t.TempDir()
+	assertConfigCommand(t, configHome, "", "config", "alias", "set", "top", "hits=3")
+	assertConfigCommand(t, configHome, "", "config", "alias", "set", "all", "'yql=select * from music where true'")
+
+	_, rootDir := mock.ApplicationPackageDir(t, false, false)
+	wd, err := os.Getwd()
+	require.Nil(t, err)
+	t.Cleanup(func() { os.Chdir(wd) })
+	require.Nil(t, os.Chdir(rootDir))
+	assertConfigCommand(t, configHome, "", "config", "alias", "set", "--local", "top", "hits=5")
+	assertConfigCommand(t, configHome, "@all = 'yql=select * from music where true'\n@top = hits=5\n", "config", "alias", "list")
+	assertConfigCommand(t, configHome, "@top = hits=5\n", "config", "alias", "list", "--local")
+
+	localConfig, err := os.ReadFile(filepath.Join(rootDir, ".vespa", "config.yaml"))
+	require.Nil(t, err)
+	assert.Equal(t, "alias.top: hits=5\n", string(localConfig))
+}
+
+func TestQueryAlias(t *testing.T) {
+	configHome := t.TempDir()
+	assertConfigCommand(t, configHome, "", "config", "alias", "set", "top", "select from sources * where title contains 'foo'", "hits=3")
+
+	client := &mock.HTTPClient{}
+	client.NextResponseString(200, "{\"query\":\"result\"}")
+	cli, _, stderr := newTestCLI(t, "VESPA_CLI_HOME="+configHome)
+	cli.httpClient = client
+	assert.Nil(t, cli.Run("-t", "http://127.0.0.1:8080", "query", "@top", "hits=5"))
+	assert.Equal(t,
+		"hits=5&timeout=10s&yql=select+from+sources+%2A+where+title+contains+%27foo%27",
+		client.LastRequest.URL.RawQuery)
+
+	assert.NotNil(t, cli.Run("-t", "http://127.0.0.1:8080", "query", "@missing"))
+	assert.Equal(t, "Error: no such alias: missing\nHint: Try 'vespa config alias list' to list aliases\n", stderr.String())
+}
+
+func TestCurlAlias(t *testing.T) {
+	configHome := t.TempDir()
+	assertConfigCommand(t, configHome, "", "config", "alias", "set", "--", "status", "-v /ApplicationStatus")
+
+	cli, stdout, _ := newTestCLI(t, "VESPA_CLI_HOME="+configHome)
+	assert.Nil(t, cli.Run("-t", "http://127.0.0.1:8080", "curl", "-n", "--", "--data-binary", "@file.json", "@status"))
+	assert.Equal(t, "curl --data-binary @file.json -v http://127.0.0.1:8080/ApplicationStatus\n", stdout.String())
+}
@@ -34,8 +34,20 @@ func TestConfig(t *testing.T) {
 	assertConfigCommand(t, configHome, "", "config", "set", "target", "http://127.0.0.1:8080")
 	assertConfigCommand(t, configHome, "", "config", "set", "target", "https://127.0.0.1")
 	assertConfigCommand(t, configHome, "target = https://127.0.0.1\n", "config", "get", "target")
+	assertConfigCommand(t, configHome, "", "config", "set", "target", "k8s://vespa/my-release?context=dev")
+	assertConfigCommand(t, configHome, "target = k8s://vespa/my-release?context=dev\n", "config", "get", "target")
+	assertConfigCommandErr(t, configHome, "Error: invalid kubernetes target: \"k8s://vespa\": must be on the form k8s://<namespace>/<release>\n",
+		"config", "set", "target", "k8s://vespa")
+	assertConfigCommand(t, configHome, "", "config", "set", "target", "https://127.0.0.1")
 	assertConfigCommand(t, configHome, "target = local\n", "config", "get", "-t", "local", "target")
 
+	// via
+	assertConfigCommand(t, configHome, "", "config", "set", "via", "ssh://deployer@bastion.example.com")
+	assertConfigCommand(t, configHome, "via = ssh://deployer@bastion.example.com\n", "config", "get", "via")
+	assertConfigCommandErr(t, configHome, "Error: invalid ssh tunnel: \"bastion\": must be on the form ssh://[user@]host[:port]\n",
+		"config", "set", "via", "bastion")
+	assertConfigCommand(t, configHome, "", "config", "unset", "via")
+
 	// application
 	assertConfigCommandErr(t, configHome, "Error: invalid application: \"foo\"\n", "config", "set", "application", "foo")
 	assertConfigCommand(t, configHome, "application = <unset>\n", "config", "get", "application")
@@ -113,6 +125,7 @@ color = auto
 instance = foo
 quiet = false
 target = cloud
+via = <unset>
 zone = <unset>
 `, "config", "get")
 
@@ -127,6 +140,95 @@ zone = <unset>
 	assertConfigCommand(t, configHome, "target = cloud\n", "config", "get", "target")
 }
 
+func TestConfigEnvironment(t *testing.T) {
+	configHome := t.TempDir()
+	assertConfigCommand(t, configHome, "", "config", "set", "target", "hosted")
+	env := []string{"VESPA_CLI_TARGET=cloud", "VESPA_CLI_ZONE=perf.aws-us-east-1c", "VESPA_CLI_QUIET="}
+	assertEnvConfigCommand(t, configHome, "target = cloud\n", env, "config", "get", "target")                // environment overrides config
+	assertEnvConfigCommand(t, configHome, "target = local\n", env, "config", "get", "-t", "local", "target") // flag overrides environment
+	assertEnvConfigCommand(t, configHome, "zone = perf.aws-us-east-1c\n", env, "config", "get", "zone")
+	assertEnvConfigCommand(t, configHome, "quiet = false\n", env, "config", "get", "quiet") // empty value is ignored
+
+	assertEnvConfigCommand(t, configHome, `application = <unset>
+cluster = <unset>
+color = auto (default)
+instance = <unset>
+quiet = false (default)
+target = cloud (environment variable VESPA_CLI_TARGET)
+via = <unset>
+zone = perf.aws-us-east-1c (environment variable VESPA_CLI_ZONE)
+`, env, "config", "get", "--show-origin")
+	assertEnvConfigCommand(t, configHome, "target = hosted (config "+filepath.Join(configHome, "config.yaml")+")\n",
+		nil, "config", "get", "--show-origin", "target")
+	assertEnvConfigCommand(t, configHome, "target = local (flag --target)\n", nil, "config", "get", "--show-origin", "-t", "local", "target")
+
+	// Local config
+	_, rootDir := mock.ApplicationPackageDir(t, false, false)
+	wd, err := os.Getwd()
+	require.Nil(t, err)
+	t.Cleanup(func() { os.Chdir(wd) })
+	require.Nil(t, os.Chdir(rootDir))
+	assertConfigCommand(t, configHome, "", "config", "set", "--local", "target", "local")
+	assertEnvConfigCommand(t, configHome, "target = local (local config "+filepath.Join(".vespa", "config.yaml")+")\n",
+		nil, "config", "get", "--show-origin", "target")
+	assertEnvConfigCommand(t, configHome, "target = cloud\n", env, "config", "get", "target") // environment overrides local config
+}
+
+func TestConfigValidate(t *testing.T) {
+	configHome := t.TempDir()
+	assertConfigCommand(t, configHome, "Success: Configuration is valid\n", "config", "validate")
+
+	configFile := filepath.Join(configHome, "config.yaml")
+	require.Nil(t, os.WriteFile(configFile, []byte(`target: cloud
+color: sometimes
+zone: ""
+tagret: local
+alias.top: "hits=1"
+alias.bad: "'unterminated"
+`), 0600))
+	cli, stdout, stderr := newTestCLI(t, "VESPA_CLI_HOME="+configHome, "VESPA_CLI_APPLICATION=foo")
+	assert.NotNil(t, cli.Run("config", "validate"))
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, configFile+": invalid alias bad: unterminated quote in \"'unterminated\"\n"+
+		configFile+": invalid value of color: invalid option or value: color = sometimes\n"+
+		configFile+": unknown option: tagret\n"+
+		"environment variable VESPA_CLI_APPLICATION: invalid value: invalid application: \"foo\"\n"+
+		"Error: invalid configuration\n"+
+		"Hint: Use 'vespa config set' or 'vespa config unset' to correct options\n", stderr.String())
+
+	// Unknown options can be removed
+	assertConfigCommand(t, configHome, "", "config", "set", "color", "never")
+	assertConfigCommand(t, configHome, "", "config", "unset", "tagret")
+	assertConfigCommandErr(t, configHome, "Error: invalid option: tagret\n", "config", "unset", "tagret")
+}
+
+func TestConfigEndpoints(t *testing.T) {
+	configHome := t.TempDir()
+	assertConfigCommandErr(t, configHome, "Error: invalid endpoint of cluster feed: \"192.0.2.1\": must be an http or https URL\n",
+		"config", "set", "endpoint.feed", "192.0.2.1")
+	assertConfigCommandErr(t, configHome, "Error: invalid cluster name: \"a/b\"\n", "config", "set", "endpoint.a/b", "http://192.0.2.1")
+	assertConfigCommand(t, configHome, "", "config", "set", "endpoint.feed", "http://192.0.2.1:8080")
+	assertConfigCommand(t, configHome, "endpoint.feed = http://192.0.2.1:8080\n", "config", "get", "endpoint.feed")
+	assertConfigCommand(t, configHome, `application = <unset>
+cluster = <unset>
+color = auto
+endpoint.feed = http://192.0.2.1:8080
+instance = <unset>
+quiet = false
+target = local
+via = <unset>
+zone = <unset>
+`, "config", "get")
+	assertConfigCommand(t, configHome, "Success: Configuration is valid\n", "config", "validate")
+	// Endpoints cannot be overridden by environment variables
+	env := []string{"VESPA_CLI_ENDPOINT.FEED=192.0.2.2"}
+	assertEnvConfigCommand(t, configHome, "Success: Configuration is valid\n", env, "config", "validate")
+	assertEnvConfigCommand(t, configHome, "endpoint.feed = http://192.0.2.1:8080 (config "+filepath.Join(configHome, "config.yaml")+")\n",
+		env, "config", "get", "--show-origin", "endpoint.feed")
+	assertConfigCommand(t, configHome, "", "config", "unset", "endpoint.feed")
+	assertConfigCommand(t, configHome, "endpoint.feed = <unset>\n", "config", "get", "endpoint.feed")
+}
+
 func assertConfigCommand(t *testing.T, configHome, expected string, args ...string) {
 	t.Helper()
 	assertEnvConfigCommand(t, configHome, expected, nil, args...)
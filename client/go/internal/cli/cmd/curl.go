@@ -24,6 +24,9 @@ func newCurlCmd(cli *CLI) *cobra.Command {
 
 Execute curl with the appropriate URL, certificate and private key for your application.
 
+The path may be given as @name, where name is an alias of curl options and a
+path. See 'vespa config alias' for how to manage aliases.
+
 For a more high-level interface to query and feeding, see the 'query' and 'document' commands.
 `,
 		Example: `$ vespa curl /ApplicationStatus
@@ -33,6 +36,14 @@ $ vespa curl -- -v --data-urlencode "yql=select * from music where album contain
 		SilenceUsage:      true,
 		Args:              cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Only the path is expanded, as curl options may start with @
+			path, ok, err := cli.config.expandAlias(args[len(args)-1])
+			if err != nil {
+				return err
+			}
+			if ok {
+				args = append(args[:len(args)-1:len(args)-1], path...)
+			}
 			target, err := cli.target(targetOptions{})
 			if err != nil {
 				return err
@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
@@ -24,6 +26,10 @@ func newDeployCmd(cli *CLI) *cobra.Command {
 		logLevelArg string
 		versionArg  string
 		copyCert    bool
+		exportArg   string
+		verifyArg   bool
+		forceArg    bool
+		listFiles   bool
 	)
 	cmd := &cobra.Command{
 		Use:   "deploy [application-directory-or-file]",
@@ -46,11 +52,31 @@ If application directory is not specified, it defaults to working directory.
 In Vespa Cloud you may override the Vespa runtime version (--version) for your
 deployment. This option should only be used if you have a reason for using a
 specific version. By default Vespa Cloud chooses a suitable version for you.
+
+To deploy from a machine without access to the application source, e.g. in an
+air-gapped environment, the application package can be exported with --export.
+This writes the zip file that would be deployed, including any added
+certificate, with a manifest holding the checksum of each file and metadata
+about the source. The exported zip can then be deployed with --verify-manifest,
+which checks that its files match the manifest before deploying.
+
+Files matching patterns in .vespaignore are not deployed. The patterns follow
+the rules of .gitignore, and a .vespaignore file in a subdirectory applies to
+the files in that directory. Use --list-files to show which files would be
+deployed, and which pattern excludes each of the other files.
+
+Application package zips are reproducible, and deploy prints the hash of the
+deployed zip. When deploying to a local or custom target, the upload is skipped
+if the package is identical to the one which is already active, unless --force
+is given.
 `,
 		Example: `$ vespa deploy .
 $ vespa deploy -t cloud
 $ vespa deploy -t cloud -z dev.aws-us-east-1c  # -z can be omitted here as this zone is the default
-$ vespa deploy -t cloud -z perf.aws-us-east-1c`,
+$ vespa deploy -t cloud -z perf.aws-us-east-1c
+$ vespa deploy --list-files .
+$ vespa deploy --export bundle.zip .
+$ vespa deploy --verify-manifest bundle.zip`,
 		Args:              cobra.MaximumNArgs(1),
 		DisableAutoGenTag: true,
 		SilenceUsage:      true,
@@ -59,6 +85,17 @@ $ vespa deploy -t cloud -z perf.aws-us-east-1c`,
 			if err != nil {
 				return err
 			}
+			if listFiles {
+				return printPackageFiles(cli, pkg)
+			}
+			if exportArg != "" {
+				return exportBundle(cli, pkg, exportArg, copyCert)
+			}
+			if verifyArg {
+				if err := verifyBundle(cli, pkg); err != nil {
+					return err
+				}
+			}
 			target, err := cli.target(targetOptions{logLevel: logLevelArg})
 			if err != nil {
 				return err
@@ -76,12 +113,38 @@ $ vespa deploy -t cloud -z perf.aws-us-east-1c`,
 					return err
 				}
 			}
+			if _, err := pullModels(cli, pkg, false); err != nil {
+				return err
+			}
+			// Zip the package once, as both its hash and the upload are read from the zip
+			zipped, removeZip, err := pkg.Zipped()
+			if err != nil {
+				if errors.Is(err, vespa.ErrModelCorrupt) {
+					return errHint(err, "Run 'vespa model pull' to download missing or corrupt models")
+				}
+				return err
+			}
+			defer removeZip()
+			opts.ApplicationPackage = zipped
+			hash, err := zipped.Hash()
+			if err != nil {
+				return err
+			}
 			waiter := cli.waiter(time.Duration(waitSecs)*time.Second, cmd)
-			if _, err := waiter.DeployService(target); err != nil {
+			deployService, err := waiter.DeployService(target)
+			if err != nil {
 				return err
 			}
+			if !target.IsCloud() && !forceArg {
+				if sessionID, ok := activeSession(cli, target, deployService, hash); ok {
+					cli.printSuccess("Application package ", color.CyanString("'"+pkg.Path+"'"), " is unchanged, keeping active session ID ", color.CyanString(strconv.FormatInt(sessionID, 10)))
+					log.Printf("Application package hash: %s", color.CyanString(hash))
+					return waitForVespaReady(target, sessionID, waiter)
+				}
+			}
 			var result vespa.PrepareResult
-			err = cli.spinner(cli.Stderr, "Uploading application package...", func() error {
+			err = cli.progress(cli.Stderr, "Uploading application package...", func(progress vespa.ProgressFunc) error {
+				opts.Progress = progress
 				result, err = vespa.Deploy(opts)
 				return err
 			})
@@ -100,7 +163,11 @@ $ vespa deploy -t cloud -z perf.aws-us-east-1c`,
 			} else {
 				cli.printSuccess("Deployed ", color.CyanString("'"+pkg.Path+"'"), " with session ID ", color.CyanString(strconv.FormatInt(result.ID, 10)))
 				printPrepareLog(cli.Stderr, result)
+				if err := cli.config.writeDeployedPackage(vespa.DefaultApplication, deployService.BaseURL, deployedPackage{Hash: hash, SessionID: result.ID}); err != nil {
+					cli.printWarning("Could not record deployed application package: " + err.Error())
+				}
 			}
+			log.Printf("Application package hash: %s", color.CyanString(hash))
 			if opts.Target.IsCloud() {
 				log.Printf("\nUse %s for deployment status, or follow this deployment at", color.CyanString("vespa status deployment"))
 				log.Print(color.CyanString(opts.Target.Deployment().System.ConsoleRunURL(opts.Target.Deployment(), result.ID)))
@@ -111,10 +178,124 @@ $ vespa deploy -t cloud -z perf.aws-us-east-1c`,
 	cmd.Flags().StringVarP(&logLevelArg, "log-level", "l", "error", `Log level for Vespa logs. Must be "error", "warning", "info" or "debug"`)
 	cmd.Flags().StringVarP(&versionArg, "version", "V", "", `Override the Vespa runtime version to use in Vespa Cloud`)
 	cmd.Flags().BoolVarP(&copyCert, "add-cert", "A", false, `Copy certificate of the configured application to the current application package`)
+	cmd.Flags().StringVar(&exportArg, "export", "", "Write the application package that would be deployed, with a manifest, to given zip file instead of deploying it")
+	cmd.Flags().BoolVar(&verifyArg, "verify-manifest", false, "Verify that the files of an exported application package match its manifest before deploying it")
+	cmd.Flags().BoolVar(&forceArg, "force", false, "Upload the application package even if it is identical to the active one")
+	cmd.Flags().BoolVar(&listFiles, "list-files", false, "List the files of the application package which would be deployed, and those excluded by .vespaignore, without deploying")
+	cmd.MarkFlagsMutuallyExclusive("export", "verify-manifest", "list-files")
 	cli.bindWaitFlag(cmd, 0, &waitSecs)
 	return cmd
 }
 
+// printPackageFiles prints the files of pkg, and the ignore rule excluding each file which would not be deployed.
+func printPackageFiles(cli *CLI, pkg vespa.ApplicationPackage) error {
+	files, err := pkg.Files()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.Model != nil {
+			cached := ""
+			if !pkg.ModelCache.Has(*f.Model) {
+				cached = ", not cached"
+			}
+			fmt.Fprintln(cli.Stdout, f.Path, color.CyanString("(model from "+f.Model.URL+cached+")"))
+			continue
+		}
+		if f.ExcludedBy == nil {
+			fmt.Fprintln(cli.Stdout, f.Path)
+			continue
+		}
+		path := f.Path
+		if f.Dir {
+			path += "/"
+		}
+		fmt.Fprintln(cli.Stdout, path, color.YellowString("(excluded by "+f.ExcludedBy.String()+")"))
+	}
+	return nil
+}
+
+func exportBundle(cli *CLI, pkg vespa.ApplicationPackage, destination string, copyCert bool) error {
+	if filepath.Ext(destination) != ".zip" {
+		return fmt.Errorf("export destination must be a .zip file: %s", destination)
+	}
+	targetType, err := cli.targetType(anyTarget)
+	if err != nil {
+		return err
+	}
+	if targetType.name == vespa.TargetCloud {
+		// Add certificate now, as the exported package cannot be modified
+		target, err := cli.target(targetOptions{})
+		if err != nil {
+			return err
+		}
+		if err := requireCertificate(copyCert, true, cli, target, pkg); err != nil {
+			return err
+		}
+	}
+	if _, err := pullModels(cli, pkg, false); err != nil {
+		return err
+	}
+	manifest := vespa.BundleManifest{
+		Created:    cli.now().UTC(),
+		CLIVersion: cli.version.String(),
+		Source:     pkg.Path,
+		Submission: gitSubmission(cli, pkg.Path),
+	}
+	manifest, err = vespa.ExportBundle(pkg, manifest, destination)
+	if err != nil {
+		return fmt.Errorf("could not export application package: %w", err)
+	}
+	cli.printSuccess("Exported ", color.CyanString("'"+pkg.Path+"'"), " to ", color.CyanString("'"+destination+"'"),
+		" with ", color.CyanString(strconv.Itoa(len(manifest.Files))), " files")
+	return nil
+}
+
+func verifyBundle(cli *CLI, pkg vespa.ApplicationPackage) error {
+	if !pkg.IsZip() {
+		return fmt.Errorf("cannot verify manifest of '%s': not an exported application package", pkg.Path)
+	}
+	manifest, err := vespa.VerifyBundle(pkg.Path)
+	if err != nil {
+		return errHint(err, "Export the application package again with 'vespa deploy --export'")
+	}
+	source := ""
+	if manifest.Submission.Commit != "" {
+		source = " from commit " + color.CyanString(manifest.Submission.Commit)
+	}
+	log.Printf("Verified %s files of '%s', exported %s%s", color.CyanString(strconv.Itoa(len(manifest.Files))), pkg.Path,
+		manifest.Created.Format(time.RFC3339), source)
+	return nil
+}
+
+// activeSession returns the ID of the active session on target, if it was deployed by us from an application package
+// with given hash.
+func activeSession(cli *CLI, target vespa.Target, deployService *vespa.Service, hash string) (int64, bool) {
+	deployed, ok := cli.config.readDeployedPackage(vespa.DefaultApplication, deployService.BaseURL)
+	if !ok || deployed.Hash != hash {
+		return 0, false
+	}
+	// The package may have been replaced by someone else since we deployed it
+	generation, err := target.AwaitDeployment(vespa.AnyDeployment, 0)
+	if err != nil || generation != deployed.SessionID {
+		return 0, false
+	}
+	return generation, true
+}
+
+// gitSubmission returns metadata about the latest commit of the git repository containing dir, if any.
+func gitSubmission(cli *CLI, dir string) vespa.Submission {
+	out, err := cli.exec.Run("git", "-C", dir, "log", "-1", "--format=%H%n%ae%n%s")
+	if err != nil {
+		return vespa.Submission{}
+	}
+	lines := strings.SplitN(strings.TrimSpace(string(out)), "\n", 3)
+	if len(lines) != 3 {
+		return vespa.Submission{}
+	}
+	return vespa.Submission{Commit: lines[0], AuthorEmail: lines[1], Description: lines[2]}
+}
+
 func newPrepareCmd(cli *CLI) *cobra.Command {
 	return &cobra.Command{
 		Use:               "prepare [application-directory-or-file]",
@@ -131,9 +312,13 @@ func newPrepareCmd(cli *CLI) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if _, err := pullModels(cli, pkg, false); err != nil {
+				return err
+			}
 			opts := vespa.DeploymentOptions{ApplicationPackage: pkg, Target: target}
 			var result vespa.PrepareResult
-			err = cli.spinner(cli.Stderr, "Uploading application package...", func() error {
+			err = cli.progress(cli.Stderr, "Uploading application package...", func(progress vespa.ProgressFunc) error {
+				opts.Progress = progress
 				result, err = vespa.Prepare(opts)
 				return err
 			})
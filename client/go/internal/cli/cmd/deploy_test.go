@@ -7,9 +7,12 @@ package cmd
 import (
 	"archive/zip"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -25,7 +28,7 @@ func TestDeployCloud(t *testing.T) {
 	createApplication(t, pkgDir, false, false)
 
 	cli, stdout, stderr := newTestCLI(t, "NO_COLOR=true")
-	httpClient := &mock.HTTPClient{}
+	httpClient := &mock.HTTPClient{ReadBody: true}
 	httpClient.NextResponseString(200, `ok`)
 	cli.httpClient = httpClient
 
@@ -85,7 +88,7 @@ func TestDeployCloudFastWait(t *testing.T) {
 	createApplication(t, pkgDir, false, false)
 
 	cli, stdout, stderr := newTestCLI(t, "CI=true")
-	httpClient := &mock.HTTPClient{}
+	httpClient := &mock.HTTPClient{ReadBody: true}
 	cli.httpClient = httpClient
 
 	app := vespa.ApplicationID{Tenant: "t1", Application: "a1", Instance: "i1"}
@@ -125,7 +128,7 @@ func TestDeployCloudUnauthorized(t *testing.T) {
 	createApplication(t, pkgDir, false, false)
 
 	cli, _, stderr := newTestCLI(t, "CI=true")
-	httpClient := &mock.HTTPClient{}
+	httpClient := &mock.HTTPClient{ReadBody: true}
 	cli.httpClient = httpClient
 
 	app := vespa.ApplicationID{Tenant: "t1", Application: "a1", Instance: "i1"}
@@ -145,7 +148,7 @@ Hint: If the tenant already exists you may need to run 'vespa auth login' to gai
 
 func TestDeployWait(t *testing.T) {
 	cli, stdout, _ := newTestCLI(t)
-	client := &mock.HTTPClient{}
+	client := &mock.HTTPClient{ReadBody: true}
 	cli.httpClient = client
 	cli.retryInterval = 0
 	pkg := "testdata/applications/withSource/src/main/application"
@@ -165,10 +168,53 @@ func TestDeployWait(t *testing.T) {
 	mockServiceStatus(client, "foo") // Look up services
 	assert.Nil(t, cli.Run("deploy", "--wait=3", pkg))
 	assert.Equal(t,
-		"\nSuccess: Deployed '"+pkg+"' with session ID 1\n",
+		"\nSuccess: Deployed '"+pkg+"' with session ID 1\nApplication package hash: "+packageHash(t, pkg)+"\n",
 		stdout.String())
 }
 
+func TestDeploySkipsUnchangedPackage(t *testing.T) {
+	cli, stdout, _ := newTestCLI(t, "NO_COLOR=true")
+	client := &mock.HTTPClient{ReadBody: true}
+	cli.httpClient = client
+	pkg := "testdata/applications/withSource/src/main/application"
+	hash := packageHash(t, pkg)
+	client.NextResponseString(200, `{"session-id": "1"}`)
+	require.Nil(t, cli.Run("deploy", "--wait=0", pkg))
+	assert.Equal(t, "\nSuccess: Deployed '"+pkg+"' with session ID 1\nApplication package hash: "+hash+"\n", stdout.String())
+
+	// Active session is the one we deployed
+	stdout.Reset()
+	client.Requests = nil
+	mockServiceStatus(client)
+	require.Nil(t, cli.Run("deploy", "--wait=0", pkg))
+	assert.Equal(t, "Success: Application package '"+pkg+"' is unchanged, keeping active session ID 1\nApplication package hash: "+hash+"\n", stdout.String())
+	require.Equal(t, 1, len(client.Requests))
+	assert.True(t, strings.HasSuffix(client.Requests[0].URL.Path, "/serviceconverge"))
+
+	// Upload is forced
+	cli, stdout, _ = newTestCLI(t, "NO_COLOR=true")
+	cli.httpClient = client
+	client.Requests = nil
+	client.NextResponseString(200, `{"session-id": "1"}`)
+	require.Nil(t, cli.Run("deploy", "--wait=0", "--force", pkg))
+	assert.Contains(t, stdout.String(), "Success: Deployed")
+	assertDeployRequestMade("http://127.0.0.1:19071", client, t)
+
+	// Another application package has been activated since our deployment
+	cli, stdout, _ = newTestCLI(t, "NO_COLOR=true")
+	cli.httpClient = client
+	client.Requests = nil
+	client.NextResponseString(200, `{"session-id": "1"}`)
+	require.Nil(t, cli.Run("deploy", "--wait=0", pkg))
+	client.Requests = nil
+	stdout.Reset()
+	client.NextResponseString(200, `{"currentGeneration": 2, "services": []}`)
+	client.NextResponseString(200, `{"session-id": "3"}`)
+	require.Nil(t, cli.Run("deploy", "--wait=0", pkg))
+	assert.Equal(t, "\nSuccess: Deployed '"+pkg+"' with session ID 3\nApplication package hash: "+hash+"\n", stdout.String())
+	assertDeployRequestMade("http://127.0.0.1:19071", client, t)
+}
+
 func TestPrepareZip(t *testing.T) {
 	assertPrepare("testdata/applications/withTarget/target/application.zip",
 		[]string{"prepare", "testdata/applications/withTarget/target/application.zip"}, t)
@@ -187,12 +233,12 @@ func TestDeployZipWithURLTargetArgument(t *testing.T) {
 	applicationPackage := "testdata/applications/withTarget/target/application.zip"
 	arguments := []string{"deploy", "--wait=0", "testdata/applications/withTarget/target/application.zip", "-t", "http://target:19071"}
 
-	client := &mock.HTTPClient{}
+	client := &mock.HTTPClient{ReadBody: true}
 	cli, stdout, _ := newTestCLI(t)
 	cli.httpClient = client
 	assert.Nil(t, cli.Run(arguments...))
 	assert.Equal(t,
-		"\nSuccess: Deployed '"+applicationPackage+"' with session ID 0\n",
+		"\nSuccess: Deployed '"+applicationPackage+"' with session ID 0\nApplication package hash: "+packageHash(t, applicationPackage)+"\n",
 		stdout.String())
 	assertDeployRequestMade("http://target:19071", client, t)
 }
@@ -227,20 +273,22 @@ func TestDeployApplicationDirectoryWithPomAndEmptyTarget(t *testing.T) {
 
 func TestDeployIncludesExpectedFiles(t *testing.T) {
 	cli, stdout, _ := newTestCLI(t)
-	client := &mock.HTTPClient{}
+	client := &mock.HTTPClient{ReadBody: true}
 	cli.httpClient = client
 	assert.Nil(t, cli.Run("deploy", "--wait=0", "testdata/applications/withSource"))
 	applicationPackage := "testdata/applications/withSource/src/main/application"
-	assert.Equal(t,
-		"\nSuccess: Deployed '"+applicationPackage+"' with session ID 0\n",
-		stdout.String())
 
 	zipName := filepath.Join(t.TempDir(), "tmp.zip")
 	f, err := os.Create(zipName)
 	assert.Nil(t, err)
-	if _, err := io.Copy(f, client.LastRequest.Body); err != nil {
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), client.LastRequest.Body); err != nil {
 		t.Fatal(err)
 	}
+	// The printed hash is the hash of the uploaded zip
+	assert.Equal(t,
+		"\nSuccess: Deployed '"+applicationPackage+"' with session ID 0\nApplication package hash: "+hex.EncodeToString(h.Sum(nil))+"\n",
+		stdout.String())
 	zr, err := zip.OpenReader(zipName)
 	assert.Nil(t, err)
 	defer zr.Close()
@@ -251,6 +299,85 @@ func TestDeployIncludesExpectedFiles(t *testing.T) {
 	assert.Equal(t, []string{".vespaignore", "hosts.xml", "schemas/msmarco.sd", "services.xml"}, zipFiles)
 }
 
+func TestDeployListFiles(t *testing.T) {
+	cli, stdout, _ := newTestCLI(t, "NO_COLOR=true")
+	client := &mock.HTTPClient{ReadBody: true}
+	cli.httpClient = client
+	require.Nil(t, cli.Run("deploy", "--list-files", "testdata/applications/withSource"))
+	assert.Equal(t, `.vespaignore
+hosts.xml
+ignored-dir/ (excluded by .vespaignore:1:ignored-dir/)
+ignored-file (excluded by .vespaignore:2:ignored-file)
+schemas/msmarco.sd
+services.xml
+`, stdout.String())
+	assert.Equal(t, 0, len(client.Requests))
+
+	stdout.Reset()
+	require.Nil(t, cli.Run("deploy", "--list-files", "testdata/applications/withTarget/target/application.zip"))
+	assert.Equal(t, "/hosts.xml\n/schemas/msmarco.sd\n/services.xml\n", stdout.String())
+}
+
+func TestDeployExport(t *testing.T) {
+	cli, stdout, stderr := newTestCLI(t, "NO_COLOR=true")
+	client := &mock.HTTPClient{ReadBody: true}
+	cli.httpClient = client
+	cli.exec = &mock.Exec{CombinedOutput: "0123abcd\njane@example.com\nAdd music schema\n"}
+	bundle := filepath.Join(t.TempDir(), "bundle.zip")
+	require.Nil(t, cli.Run("deploy", "--export", bundle, "testdata/applications/withSource"))
+	assert.Equal(t, "Success: Exported 'testdata/applications/withSource/src/main/application' to '"+bundle+"' with 4 files\n", stdout.String())
+	assert.Equal(t, 0, len(client.Requests))
+
+	manifest, err := vespa.VerifyBundle(bundle)
+	require.Nil(t, err)
+	assert.Equal(t, vespa.Submission{Commit: "0123abcd", AuthorEmail: "jane@example.com", Description: "Add music schema"}, manifest.Submission)
+	assert.Equal(t, "testdata/applications/withSource/src/main/application", manifest.Source)
+	assert.Equal(t, []string{".vespaignore", "hosts.xml", "schemas/msmarco.sd", "services.xml"}, sortedKeys(manifest.Files))
+
+	cli, stdout, stderr = newTestCLI(t, "NO_COLOR=true")
+	cli.httpClient = client
+	require.Nil(t, cli.Run("deploy", "--wait=0", "--verify-manifest", bundle))
+	assert.True(t, strings.HasPrefix(stdout.String(), "Verified 4 files of '"+bundle+"', exported "))
+	assert.True(t, strings.HasSuffix(stdout.String(), " from commit 0123abcd\n\nSuccess: Deployed '"+bundle+"' with session ID 0\n"+
+		"Application package hash: "+packageHash(t, bundle)+"\n"))
+	assertDeployRequestMade("http://127.0.0.1:19071", client, t)
+
+	// A package that was not exported cannot be verified
+	stderr.Reset()
+	require.NotNil(t, cli.Run("deploy", "--wait=0", "--verify-manifest", "testdata/applications/withTarget/target/application.zip"))
+	assert.Equal(t, "Error: bundle testdata/applications/withTarget/target/application.zip has no bundle-manifest.json\n"+
+		"Hint: Export the application package again with 'vespa deploy --export'\n", stderr.String())
+
+	cli, _, stderr = newTestCLI(t)
+	require.NotNil(t, cli.Run("deploy", "--export", "bundle.tar", "testdata/applications/withSource"))
+	assert.Equal(t, "Error: export destination must be a .zip file: bundle.tar\n", stderr.String())
+}
+
+func TestDeployExportCloud(t *testing.T) {
+	pkgDir := filepath.Join(t.TempDir(), "app")
+	createApplication(t, pkgDir, false, false)
+	cli, _, _ := newTestCLI(t)
+	assert.Nil(t, cli.Run("config", "set", "application", "t1.a1.i1"))
+	assert.Nil(t, cli.Run("config", "set", "target", "cloud"))
+	assert.Nil(t, cli.Run("auth", "api-key"))
+	assert.Nil(t, cli.Run("auth", "cert", "--no-add"))
+
+	bundle := filepath.Join(t.TempDir(), "bundle.zip")
+	require.Nil(t, cli.Run("deploy", "--export", bundle, "--add-cert", pkgDir))
+	manifest, err := vespa.VerifyBundle(bundle)
+	require.Nil(t, err)
+	assert.Contains(t, manifest.Files, "security/clients.pem")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func TestDeployApplicationPackageErrorWithUnexpectedNonJson(t *testing.T) {
 	assertApplicationPackageError(t, "deploy", 400,
 		"Raw text error",
@@ -290,18 +417,26 @@ func TestDeployError(t *testing.T) {
 func assertDeploy(applicationPackage string, arguments []string, t *testing.T) {
 	t.Helper()
 	cli, stdout, _ := newTestCLI(t)
-	client := &mock.HTTPClient{}
+	client := &mock.HTTPClient{ReadBody: true}
 	cli.httpClient = client
 	assert.Nil(t, cli.Run(arguments...))
 	assert.Equal(t,
-		"\nSuccess: Deployed '"+applicationPackage+"' with session ID 0\n",
+		"\nSuccess: Deployed '"+applicationPackage+"' with session ID 0\nApplication package hash: "+packageHash(t, applicationPackage)+"\n",
 		stdout.String())
 	assertDeployRequestMade("http://127.0.0.1:19071", client, t)
 }
 
+func packageHash(t *testing.T, path string) string {
+	t.Helper()
+	pkg := vespa.ApplicationPackage{Path: path}
+	hash, err := pkg.Hash()
+	require.Nil(t, err)
+	return hash
+}
+
 func assertPrepare(applicationPackage string, arguments []string, t *testing.T) {
 	t.Helper()
-	client := &mock.HTTPClient{}
+	client := &mock.HTTPClient{ReadBody: true}
 	client.NextResponseString(200, `{"session-id":"42"}`)
 	client.NextResponseString(200, `{"session-id":"42","message":"Session 42 for tenant 'default' prepared.","log":[{"level":"WARNING","message":"Warning message 1","time": 1430134091319}]}`)
 	cli, stdout, _ := newTestCLI(t)
@@ -319,7 +454,7 @@ func assertPrepare(applicationPackage string, arguments []string, t *testing.T)
 
 func assertActivate(arguments []string, t *testing.T) {
 	t.Helper()
-	client := &mock.HTTPClient{}
+	client := &mock.HTTPClient{ReadBody: true}
 	cli, stdout, _ := newTestCLI(t)
 	cli.httpClient = client
 	if err := cli.config.writeSessionID(vespa.DefaultApplication, 42); err != nil {
@@ -357,7 +492,7 @@ func assertDeployRequestMade(target string, client *mock.HTTPClient, t *testing.
 
 func assertApplicationPackageError(t *testing.T, cmd string, status int, expectedMessage string, returnBody string) {
 	t.Helper()
-	client := &mock.HTTPClient{}
+	client := &mock.HTTPClient{ReadBody: true}
 	client.NextResponseString(status, returnBody)
 	cli, _, stderr := newTestCLI(t)
 	cli.httpClient = client
@@ -376,7 +511,7 @@ func assertApplicationPackageError(t *testing.T, cmd string, status int, expecte
 
 func assertDeployServerError(t *testing.T, status int, errorMessage string) {
 	t.Helper()
-	client := &mock.HTTPClient{}
+	client := &mock.HTTPClient{ReadBody: true}
 	client.NextResponseString(status, errorMessage)
 	cli, _, stderr := newTestCLI(t)
 	cli.httpClient = client
@@ -385,3 +520,9 @@ func assertDeployServerError(t *testing.T, status int, errorMessage string) {
 		"Error: error from deploy API at 127.0.0.1:19071 (status "+strconv.Itoa(status)+"):\n"+errorMessage+"\n",
 		stderr.String())
 }
+
+func TestFormatProgress(t *testing.T) {
+	assert.Equal(t, "0.0 MB of 0.0 MB (100%)", formatProgress(0, 0))
+	assert.Equal(t, "12.3 MB of 45.6 MB (26%)", formatProgress(12_300_000, 45_600_000))
+	assert.Equal(t, "45.6 MB of 45.6 MB (100%)", formatProgress(45_600_000, 45_600_000))
+}
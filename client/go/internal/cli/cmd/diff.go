@@ -0,0 +1,127 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change in a diff.
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // One of ' ', '-' or '+'
+	line string
+}
+
+// diffLines returns the lines of a unified diff from a to b, for the file named filename. No lines are returned if a
+// and b are equal.
+func diffLines(a, b, filename string) []string {
+	if a == b {
+		return nil
+	}
+	ops := diffOps(splitLines(a), splitLines(b))
+	lines := []string{"--- a/" + filename, "+++ b/" + filename}
+	for start := 0; start < len(ops); {
+		// Find the next change, and extend the hunk until changes are separated by more than twice the context
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		end := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+		hunkStart := max(first-diffContext, 0)
+		hunkEnd := min(end+diffContext, len(ops))
+		lines = append(lines, hunkHeader(ops, hunkStart, hunkEnd))
+		for _, op := range ops[hunkStart:hunkEnd] {
+			lines = append(lines, string(op.kind)+op.line)
+		}
+		start = hunkEnd
+	}
+	return lines
+}
+
+// hunkHeader returns the header of the hunk consisting of ops[start:end].
+func hunkHeader(ops []diffOp, start, end int) string {
+	aLine, bLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			aLine++
+		}
+		if op.kind != '-' {
+			bLine++
+		}
+	}
+	aCount, bCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+	if aCount == 0 {
+		aLine--
+	}
+	if bCount == 0 {
+		bLine--
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", aLine, aCount, bLine, bCount)
+}
+
+// diffOps returns the operations transforming a into b, based on their longest common subsequence.
+func diffOps(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,47 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLines(t *testing.T) {
+	assert.Nil(t, diffLines("a\nb\n", "a\nb\n", "foo.xml"))
+	assert.Equal(t, []string{"--- a/foo.xml", "+++ b/foo.xml", "@@ -0,0 +1,2 @@", "+a", "+b"}, diffLines("", "a\nb\n", "foo.xml"))
+
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		a = append(a, string(rune('a'+i-1)))
+	}
+	b = append(b, a...)
+	b[1] = "B"     // Changed
+	b[3] = "D"     // Changed, merged with the above
+	b = b[:17]     // Removed "r", "s" and "t"
+	b[14] = "o\nO" // Line added after "o"
+	want := `--- a/foo.xml
++++ b/foo.xml
+@@ -1,7 +1,7 @@
+ a
+-b
++B
+ c
+-d
++D
+ e
+ f
+ g
+@@ -13,8 +13,6 @@
+ m
+ n
+ o
++O
+ p
+ q
+-r
+-s
+-t`
+	assert.Equal(t, want, strings.Join(diffLines(strings.Join(a, "\n"), strings.Join(b, "\n"), "foo.xml"), "\n"))
+}
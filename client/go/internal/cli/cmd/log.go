@@ -3,11 +3,14 @@ package cmd
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/vespa-engine/vespa/client/go/internal/version"
 	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
 )
 
 func newLogCmd(cli *CLI) *cobra.Command {
@@ -17,6 +20,10 @@ func newLogCmd(cli *CLI) *cobra.Command {
 		levelArg   string
 		followArg  bool
 		dequoteArg bool
+		formatArg  vespalog.Format
+		filter     vespalog.Filter
+		component  string
+		message    string
 	)
 	cmd := &cobra.Command{
 		Use:   "log [relative-period]",
@@ -30,7 +37,9 @@ Logs for the past hour are shown if no arguments are given.
 		Example: `$ vespa log 1h
 $ vespa log --nldequote=false 10m
 $ vespa log --from 2021-08-25T15:00:00Z --to 2021-08-26T02:00:00Z
-$ vespa log --follow`,
+$ vespa log --follow
+$ vespa log --service container --component 'Handler$' --message timeout
+$ vespa log --format json-v1 10m`,
 		DisableAutoGenTag: true,
 		SilenceUsage:      true,
 		Args:              cobra.MaximumNArgs(1),
@@ -39,11 +48,19 @@ $ vespa log --follow`,
 			if err != nil {
 				return err
 			}
+			if err := compileRegexp(&filter.Component, component, "--component"); err != nil {
+				return err
+			}
+			if err := compileRegexp(&filter.Message, message, "--message"); err != nil {
+				return err
+			}
 			options := vespa.LogOptions{
 				Level:   vespa.LogLevel(levelArg),
 				Follow:  followArg,
 				Writer:  cli.Stdout,
 				Dequote: dequoteArg,
+				Filter:  filter,
+				Format:  formatArg,
 			}
 			if options.Follow {
 				if fromArg != "" || toArg != "" || len(args) > 0 {
@@ -74,9 +91,28 @@ $ vespa log --follow`,
 	cmd.Flags().StringVarP(&levelArg, "level", "l", "debug", `The maximum log level to show. Must be "error", "warning", "info" or "debug"`)
 	cmd.Flags().BoolVarP(&followArg, "follow", "f", false, "Follow logs")
 	cmd.Flags().BoolVarP(&dequoteArg, "nldequote", "n", true, "Dequote LF and TAB characters in log messages")
+	cmd.Flags().StringVar(&filter.Host, "host", "", "Show only logs from this host")
+	cmd.Flags().StringVar(&filter.Service, "service", "", "Show only logs from this service")
+	cmd.Flags().StringVar(&filter.Pid, "pid", "", "Show only logs from this process ID")
+	cmd.Flags().StringVar(&component, "component", "", "Show only logs from components matching this regular expression")
+	cmd.Flags().StringVar(&message, "message", "", "Show only logs with messages matching this regular expression")
+	cmd.Flags().BoolVar(&filter.OnlyInternal, "internal", false, "Show only logs from Vespa-internal components")
+	cmd.Flags().Var(&formatArg, "format", "Output format. Must be "+strings.Join(vespalog.FormatNames(), ", "))
 	return cmd
 }
 
+func compileRegexp(target **regexp.Regexp, expr, flagName string) error {
+	if expr == "" {
+		return nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", flagName, err)
+	}
+	*target = re
+	return nil
+}
+
 func parsePeriod(from, to string, args []string) (time.Time, time.Time, error) {
 	relativePeriod := from == "" || to == ""
 	if relativePeriod {
@@ -63,6 +63,21 @@ func TestLogLocal(t *testing.T) {
 	assert.Contains(t, stderr.String(), "Error: invalid period: cannot combine --from/--to with relative value: 1h\n")
 }
 
+func TestLogLocalFilterAndFormat(t *testing.T) {
+	httpClient := &mock.HTTPClient{}
+	httpClient.NextResponseString(200, `1632738690.905535	localhost	806/53	logserver-container	Container.com.yahoo.container.jdisc.ConfiguredApplication	info	Switching to the latest deployed set of configurations and components. Application config generation: 52532
+1632738698.600189	localhost	1723/33590	config-sentinel	sentinel.sentinel.config-owner	warning	Sentinel got 3 service elements`)
+	cli, stdout, stderr := newTestCLI(t)
+	cli.httpClient = httpClient
+
+	assert.Nil(t, cli.Run("log", "--from", "2021-09-27T10:00:00Z", "--to", "2021-09-27T11:00:00Z", "--service", "config-sentinel", "--format", "logfmt"))
+	expected := "time=2021-09-27T10:31:38.600189Z host=localhost pid=1723 tid=33590 service=config-sentinel component=sentinel.sentinel.config-owner level=warning msg=\"Sentinel got 3 service elements\"\n"
+	assert.Equal(t, expected, stdout.String())
+
+	assert.NotNil(t, cli.Run("log", "--component", "*"))
+	assert.Contains(t, stderr.String(), "Error: invalid --component: error parsing regexp")
+}
+
 func TestLogLocalIncompatible(t *testing.T) {
 	httpClient := &mock.HTTPClient{}
 	httpClient.NextResponseString(404, `not found`)
@@ -0,0 +1,153 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vespa-engine/vespa/client/go/internal/vespa"
+)
+
+func newModelCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "model",
+		Short: "Manage models of an application package",
+		Long: `Manage models of an application package.
+
+Large files, such as ONNX models and vocabularies, can be kept out of the
+application package by listing them in models.json in the application package
+directory:
+
+{
+  "models": [
+    {
+      "path": "models/e5-small-v2.onnx",
+      "url": "https://example.com/models/e5-small-v2.onnx",
+      "sha256": "<sha256 checksum, in hex>"
+    }
+  ]
+}
+
+The url is either an HTTP(S) URL, a file URL, or the path to a local file
+relative to the application package directory. Models are downloaded to a local
+cache, verified against their checksums, and added to the application package
+at the given paths when it is deployed. Deploying downloads models which are
+not already cached.`,
+		Example: `$ vespa model pull
+$ vespa model verify myapp/`,
+		DisableAutoGenTag: true,
+		SilenceUsage:      false,
+		Args:              cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("invalid command: %s", args[0])
+		},
+	}
+}
+
+func newModelPullCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull [application-directory]",
+		Short: "Download the models of an application package to the local cache",
+		Long: `Download the models of an application package to the local cache.
+
+Models which are already cached are verified, and downloaded again if they do
+not match their checksum.`,
+		Example:           `$ vespa model pull myapp/`,
+		Args:              cobra.MaximumNArgs(1),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pkg, err := cli.applicationPackageFrom(args, vespa.PackageOptions{})
+			if err != nil {
+				return err
+			}
+			models, err := pkg.Models()
+			if err != nil {
+				return err
+			}
+			if len(models) == 0 {
+				log.Printf("No models listed in %s", color.CyanString(filepath.Join(pkg.Path, vespa.ModelManifestFile)))
+				return nil
+			}
+			downloaded, err := pullModels(cli, pkg, true)
+			if err != nil {
+				return err
+			}
+			cli.printSuccess(fmt.Sprintf("Downloaded %d of %d models to %s", downloaded, len(models), color.CyanString(pkg.ModelCache.Dir)))
+			return nil
+		},
+	}
+}
+
+func newModelVerifyCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:               "verify [application-directory]",
+		Short:             "Verify that the models of an application package are cached and match their checksums",
+		Example:           `$ vespa model verify myapp/`,
+		Args:              cobra.MaximumNArgs(1),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pkg, err := cli.applicationPackageFrom(args, vespa.PackageOptions{})
+			if err != nil {
+				return err
+			}
+			models, err := pkg.Models()
+			if err != nil {
+				return err
+			}
+			failed := 0
+			for _, m := range models {
+				if err := pkg.ModelCache.Verify(m); err != nil {
+					cli.printErr(err)
+					failed++
+				} else {
+					log.Printf("%s: %s", m.Path, color.GreenString("ok"))
+				}
+			}
+			if failed > 0 {
+				return errHint(fmt.Errorf("%d of %d models failed verification", failed, len(models)),
+					"Run 'vespa model pull' to download missing or corrupt models")
+			}
+			cli.printSuccess(fmt.Sprintf("Verified %d models", len(models)))
+			return nil
+		},
+	}
+}
+
+// pullModels downloads the models of pkg which are not in the model cache, and returns the number of models downloaded.
+// If verify is true, cached models are verified, and downloaded again if they do not match their checksum.
+func pullModels(cli *CLI, pkg vespa.ApplicationPackage, verify bool) (int, error) {
+	models, err := pkg.Models()
+	if err != nil {
+		return 0, err
+	}
+	downloaded := 0
+	for _, m := range models {
+		if verify && pkg.ModelCache.Has(m) {
+			if err := pkg.ModelCache.Verify(m); err != nil {
+				cli.printWarning(err)
+				if err := os.Remove(pkg.ModelCache.Path(m)); err != nil {
+					return downloaded, err
+				}
+			}
+		}
+		if pkg.ModelCache.Has(m) {
+			continue
+		}
+		err := cli.spinner(cli.Stderr, "Downloading "+m.Path+"...", func() error {
+			_, err := pkg.ModelCache.Pull(cli.httpClient, m, pkg.Path)
+			return err
+		})
+		if err != nil {
+			return downloaded, err
+		}
+		cli.printInfo("Downloaded ", color.CyanString(m.Path), " from ", m.URL)
+		downloaded++
+	}
+	return downloaded, nil
+}
@@ -0,0 +1,154 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/httputil"
+	"github.com/vespa-engine/vespa/client/go/internal/mock"
+)
+
+func TestModelPullAndVerify(t *testing.T) {
+	server := mock.NewFileServer(t, map[string]string{"/e5.onnx": "e5 model", "/vocab.txt": "vocabulary"})
+	appDir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "services.xml"), []byte("<services/>"), 0644))
+	writeModels(t, appDir,
+		"models/e5.onnx", server.URL("/e5.onnx"), "e5 model",
+		"models/vocab.txt", server.URL("/vocab.txt"), "vocabulary")
+	cli, stdout, stderr := newTestCLI(t, "NO_COLOR=true")
+	cli.httpClient = httputil.NewClient(time.Second)
+	cacheDir := filepath.Join(cli.config.cacheDir, "models")
+
+	require.NotNil(t, cli.Run("model", "verify", appDir))
+	assert.Equal(t, "Error: models/e5.onnx: model is not cached\n"+
+		"Error: models/vocab.txt: model is not cached\n"+
+		"Error: 2 of 2 models failed verification\n"+
+		"Hint: Run 'vespa model pull' to download missing or corrupt models\n", stderr.String())
+
+	stderr.Reset()
+	require.Nil(t, cli.Run("model", "pull", appDir))
+	assert.Equal(t, "Success: Downloaded 2 of 2 models to "+cacheDir+"\n", stdout.String())
+	assert.Equal(t, "Downloaded models/e5.onnx from "+server.URL("/e5.onnx")+"\n"+
+		"Downloaded models/vocab.txt from "+server.URL("/vocab.txt")+"\n", stderr.String())
+
+	// Cached models are not downloaded again
+	stdout.Reset()
+	require.Nil(t, cli.Run("model", "pull", appDir))
+	assert.Equal(t, "Success: Downloaded 0 of 2 models to "+cacheDir+"\n", stdout.String())
+	assert.Equal(t, []string{"/e5.onnx", "/vocab.txt"}, server.Requests())
+
+	stdout.Reset()
+	require.Nil(t, cli.Run("model", "verify", appDir))
+	assert.Equal(t, "models/e5.onnx: ok\nmodels/vocab.txt: ok\nSuccess: Verified 2 models\n", stdout.String())
+
+	// Corrupt models are detected, and downloaded again
+	require.Nil(t, os.WriteFile(filepath.Join(cacheDir, sha256Hex("vocabulary")), []byte("corrupt"), 0644))
+	stdout.Reset()
+	stderr.Reset()
+	require.NotNil(t, cli.Run("model", "verify", appDir))
+	assert.Equal(t, "models/e5.onnx: ok\n", stdout.String())
+	assert.Equal(t, fmt.Sprintf("Error: models/vocab.txt: checksum mismatch: expected %s, got %s\n", sha256Hex("vocabulary"), sha256Hex("corrupt"))+
+		"Error: 1 of 2 models failed verification\n"+
+		"Hint: Run 'vespa model pull' to download missing or corrupt models\n", stderr.String())
+
+	stdout.Reset()
+	stderr.Reset()
+	require.Nil(t, cli.Run("model", "pull", appDir))
+	assert.Equal(t, "Success: Downloaded 1 of 2 models to "+cacheDir+"\n", stdout.String())
+	assert.Equal(t, fmt.Sprintf("Warning: models/vocab.txt: checksum mismatch: expected %s, got %s\n", sha256Hex("vocabulary"), sha256Hex("corrupt"))+
+		"Downloaded models/vocab.txt from "+server.URL("/vocab.txt")+"\n", stderr.String())
+
+	// Application without models
+	stdout.Reset()
+	require.Nil(t, cli.Run("model", "pull", "testdata/applications/withSource/src/main/application"))
+	assert.Equal(t, "No models listed in testdata/applications/withSource/src/main/application/models.json\n", stdout.String())
+}
+
+func TestDeployWithModels(t *testing.T) {
+	appDir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "services.xml"), []byte("<services/>"), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, ".vespaignore"), []byte("/local-model.onnx\n"), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "local-model.onnx"), []byte("local model"), 0644))
+	writeModels(t, appDir, "models/model.onnx", "local-model.onnx", "local model")
+
+	cli, stdout, stderr := newTestCLI(t, "NO_COLOR=true")
+	client := &mock.HTTPClient{ReadBody: true}
+	cli.httpClient = client
+	require.Nil(t, cli.Run("deploy", "--list-files", appDir))
+	assert.Equal(t, `.vespaignore
+local-model.onnx (excluded by .vespaignore:1:/local-model.onnx)
+models.json
+models/model.onnx (model from local-model.onnx, not cached)
+services.xml
+`, stdout.String())
+
+	cli, stdout, stderr = newTestCLI(t, "NO_COLOR=true")
+	cli.httpClient = client
+	cacheDir := filepath.Join(cli.config.cacheDir, "models")
+	require.Nil(t, cli.Run("deploy", "--wait=0", appDir))
+	assert.Equal(t, "Downloaded models/model.onnx from local-model.onnx\n", stderr.String())
+	body, err := io.ReadAll(client.LastRequest.Body)
+	require.Nil(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.Nil(t, err)
+	var files []string
+	for _, f := range zr.File {
+		files = append(files, f.Name)
+	}
+	assert.Equal(t, []string{".vespaignore", "models.json", "models/model.onnx", "services.xml"}, files)
+
+	// Corrupt cached models are not deployed
+	require.Nil(t, os.WriteFile(filepath.Join(cacheDir, sha256Hex("local model")), []byte("local mode"), 0644))
+	cli, _, stderr = newTestCLI(t, "NO_COLOR=true")
+	cli.httpClient = client
+	cli.config.cacheDir = filepath.Dir(cacheDir)
+	client.LastRequest = nil
+	require.NotNil(t, cli.Run("deploy", "--wait=0", appDir))
+	assert.Equal(t, fmt.Sprintf("Error: models/model.onnx: checksum mismatch: expected %s, got %s: cached model is corrupt\n", sha256Hex("local model"), sha256Hex("local mode"))+
+		"Hint: Run 'vespa model pull' to download missing or corrupt models\n", stderr.String())
+	assert.Nil(t, client.LastRequest)
+	require.Nil(t, os.WriteFile(filepath.Join(cacheDir, sha256Hex("local model")), []byte("local model"), 0644))
+
+	cli, stdout, _ = newTestCLI(t, "NO_COLOR=true")
+	cli.config.cacheDir = filepath.Dir(cacheDir)
+	require.Nil(t, cli.Run("deploy", "--list-files", appDir))
+	assert.Contains(t, stdout.String(), "models/model.onnx (model from local-model.onnx)\n")
+}
+
+// writeModels writes a model manifest to appDir, listing models as triples of path, url and content.
+func writeModels(t *testing.T, appDir string, models ...string) {
+	t.Helper()
+	var entries []string
+	for i := 0; i < len(models); i += 3 {
+		entries = append(entries, fmt.Sprintf(`{"path": %q, "url": %q, "sha256": %q}`, models[i], models[i+1], sha256Hex(models[i+2])))
+	}
+	manifest := `{"models": [` + joinLines(entries) + `]}`
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "models.json"), []byte(manifest), 0644))
+}
+
+func joinLines(entries []string) string {
+	var sb bytes.Buffer
+	for i, e := range entries {
+		if i > 0 {
+			sb.WriteString(",\n")
+		}
+		sb.WriteString(e)
+	}
+	return sb.String()
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
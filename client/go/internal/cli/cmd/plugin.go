@@ -0,0 +1,173 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// pluginPrefix is the prefix of executables which are made available as commands of this CLI. An executable named
+// vespa-foo is run as 'vespa foo'.
+const pluginPrefix = "vespa-"
+
+// defaultVespaHome is the installation directory of Vespa when VESPA_HOME is not set.
+const defaultVespaHome = "/opt/vespa"
+
+// pluginDirs returns the directories to search for plugins, in order of precedence. The bin directory of a Vespa
+// installation is never searched, as it contains Vespa programs which are not plugins.
+func (c *CLI) pluginDirs() []string {
+	vespaHome := c.Environment["VESPA_HOME"]
+	if vespaHome == "" {
+		vespaHome = defaultVespaHome
+	}
+	vespaBin := filepath.Join(vespaHome, "bin")
+	dirs := []string{filepath.Join(c.config.homeDir, "plugins")}
+	for _, dir := range filepath.SplitList(c.Environment["PATH"]) {
+		if dir != "" && filepath.Clean(dir) != vespaBin {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// findPlugin returns the path of the plugin implementing command name, searching dirs in order.
+func findPlugin(dirs []string, name string) (string, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	filename := pluginPrefix + name
+	if runtime.GOOS == "windows" {
+		filename += ".exe"
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, filename)
+		if isExecutable(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0111 != 0
+}
+
+// addPlugin adds a command for the plugin named by the first of args, if cobra does not know that command. Plugins
+// are looked up only when needed, so that built-in commands never search for plugins, and plugins never replace
+// built-in commands.
+func (c *CLI) addPlugin(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return
+	}
+	name := args[0]
+	if cmd, _, err := c.cmd.Find(args[:1]); err != nil || cmd != c.cmd || c.hasCommand(name) {
+		return
+	}
+	if path, ok := findPlugin(c.pluginDirs(), name); ok {
+		c.cmd.AddCommand(newPluginCmd(c, name, path))
+	}
+}
+
+func (c *CLI) hasCommand(name string) bool {
+	switch name {
+	case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd: // Added by cobra on execution
+		return true
+	}
+	for _, cmd := range c.cmd.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func newPluginCmd(cli *CLI, name, path string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name,
+		Short: fmt.Sprintf("Run plugin %s", path),
+		Long: fmt.Sprintf(`Run plugin %s.
+
+Plugins are executables named vespa-<command>, found in $VESPA_CLI_HOME/plugins
+or in PATH, excluding $VESPA_HOME/bin. The plugin name must be the first
+argument. All arguments are passed unmodified to the plugin, which means that
+global flags must be set with 'vespa config set'.
+
+The plugin inherits the configuration of Vespa CLI through these environment
+variables:
+
+VESPA_CLI_TARGET                   The configured target, or its URL
+VESPA_CLI_CLUSTER                  The name of the container cluster
+VESPA_CLI_ENDPOINT_URL             The URL of the container cluster
+VESPA_CLI_DATA_PLANE_CA_CERT_FILE  CA certificate of the container cluster
+VESPA_CLI_DATA_PLANE_CERT_FILE     Certificate used to access the container cluster
+VESPA_CLI_DATA_PLANE_KEY_FILE      Private key used to access the container cluster
+VESPA_CLI_AUTH_HEADER              Authorization header, as "name: value"
+
+Variables describing the container cluster are only set if the cluster can be
+resolved.`, path),
+		DisableFlagParsing: true,
+		DisableAutoGenTag:  true,
+		SilenceUsage:       true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.runPlugin(path, args)
+		},
+	}
+}
+
+func (c *CLI) runPlugin(path string, args []string) error {
+	if err := c.exec.Exec(path, args, c.pluginEnv(), c.Stdin, c.Stdout, c.Stderr); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The plugin is responsible for reporting its own errors
+			return ErrCLI{Status: exitErr.ExitCode(), quiet: true, error: err}
+		}
+		return fmt.Errorf("could not run plugin %s: %w", path, err)
+	}
+	return nil
+}
+
+// pluginEnv returns the environment variables describing the configured target and cluster. Values which cannot be
+// resolved, e.g. because the target is not running, are left out.
+func (c *CLI) pluginEnv() []string {
+	var env []string
+	setenv := func(name, value string) {
+		if value != "" {
+			env = append(env, name+"="+value)
+		}
+	}
+	targetValue, err := c.config.targetOrURL()
+	if err != nil {
+		return env
+	}
+	setenv("VESPA_CLI_TARGET", targetValue)
+	target, err := c.target(targetOptions{})
+	if err != nil {
+		return env
+	}
+	service, err := c.waiter(0, nil).Service(target, c.config.cluster())
+	if err != nil {
+		return env
+	}
+	setenv("VESPA_CLI_CLUSTER", service.Name)
+	setenv("VESPA_CLI_ENDPOINT_URL", service.BaseURL)
+	setenv("VESPA_CLI_DATA_PLANE_CA_CERT_FILE", service.TLSOptions.CACertificateFile)
+	setenv("VESPA_CLI_DATA_PLANE_CERT_FILE", service.TLSOptions.CertificateFile)
+	setenv("VESPA_CLI_DATA_PLANE_KEY_FILE", service.TLSOptions.PrivateKeyFile)
+	if headers, err := service.AuthHeaders(); err == nil {
+		if auth := headers.Get("Authorization"); auth != "" {
+			setenv("VESPA_CLI_AUTH_HEADER", "Authorization: "+auth)
+		}
+	}
+	return env
+}
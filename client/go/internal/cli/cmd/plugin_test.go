@@ -0,0 +1,115 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/mock"
+)
+
+func writePlugin(t *testing.T, dir, name string, mode os.FileMode) string {
+	t.Helper()
+	require.Nil(t, os.MkdirAll(dir, 0755))
+	path := filepath.Join(dir, name)
+	require.Nil(t, os.WriteFile(path, []byte("#!/bin/sh\n"), mode))
+	return path
+}
+
+func TestFindPlugin(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+	hello1 := writePlugin(t, dir1, "vespa-hello", 0755)
+	writePlugin(t, dir2, "vespa-hello", 0755)
+	bye := writePlugin(t, dir2, "vespa-bye", 0755)
+	writePlugin(t, dir2, "vespa-noexec", 0644)
+	writePlugin(t, dir2, "vespa-", 0755)
+	require.Nil(t, os.Mkdir(filepath.Join(dir2, "vespa-dir"), 0755))
+	dirs := []string{dir1, filepath.Join(dir1, "missing"), dir2}
+	assertPlugin := func(name, want string) {
+		t.Helper()
+		path, ok := findPlugin(dirs, name)
+		assert.Equal(t, want, path)
+		assert.Equal(t, want != "", ok)
+	}
+	assertPlugin("hello", hello1)
+	assertPlugin("bye", bye)
+	assertPlugin("noexec", "")
+	assertPlugin("", "")
+	assertPlugin("dir", "")
+	assertPlugin("missing", "")
+	assertPlugin("../"+filepath.Base(dir2)+"/vespa-bye", "")
+}
+
+func TestPluginLookup(t *testing.T) {
+	vespaHome := t.TempDir()
+	pathDir := t.TempDir()
+	writePlugin(t, filepath.Join(vespaHome, "bin"), "vespa-start-services", 0755)
+	writePlugin(t, pathDir, "vespa-hello", 0755)
+	cli, _, stderr := newTestCLI(t, "VESPA_HOME="+vespaHome, "PATH="+filepath.Join(vespaHome, "bin")+":"+pathDir)
+	cli.exec = &mock.Exec{}
+
+	// Plugins are not added unless requested
+	require.Nil(t, cli.Run("version"))
+	hello, _, err := cli.cmd.Find([]string{"hello"})
+	require.Nil(t, err)
+	assert.Equal(t, cli.cmd, hello)
+
+	// Programs in VESPA_HOME/bin are not plugins
+	require.NotNil(t, cli.Run("start-services"))
+	assert.Equal(t, "Error: invalid command: start-services\n", stderr.String())
+	require.Nil(t, cli.Run("hello"))
+}
+
+func TestPlugin(t *testing.T) {
+	homeDir := filepath.Join(t.TempDir(), ".vespa")
+	pathDir := t.TempDir()
+	hello := writePlugin(t, filepath.Join(homeDir, "plugins"), "vespa-hello", 0755)
+	writePlugin(t, pathDir, "vespa-hello", 0755)
+	writePlugin(t, pathDir, "vespa-deploy", 0755)
+	cli, stdout, _ := newTestCLI(t, "VESPA_CLI_HOME="+homeDir, "PATH="+pathDir)
+	exec := &mock.Exec{CombinedOutput: "hello from plugin\n"}
+	cli.exec = exec
+
+	require.Nil(t, cli.Run("config", "set", "target", "http://127.0.0.1:8080"))
+	require.Nil(t, cli.Run("hello", "--name", "world", "-t", "cloud"))
+	assert.Equal(t, "hello from plugin\n", stdout.String())
+	assert.Equal(t, hello, exec.LastName)
+	assert.Equal(t, []string{"--name", "world", "-t", "cloud"}, exec.LastArgs)
+	assert.Equal(t, []string{
+		"VESPA_CLI_TARGET=http://127.0.0.1:8080",
+		"VESPA_CLI_ENDPOINT_URL=http://127.0.0.1:8080",
+	}, exec.LastEnv)
+
+	// Built-in commands cannot be replaced
+	deploy, _, err := cli.cmd.Find([]string{"deploy"})
+	require.Nil(t, err)
+	assert.NotContains(t, deploy.Short, "plugin")
+}
+
+func TestPluginLocalTarget(t *testing.T) {
+	pathDir := t.TempDir()
+	writePlugin(t, pathDir, "vespa-hello", 0755)
+	cli, _, _ := newTestCLI(t, "PATH="+pathDir)
+	client := &mock.HTTPClient{}
+	cli.httpClient = client
+	exec := &mock.Exec{}
+	cli.exec = exec
+
+	mockServiceStatus(client, "foo", "bar")
+	require.Nil(t, cli.Run("config", "set", "cluster", "bar"))
+	require.Nil(t, cli.Run("hello"))
+	assert.Equal(t, []string{
+		"VESPA_CLI_TARGET=local",
+		"VESPA_CLI_CLUSTER=bar",
+		"VESPA_CLI_ENDPOINT_URL=http://127.0.0.1:8080",
+	}, exec.LastEnv)
+
+	// Target is set even if the cluster cannot be resolved
+	client.NextStatus(500)
+	require.Nil(t, cli.Run("hello"))
+	assert.Equal(t, []string{"VESPA_CLI_TARGET=local"}, exec.LastEnv)
+}
@@ -10,6 +10,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/vespa-engine/vespa/client/go/internal/ioutil"
 	"github.com/vespa-engine/vespa/client/go/internal/vespa"
 	"github.com/vespa-engine/vespa/client/go/internal/vespa/xml"
+	"gopkg.in/yaml.v3"
 )
 
 func newProdCmd() *cobra.Command {
@@ -38,8 +40,25 @@ $ vespa prod deploy`,
 	}
 }
 
+// prodInitOptions holds the values given to a non-interactive prod init, either as flags or in a file.
+type prodInitOptions struct {
+	Regions   []string          `yaml:"regions"`
+	Nodes     map[string]string `yaml:"nodes"`
+	Resources map[string]string `yaml:"resources"`
+}
+
+func (o prodInitOptions) empty() bool {
+	return len(o.Regions) == 0 && len(o.Nodes) == 0 && len(o.Resources) == 0
+}
+
 func newProdInitCmd(cli *CLI) *cobra.Command {
-	return &cobra.Command{
+	var (
+		regions   []string
+		nodes     []string
+		resources []string
+		fromFile  string
+	)
+	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Modify service.xml and deployment.xml for production deployment",
 		Long: `Modify service.xml and deployment.xml for production deployment.
@@ -48,9 +67,33 @@ Only basic deployment configuration is available through this command. For
 advanced configuration see the relevant Vespa Cloud documentation and make
 changes to deployment.xml and services.xml directly.
 
+By default, the values to use are prompted for. Giving any of --region, --nodes,
+--resources or --from-file instead changes only the given values, without
+prompting. All values are validated before any file is modified. The file
+given to --from-file is YAML, with the same values as the flags:
+
+regions:
+  - aws-us-east-1c
+  - aws-us-west-2a
+nodes:
+  qrs: 2
+  music: 4,8
+resources:
+  qrs: auto
+  music: vcpu=8,memory=32Gb,disk=300Gb
+
+Flags take precedence over values in the file. A diff of the changes to
+services.xml and deployment.xml is shown before they are written. Changes in
+formatting only are not shown, and files without other changes are not written.
+
 Reference:
 https://cloud.vespa.ai/en/reference/services
 https://cloud.vespa.ai/en/reference/deployment`,
+		Example: `$ vespa prod init
+$ vespa prod init --region aws-us-east-1c --region aws-us-west-2a
+$ vespa prod init --nodes qrs=2 --nodes music=4,8 --resources music=vcpu=8,memory=32Gb,disk=300Gb
+$ vespa prod init --from-file prod.yaml`,
+		Args:              cobra.MaximumNArgs(1),
 		DisableAutoGenTag: true,
 		SilenceUsage:      true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -66,6 +109,10 @@ https://cloud.vespa.ai/en/reference/deployment`,
 				return errHint(fmt.Errorf("cannot modify compressed application package '%s'", pkg.Path),
 					"Try running 'mvn clean' and run this command again")
 			}
+			options, err := prodInitOptionsFrom(fromFile, regions, nodes, resources)
+			if err != nil {
+				return err
+			}
 
 			deploymentXML, err := readDeploymentXML(pkg)
 			if err != nil {
@@ -76,31 +123,155 @@ https://cloud.vespa.ai/en/reference/deployment`,
 				return fmt.Errorf("a services.xml declaring your cluster(s) must exist: %w", err)
 			}
 
-			fmt.Fprint(cli.Stdout, "This will modify any existing ", color.YellowString("deployment.xml"), " and ", color.YellowString("services.xml"),
-				"!\nBefore modification a backup of the original file will be created.\n\n")
-			fmt.Fprint(cli.Stdout, "A default value is suggested (shown inside brackets) based on\nthe files' existing contents. Press enter to use it.\n\n")
-			fmt.Fprint(cli.Stdout, "Abort the configuration at any time by pressing Ctrl-C. The\nfiles will remain untouched.\n\n")
-			fmt.Fprint(cli.Stdout, "See this guide for sizing a Vespa deployment:\n", color.GreenString("https://docs.vespa.ai/en/performance/sizing-search.html\n\n"))
-			r := bufio.NewReader(cli.Stdin)
-			deploymentXML, err = updateRegions(cli, r, deploymentXML, target.Deployment().System)
-			if err != nil {
-				return err
-			}
-			servicesXML, err = updateNodes(cli, r, servicesXML)
-			if err != nil {
-				return err
+			updateDeployment := true
+			if options.empty() {
+				if fromFile != "" {
+					return fmt.Errorf("no regions, nodes or resources found in %s", fromFile)
+				}
+				fmt.Fprint(cli.Stdout, "This will modify any existing ", color.YellowString("deployment.xml"), " and ", color.YellowString("services.xml"),
+					"!\nBefore modification a backup of the original file will be created.\n\n")
+				fmt.Fprint(cli.Stdout, "A default value is suggested (shown inside brackets) based on\nthe files' existing contents. Press enter to use it.\n\n")
+				fmt.Fprint(cli.Stdout, "Abort the configuration at any time by pressing Ctrl-C. The\nfiles will remain untouched.\n\n")
+				fmt.Fprint(cli.Stdout, "See this guide for sizing a Vespa deployment:\n", color.GreenString("https://docs.vespa.ai/en/performance/sizing-search.html\n\n"))
+				r := bufio.NewReader(cli.Stdin)
+				deploymentXML, err = updateRegions(cli, r, deploymentXML, target.Deployment().System)
+				if err != nil {
+					return err
+				}
+				servicesXML, err = updateNodes(cli, r, servicesXML)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cli.Stdout)
+			} else {
+				if err := validateProdInitOptions(options, servicesXML, target.Deployment().System); err != nil {
+					return err
+				}
+				updateDeployment = len(options.Regions) > 0
+				if updateDeployment {
+					deploymentXML, err = setRegions(deploymentXML, options.Regions)
+					if err != nil {
+						return err
+					}
+				}
+				servicesXML, err = setNodes(servicesXML, options)
+				if err != nil {
+					return err
+				}
 			}
 
-			fmt.Fprintln(cli.Stdout)
-			if err := writeWithBackup(cli.Stdout, pkg, "deployment.xml", deploymentXML.String()); err != nil {
-				return err
-			}
-			if err := writeWithBackup(cli.Stdout, pkg, "services.xml", servicesXML.String()); err != nil {
-				return err
+			if updateDeployment {
+				if err := updateXMLFile(cli.Stdout, pkg, "deployment.xml", deploymentXML.String()); err != nil {
+					return err
+				}
 			}
-			return nil
+			return updateXMLFile(cli.Stdout, pkg, "services.xml", servicesXML.String())
 		},
 	}
+	cmd.Flags().StringSliceVarP(&regions, "region", "", nil, "Production region to deploy to. Can be repeated or comma-separated")
+	cmd.Flags().StringArrayVarP(&nodes, "nodes", "", nil, "Node count of a cluster, as cluster=count or cluster=min,max. Can be repeated")
+	cmd.Flags().StringArrayVarP(&resources, "resources", "", nil, "Node resources of a cluster, as cluster=vcpu=N,memory=N,disk=N or cluster=auto. Can be repeated")
+	cmd.Flags().StringVarP(&fromFile, "from-file", "", "", "Read regions, nodes and resources from this YAML file")
+	return cmd
+}
+
+// prodInitOptionsFrom reads options from the YAML file fromFile, if given, and overrides them with values given as
+// flags.
+func prodInitOptionsFrom(fromFile string, regions, nodes, resources []string) (prodInitOptions, error) {
+	var options prodInitOptions
+	if fromFile != "" {
+		f, err := os.Open(fromFile)
+		if err != nil {
+			return prodInitOptions{}, err
+		}
+		defer f.Close()
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		if err := dec.Decode(&options); err != nil && !errors.Is(err, io.EOF) {
+			return prodInitOptions{}, fmt.Errorf("could not read %s: %w", fromFile, err)
+		}
+	}
+	if len(regions) > 0 {
+		options.Regions = regions
+	}
+	for i := range options.Regions {
+		options.Regions[i] = strings.TrimSpace(options.Regions[i])
+	}
+	var err error
+	if options.Nodes, err = parseClusterValues("nodes", nodes, options.Nodes); err != nil {
+		return prodInitOptions{}, err
+	}
+	if options.Resources, err = parseClusterValues("resources", resources, options.Resources); err != nil {
+		return prodInitOptions{}, err
+	}
+	for cluster, count := range options.Nodes {
+		// Accept min,max as a shorthand for [min,max]
+		if count = strings.TrimSpace(count); strings.Contains(count, ",") && !strings.HasPrefix(count, "[") {
+			count = "[" + count + "]"
+		}
+		options.Nodes[cluster] = count
+	}
+	return options, nil
+}
+
+// parseClusterValues parses flag values on the form cluster=value into values.
+func parseClusterValues(flag string, flagValues []string, values map[string]string) (map[string]string, error) {
+	for _, v := range flagValues {
+		cluster, value, ok := strings.Cut(v, "=")
+		if !ok || cluster == "" || value == "" {
+			return nil, fmt.Errorf("invalid value for --%s: %q: must be on the form cluster=value", flag, v)
+		}
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[cluster] = value
+	}
+	return values, nil
+}
+
+// validateProdInitOptions validates all given options against servicesXML, and returns an error for every invalid
+// value.
+func validateProdInitOptions(options prodInitOptions, servicesXML xml.Services, system vespa.System) error {
+	var errs []error
+	if len(options.Regions) > 0 {
+		if err := validateRegions(strings.Join(options.Regions, ","), system); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	clusters := make(map[string]bool)
+	var clusterIDs []string
+	for _, c := range servicesXML.Container {
+		clusters[c.ID] = true
+		clusterIDs = append(clusterIDs, c.ID)
+	}
+	for _, c := range servicesXML.Content {
+		clusters[c.ID] = true
+		clusterIDs = append(clusterIDs, c.ID)
+	}
+	for _, cluster := range sortedClusterKeys(options.Nodes) {
+		if !clusters[cluster] {
+			errs = append(errs, fmt.Errorf("invalid nodes for cluster %s: no such cluster in services.xml, expected one of %s", cluster, strings.Join(clusterIDs, ", ")))
+		} else if err := validateNodeCount(options.Nodes[cluster]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid nodes for cluster %s: %w", cluster, err))
+		}
+	}
+	for _, cluster := range sortedClusterKeys(options.Resources) {
+		if !clusters[cluster] {
+			errs = append(errs, fmt.Errorf("invalid resources for cluster %s: no such cluster in services.xml, expected one of %s", cluster, strings.Join(clusterIDs, ", ")))
+		} else if err := validateResources(options.Resources[cluster]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid resources for cluster %s: %w", cluster, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sortedClusterKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 type prodDeployOptions struct {
@@ -157,6 +328,9 @@ $ vespa prod deploy`,
 			if err := requireCertificate(options.copyCert, true, cli, target, pkg); err != nil {
 				return err
 			}
+			if _, err := pullModels(cli, pkg, false); err != nil {
+				return err
+			}
 			deployment := vespa.DeploymentOptions{ApplicationPackage: pkg, Target: target}
 			submission := vespa.Submission{
 				Risk:        options.risk,
@@ -165,7 +339,12 @@ $ vespa prod deploy`,
 				AuthorEmail: options.authorEmail,
 				SourceURL:   options.sourceURL,
 			}
-			build, err := vespa.Submit(deployment, submission)
+			var build int64
+			err = cli.progress(cli.Stderr, "Uploading application package...", func(progress vespa.ProgressFunc) error {
+				deployment.Progress = progress
+				build, err = vespa.Submit(deployment, submission)
+				return err
+			})
 			if err != nil {
 				return fmt.Errorf("could not deploy application: %w", err)
 			} else {
@@ -216,13 +395,55 @@ func writeWithBackup(stdout io.Writer, pkg vespa.ApplicationPackage, filename, c
 	return os.WriteFile(dst, []byte(contents), 0644)
 }
 
+// updateXMLFile prints the difference between the current contents of XML file filename in pkg and contents, and then
+// writes contents to the file. Differences in formatting are ignored, and the file is left as is if there are no other
+// differences.
+func updateXMLFile(stdout io.Writer, pkg vespa.ApplicationPackage, filename, contents string) error {
+	data, err := os.ReadFile(filepath.Join(pkg.Path, filename))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	current := string(data)
+	if xml.Equal(current, contents) {
+		fmt.Fprintf(stdout, "Not writing %s: File is unchanged\n", color.YellowString(filename))
+		return nil
+	}
+	format := func(s string) string {
+		if formatted, err := xml.Format(s); err == nil {
+			return formatted
+		}
+		return s
+	}
+	printFileDiff(stdout, filename, format(current), format(contents))
+	return writeWithBackup(stdout, pkg, filename, contents)
+}
+
+// printFileDiff prints the difference between current and contents of filename.
+func printFileDiff(stdout io.Writer, filename, current, contents string) {
+	for _, line := range diffLines(current, contents, filename) {
+		switch {
+		case strings.HasPrefix(line, "-"):
+			line = color.RedString(line)
+		case strings.HasPrefix(line, "+"):
+			line = color.GreenString(line)
+		case strings.HasPrefix(line, "@@"):
+			line = color.CyanString(line)
+		}
+		fmt.Fprintln(stdout, line)
+	}
+}
+
 func updateRegions(cli *CLI, stdin *bufio.Reader, deploymentXML xml.Deployment, system vespa.System) (xml.Deployment, error) {
 	regions, err := promptRegions(cli, stdin, deploymentXML, system)
 	if err != nil {
 		return xml.Deployment{}, err
 	}
-	parts := strings.Split(regions, ",")
-	regionElements := xml.Regions(parts...)
+	return setRegions(deploymentXML, strings.Split(regions, ","))
+}
+
+// setRegions replaces the production regions in deploymentXML with regions.
+func setRegions(deploymentXML xml.Deployment, regions []string) (xml.Deployment, error) {
+	regionElements := xml.Regions(regions...)
 	if err := deploymentXML.Replace("prod", "region", regionElements); err != nil {
 		return xml.Deployment{}, fmt.Errorf("could not update region elements in deployment.xml: %w", err)
 	}
@@ -249,16 +470,18 @@ func promptRegions(cli *CLI, stdin *bufio.Reader, deploymentXML xml.Deployment,
 			currentRegions = append(currentRegions, r.Name)
 		}
 	}
-	validator := func(input string) error {
-		regions := strings.Split(input, ",")
-		for _, r := range regions {
-			if !xml.IsProdRegion(r, system) {
-				return fmt.Errorf("invalid region %s", r)
-			}
+	validator := func(input string) error { return validateRegions(input, system) }
+	return prompt(cli, stdin, "Which regions do you wish to deploy in?", strings.Join(currentRegions, ","), validator)
+}
+
+func validateRegions(input string, system vespa.System) error {
+	regions := strings.Split(input, ",")
+	for _, r := range regions {
+		if !xml.IsProdRegion(r, system) {
+			return fmt.Errorf("invalid region %s", r)
 		}
-		return nil
 	}
-	return prompt(cli, stdin, "Which regions do you wish to deploy in?", strings.Join(currentRegions, ","), validator)
+	return nil
 }
 
 func updateNodes(cli *CLI, r *bufio.Reader, servicesXML xml.Services) (xml.Services, error) {
@@ -283,6 +506,43 @@ func updateNodes(cli *CLI, r *bufio.Reader, servicesXML xml.Services) (xml.Servi
 	return servicesXML, nil
 }
 
+// setNodes replaces the node count and resources of the clusters in servicesXML given in options. Values which are not
+// given are kept.
+func setNodes(servicesXML xml.Services, options prodInitOptions) (xml.Services, error) {
+	update := func(kind, id string, nodes xml.Nodes) error {
+		count, hasCount := options.Nodes[id]
+		spec, hasResources := options.Resources[id]
+		if !hasCount && !hasResources {
+			return nil
+		}
+		if hasCount {
+			nodes.Count = count
+		}
+		if hasResources {
+			nodes.Resources = nil
+			if spec != "auto" {
+				r, err := xml.ParseResources(spec)
+				if err != nil {
+					return err // Should not happen as resources have already been validated
+				}
+				nodes.Resources = &r
+			}
+		}
+		return servicesXML.Replace(kind+"#"+id, "nodes", nodes)
+	}
+	for _, c := range servicesXML.Container {
+		if err := update("container", c.ID, c.Nodes); err != nil {
+			return xml.Services{}, err
+		}
+	}
+	for _, c := range servicesXML.Content {
+		if err := update("content", c.ID, c.Nodes); err != nil {
+			return xml.Services{}, err
+		}
+	}
+	return servicesXML, nil
+}
+
 func promptNodes(cli *CLI, r *bufio.Reader, clusterID string, defaultValue xml.Nodes) (xml.Nodes, error) {
 	count, err := promptNodeCount(cli, r, clusterID, defaultValue.Count)
 	if err != nil {
@@ -307,35 +567,40 @@ func promptNodes(cli *CLI, r *bufio.Reader, clusterID string, defaultValue xml.N
 		}
 		resources = &r
 	}
-	return xml.Nodes{Count: count, Resources: resources}, nil
+	return xml.Nodes{Count: count, Groups: defaultValue.Groups, Resources: resources}, nil
 }
 
 func promptNodeCount(cli *CLI, stdin *bufio.Reader, clusterID string, nodeCount string) (string, error) {
 	fmt.Fprintln(cli.Stdout, color.CyanString("\n> Node count: "+clusterID+" cluster"))
 	fmt.Fprintf(cli.Stdout, "Documentation: %s\n", color.GreenString("https://cloud.vespa.ai/en/reference/services"))
 	fmt.Fprintf(cli.Stdout, "Example: %s\nExample: %s\n\n", color.YellowString("4"), color.YellowString("[2,8]"))
-	validator := func(input string) error {
-		min, _, err := xml.ParseNodeCount(input)
-		if min < 2 {
-			return errHint(fmt.Errorf("at least 2 nodes are required for all clusters in a production environment, got %d", min), "See https://cloud.vespa.ai/en/production-deployment")
-		}
+	return prompt(cli, stdin, fmt.Sprintf("How many nodes should the %s cluster have?", color.CyanString(clusterID)), nodeCount, validateNodeCount)
+}
+
+func validateNodeCount(input string) error {
+	min, _, err := xml.ParseNodeCount(input)
+	if err != nil {
 		return err
 	}
-	return prompt(cli, stdin, fmt.Sprintf("How many nodes should the %s cluster have?", color.CyanString(clusterID)), nodeCount, validator)
+	if min < 2 {
+		return errHint(fmt.Errorf("at least 2 nodes are required for all clusters in a production environment, got %d", min), "See https://cloud.vespa.ai/en/production-deployment")
+	}
+	return nil
 }
 
 func promptResources(cli *CLI, stdin *bufio.Reader, clusterID string, resources string) (string, error) {
 	fmt.Fprintln(cli.Stdout, color.CyanString("\n> Node resources: "+clusterID+" cluster"))
 	fmt.Fprintf(cli.Stdout, "Documentation: %s\n", color.GreenString("https://cloud.vespa.ai/en/reference/services"))
 	fmt.Fprintf(cli.Stdout, "Example: %s\nExample: %s\n\n", color.YellowString("auto"), color.YellowString("vcpu=4,memory=8Gb,disk=100Gb"))
-	validator := func(input string) error {
-		if input == "auto" {
-			return nil
-		}
-		_, err := xml.ParseResources(input)
-		return err
+	return prompt(cli, stdin, fmt.Sprintf("Which resources should each node in the %s cluster have?", color.CyanString(clusterID)), resources, validateResources)
+}
+
+func validateResources(input string) error {
+	if input == "auto" {
+		return nil
 	}
-	return prompt(cli, stdin, fmt.Sprintf("Which resources should each node in the %s cluster have?", color.CyanString(clusterID)), resources, validator)
+	_, err := xml.ParseResources(input)
+	return err
 }
 
 func readDeploymentXML(pkg vespa.ApplicationPackage) (xml.Deployment, error) {
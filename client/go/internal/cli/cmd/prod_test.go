@@ -80,6 +80,108 @@ func TestProdInit(t *testing.T) {
 	assert.True(t, ioutil.Exists(servicesPath+".1.bak"))
 }
 
+func TestProdInitNonInteractive(t *testing.T) {
+	pkgDir := filepath.Join(t.TempDir(), "app")
+	createApplication(t, pkgDir, false, false)
+	cli, stdout, stderr := newProdInitTestCLI(t)
+
+	// All values are validated before anything is written
+	assert.NotNil(t, cli.Run("prod", "init", "--region", "aws-us-west-2a,mars-north-1", "--nodes", "qrs=1", "--nodes", "foo=2",
+		"--resources", "music=vcpu=2", pkgDir))
+	assert.Equal(t, "", stdout.String())
+	assert.Contains(t, stderr.String(), `Error: invalid region mars-north-1
+invalid nodes for cluster foo: no such cluster in services.xml, expected one of qrs, music
+invalid nodes for cluster qrs: at least 2 nodes are required for all clusters in a production environment, got 1
+invalid resources for cluster music: invalid resources: "vcpu=2"
+`)
+	assert.False(t, ioutil.Exists(filepath.Join(pkgDir, "services.xml.1.bak")))
+
+	// Only given values are changed
+	cli, stdout, _ = newProdInitTestCLI(t)
+	assert.Nil(t, cli.Run("prod", "init", "--nodes", "music=4,8", "--resources", "qrs=auto", pkgDir))
+	servicesPath := filepath.Join(pkgDir, "services.xml")
+	assert.Equal(t, `--- a/services.xml
++++ b/services.xml
+@@ -2,15 +2,13 @@
+   <container id="qrs" version="1.0">
+     <document-api></document-api>
+     <search></search>
+-    <nodes count="2">
+-      <resources vcpu="4" memory="8Gb" disk="100Gb"></resources>
+-    </nodes>
++    <nodes count="2"></nodes>
+   </container>
+   <content id="music" version="1.0">
+     <redundancy>2</redundancy>
+     <documents>
+       <document type="music" mode="index"></document>
+     </documents>
+-    <nodes count="4"></nodes>
++    <nodes count="[4,8]"></nodes>
+   </content>
+ </services>
+Backing up existing services.xml to `+servicesPath+`.1.bak
+Writing `+servicesPath+`
+`, stdout.String())
+	assert.False(t, ioutil.Exists(filepath.Join(pkgDir, "deployment.xml.1.bak")))
+
+	// Files without changes, other than in formatting, are left as is
+	cli, stdout, _ = newProdInitTestCLI(t)
+	servicesXML := readFileString(t, servicesPath)
+	require.Nil(t, os.WriteFile(servicesPath, []byte(strings.ReplaceAll(servicesXML, "<search></search>", "<search/>")), 0644))
+	assert.Nil(t, cli.Run("prod", "init", "--region", "aws-us-west-2a", pkgDir))
+	deploymentPath := filepath.Join(pkgDir, "deployment.xml")
+	assert.Equal(t, `--- a/deployment.xml
++++ b/deployment.xml
+@@ -1,5 +1,5 @@
+ <deployment version="1.0">
+   <prod>
+-    <region>aws-us-east-1c</region>
++    <region>aws-us-west-2a</region>
+   </prod>
+ </deployment>
+Backing up existing deployment.xml to `+deploymentPath+`.1.bak
+Writing `+deploymentPath+`
+Not writing services.xml: File is unchanged
+`, stdout.String())
+	assert.False(t, ioutil.Exists(servicesPath+".2.bak"))
+	assert.Contains(t, readFileString(t, servicesPath), "<search/>")
+
+	// Values can be read from a file, and flags take precedence
+	cli, stdout, _ = newProdInitTestCLI(t)
+	initFile := filepath.Join(t.TempDir(), "prod.yaml")
+	require.Nil(t, os.WriteFile(initFile, []byte(`regions:
+  - aws-us-east-1c
+nodes:
+  qrs: 3
+  music: 6
+resources:
+  music: vcpu=16,memory=64Gb,disk=100Gb
+`), 0644))
+	assert.Nil(t, cli.Run("prod", "init", "--from-file", initFile, "--region", "aws-us-west-2a,aws-eu-west-1a", "--nodes", "qrs=4", "--resources", "qrs=auto", pkgDir))
+	assert.Contains(t, readFileString(t, filepath.Join(pkgDir, "deployment.xml")), "<region>aws-us-west-2a</region>")
+	assert.NotContains(t, readFileString(t, filepath.Join(pkgDir, "deployment.xml")), "aws-us-east-1c")
+	servicesXML = readFileString(t, servicesPath)
+	assert.Contains(t, servicesXML, `<nodes count="4"></nodes>`)
+	assert.Contains(t, servicesXML, `<nodes count="6">
+      <resources vcpu="16" memory="64Gb" disk="100Gb"></resources>
+    </nodes>`)
+	assert.Contains(t, stdout.String(), `     <region>aws-us-west-2a</region>
++    <region>aws-eu-west-1a</region>
+`)
+}
+
+// newProdInitTestCLI returns a CLI configured for a cloud application, with a fresh set of flags.
+func newProdInitTestCLI(t *testing.T) (*CLI, *bytes.Buffer, *bytes.Buffer) {
+	cli, stdout, stderr := newTestCLI(t, "NO_COLOR=true")
+	assert.Nil(t, cli.Run("config", "set", "target", "cloud"))
+	assert.Nil(t, cli.Run("config", "set", "application", "foo.bar"))
+	assert.Nil(t, cli.Run("auth", "api-key"))
+	stdout.Reset()
+	stderr.Reset()
+	return cli, stdout, stderr
+}
+
 func readFileString(t *testing.T, filename string) string {
 	t.Helper()
 	content, err := os.ReadFile(filename)
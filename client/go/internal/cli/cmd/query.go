@@ -35,11 +35,15 @@ func newQueryCmd(cli *CLI) *cobra.Command {
 		Short: "Issue a query to Vespa",
 		Example: `$ vespa query "yql=select * from music where album contains 'head'" hits=5
 $ vespa query --format=plain "yql=select * from music where album contains 'head'" hits=5
-$ vespa query --header="X-First-Name: Joe" "yql=select * from music where album contains 'head'" hits=5`,
+$ vespa query --header="X-First-Name: Joe" "yql=select * from music where album contains 'head'" hits=5
+$ vespa query @my-alias hits=5`,
 		Long: `Issue a query to Vespa.
 
 Any parameter from https://docs.vespa.ai/en/reference/query-api-reference.html
-can be set by the syntax [parameter-name]=[value].`,
+can be set by the syntax [parameter-name]=[value].
+
+An argument on the form @name is replaced by the arguments of the alias name.
+See 'vespa config alias' for how to manage aliases.`,
 		// TODO: Support referencing a query json file
 		DisableAutoGenTag: true,
 		SilenceUsage:      true,
@@ -69,6 +73,10 @@ func printCurl(stderr io.Writer, url string, service *vespa.Service) error {
 }
 
 func query(cli *CLI, arguments []string, timeoutSecs int, curl bool, format string, headers []string, waiter *Waiter) error {
+	arguments, err := cli.config.expandAliases(arguments)
+	if err != nil {
+		return err
+	}
 	target, err := cli.target(targetOptions{})
 	if err != nil {
 		return err
@@ -5,11 +5,17 @@
 package cmd
 
 import (
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/httputil"
 	"github.com/vespa-engine/vespa/client/go/internal/mock"
 )
 
@@ -203,3 +209,74 @@ func assertQueryServiceError(t *testing.T, status int, errorMessage string) {
 		stderr.String(),
 		"error output")
 }
+
+func TestQueryCustomTargetClusters(t *testing.T) {
+	configHome := t.TempDir()
+	assertConfigCommand(t, configHome, "", "config", "set", "target", "http://192.0.2.42:19071")
+	assertConfigCommand(t, configHome, "", "config", "set", "endpoint.feed", "http://192.0.2.43:8080")
+	assertConfigCommand(t, configHome, "", "config", "set", "endpoint.query", "https://192.0.2.44")
+
+	client := &mock.HTTPClient{}
+	cli, _, stderr := newTestCLI(t, "VESPA_CLI_HOME="+configHome)
+	cli.httpClient = client
+	client.NextResponseString(200, "{\"query\":\"result\"}")
+	assert.Nil(t, cli.Run("query", "-C", "query", "select * from sources * where true"))
+	assert.Equal(t, "https://192.0.2.44/search/?timeout=10s&yql=select+%2A+from+sources+%2A+where+true", client.LastRequest.URL.String())
+
+	// A cluster must be chosen when there are multiple
+	cli, _, stderr = newTestCLI(t, "VESPA_CLI_HOME="+configHome)
+	assert.NotNil(t, cli.Run("query", "select * from sources * where true"))
+	assert.Equal(t, "Error: no service specified: known services: feed, query\nHint: The --cluster option specifies the service to use\n", stderr.String())
+
+	// Endpoints from environment take precedence
+	cli, _, _ = newTestCLI(t, "VESPA_CLI_HOME="+configHome,
+		"VESPA_CLI_ENDPOINTS={\"endpoints\":[{\"cluster\":\"query\",\"url\":\"http://192.0.2.45:8080\"}]}")
+	cli.httpClient = client
+	client.NextResponseString(200, "{\"query\":\"result\"}")
+	assert.Nil(t, cli.Run("query", "-C", "query", "select * from sources * where true"))
+	assert.Equal(t, "http://192.0.2.45:8080/search/?timeout=10s&yql=select+%2A+from+sources+%2A+where+true", client.LastRequest.URL.String())
+
+	// Without endpoints, a cluster cannot be specified for an URL target
+	cli, _, stderr = newTestCLI(t)
+	assert.NotNil(t, cli.Run("query", "-t", "http://192.0.2.42:8080", "-C", "query", "select * from sources * where true"))
+	assert.Equal(t, "Error: cluster cannot be specified when target is an URL\n"+
+		"Hint: Set cluster endpoints with 'vespa config set endpoint.<cluster> <url>' to use multiple clusters\n", stderr.String())
+}
+
+func TestQueryK8sTarget(t *testing.T) {
+	query := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"query\":\"" + r.URL.RawQuery + "\"}"))
+	}))
+	defer query.Close()
+	api := mock.NewKubernetesAPI(t)
+	api.AddService("vespa", "my-vespa-query", map[string]string{"app.kubernetes.io/instance": "my-vespa"},
+		map[string]string{"app": "query"}, mock.KubernetesPort{Name: "http", Port: 8080})
+	api.AddPod("vespa", "query-0", map[string]string{"app": "query"}, true,
+		map[int]string{8080: strings.TrimPrefix(query.URL, "http://")})
+	kubeconfig := api.WriteKubeconfig(t, "test")
+
+	cli, stdout, _ := newTestCLI(t, "KUBECONFIG="+kubeconfig)
+	cli.httpClient = httputil.NewClient(10 * time.Second)
+	assert.Nil(t, cli.Run("query", "-t", "k8s://vespa/my-vespa", "-C", "query", "select * from sources * where true"))
+	assert.Equal(t, "{\n    \"query\": \"timeout=10s\u0026yql=select+%2A+from+sources+%2A+where+true\"\n}\n", stdout.String())
+
+	cli, _, stderr := newTestCLI(t, "KUBECONFIG="+kubeconfig)
+	assert.NotNil(t, cli.Run("query", "-t", "k8s://vespa/other", "select * from sources * where true"))
+	assert.Equal(t, "Error: no services found for release other in namespace vespa\n", stderr.String())
+}
+
+func TestQueryVia(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	bastion := listener.Addr().String()
+	listener.Close()
+
+	cli, _, stderr := newTestCLI(t)
+	cli.httpClient = httputil.NewClient(10 * time.Second)
+	assert.NotNil(t, cli.Run("query", "-t", "http://192.0.2.42:8080", "--via", "ssh://deployer@"+bastion, "select * from sources * where true"))
+	assert.Contains(t, stderr.String(), "ssh tunnel to 192.0.2.42:8080 failed: could not connect to ssh://deployer@"+bastion+": ")
+
+	cli, _, stderr = newTestCLI(t)
+	assert.NotNil(t, cli.Run("query", "-t", "k8s://vespa/my-vespa", "--via", "ssh://bastion", "select * from sources * where true"))
+	assert.Equal(t, "Error: via option cannot be used with k8s target\n", stderr.String())
+}
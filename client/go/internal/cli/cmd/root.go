@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -22,6 +23,8 @@ import (
 	"github.com/vespa-engine/vespa/client/go/internal/cli/auth/auth0"
 	"github.com/vespa-engine/vespa/client/go/internal/cli/auth/zts"
 	"github.com/vespa-engine/vespa/client/go/internal/httputil"
+	"github.com/vespa-engine/vespa/client/go/internal/k8s"
+	"github.com/vespa-engine/vespa/client/go/internal/sshtunnel"
 	"github.com/vespa-engine/vespa/client/go/internal/version"
 	"github.com/vespa-engine/vespa/client/go/internal/vespa"
 )
@@ -34,6 +37,7 @@ const (
 	targetFlag      = "target"
 	colorFlag       = "color"
 	quietFlag       = "quiet"
+	viaFlag         = "via"
 
 	anyTarget = iota
 	localTargetOnly
@@ -51,6 +55,7 @@ type CLI struct {
 	exec       executor
 	isTerminal func() bool
 	spinner    func(w io.Writer, message string, fn func() error) error
+	progress   func(w io.Writer, message string, fn func(progress vespa.ProgressFunc) error) error
 
 	now           func() time.Time
 	retryInterval time.Duration
@@ -64,6 +69,8 @@ type CLI struct {
 	httpClientFactory func(timeout time.Duration) httputil.Client
 	auth0Factory      auth0Factory
 	ztsFactory        ztsFactory
+
+	tunnel *sshtunnel.Tunnel
 }
 
 // ErrCLI is an error returned to the user. It wraps an exit status, a regular error and optional hints for resolving
@@ -85,6 +92,9 @@ type targetOptions struct {
 	supportedType int
 }
 
+// k8sScheme is the prefix of targets naming a Vespa installation in Kubernetes.
+const k8sScheme = "k8s://"
+
 type targetType struct {
 	name string
 	url  string
@@ -96,6 +106,8 @@ func errHint(err error, hints ...string) ErrCLI { return ErrCLI{Status: 1, hints
 type executor interface {
 	LookPath(name string) (string, error)
 	Run(name string, args ...string) ([]byte, error)
+	// Exec runs program name with args, extra environment variables env and the given standard streams.
+	Exec(name string, args []string, env []string, stdin io.Reader, stdout, stderr io.Writer) error
 }
 
 type execSubprocess struct{}
@@ -104,6 +116,14 @@ func (c *execSubprocess) LookPath(name string) (string, error) { return exec.Loo
 func (c *execSubprocess) Run(name string, args ...string) ([]byte, error) {
 	return exec.Command(name, args...).Output()
 }
+func (c *execSubprocess) Exec(name string, args []string, env []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
 
 type auth0Factory func(httpClient httputil.Client, options auth0.Options) (vespa.Authenticator, error)
 
@@ -112,6 +132,12 @@ type ztsFactory func(httpClient httputil.Client, domain, url string) (vespa.Auth
 // newSpinner writes message to writer w and executes function fn. While fn is running a spinning animation will be
 // displayed after message.
 func newSpinner(w io.Writer, message string, fn func() error) error {
+	return newProgressSpinner(w, message, func(vespa.ProgressFunc) error { return fn() })
+}
+
+// newProgressSpinner is like newSpinner, but fn may report the progress of an upload, which is displayed after the
+// animation.
+func newProgressSpinner(w io.Writer, message string, fn func(progress vespa.ProgressFunc) error) error {
 	s := spinner.New(spinner.CharSets[11], 100*time.Millisecond, spinner.WithWriter(w))
 	// Cursor is hidden by default. Hiding cursor requires Stop() to be called to restore cursor (i.e. if the process is
 	// interrupted), however we don't want to bother with a signal handler just for this
@@ -125,7 +151,11 @@ func newSpinner(w io.Writer, message string, fn func() error) error {
 	s.Prefix = message
 	s.FinalMSG = "\r" + message + "done\n"
 	s.Start()
-	err := fn()
+	err := fn(func(sent, total int64) {
+		s.Lock()
+		s.Suffix = " " + formatProgress(sent, total)
+		s.Unlock()
+	})
 	if err != nil {
 		s.FinalMSG = "\r" + message + "failed\n"
 	}
@@ -133,6 +163,15 @@ func newSpinner(w io.Writer, message string, fn func() error) error {
 	return err
 }
 
+// formatProgress returns a description of sent out of total bytes.
+func formatProgress(sent, total int64) string {
+	percent := int64(100)
+	if total > 0 {
+		percent = sent * 100 / total
+	}
+	return fmt.Sprintf("%.1f MB of %.1f MB (%d%%)", float64(sent)/1000/1000, float64(total)/1000/1000, percent)
+}
+
 // New creates the Vespa CLI, writing output to stdout and stderr, and reading environment variables from environment.
 func New(stdout, stderr io.Writer, environment []string) (*CLI, error) {
 	cmd := &cobra.Command{
@@ -233,7 +272,10 @@ func (c *CLI) configureOutput(cmd *cobra.Command, args []string) error {
 		colorize = true
 	case "never":
 	default:
-		return fmt.Errorf("invalid color option: %s", colorValue)
+		// Config commands are allowed to run, so that the invalid value can be reported and corrected
+		if !strings.HasPrefix(cmd.CommandPath(), "vespa config ") {
+			return fmt.Errorf("invalid color option: %s", colorValue)
+		}
 	}
 	color.NoColor = !colorize
 	return nil
@@ -248,14 +290,16 @@ func (c *CLI) configureFlags() map[string]*pflag.Flag {
 		zone        string
 		color       string
 		quiet       bool
+		via         string
 	)
-	c.cmd.PersistentFlags().StringVarP(&target, targetFlag, "t", "local", `The target platform to use. Must be "local", "cloud", "hosted" or an URL`)
+	c.cmd.PersistentFlags().StringVarP(&target, targetFlag, "t", "local", `The target platform to use. Must be "local", "cloud", "hosted", an URL or k8s://<namespace>/<release>`)
 	c.cmd.PersistentFlags().StringVarP(&application, applicationFlag, "a", "", "The application to use (cloud only)")
 	c.cmd.PersistentFlags().StringVarP(&instance, instanceFlag, "i", "", "The instance of the application to use (cloud only)")
 	c.cmd.PersistentFlags().StringVarP(&cluster, clusterFlag, "C", "", "The container cluster to use. This is only required for applications with multiple clusters")
 	c.cmd.PersistentFlags().StringVarP(&zone, zoneFlag, "z", "", "The zone to use. This defaults to a dev zone (cloud only)")
 	c.cmd.PersistentFlags().StringVarP(&color, colorFlag, "c", "auto", `Whether to use colors in output. Must be "auto", "never", or "always"`)
 	c.cmd.PersistentFlags().BoolVarP(&quiet, quietFlag, "q", false, "Print only errors")
+	c.cmd.PersistentFlags().StringVar(&via, viaFlag, "", "Connect to the target through an SSH host, given as ssh://[user@]host[:port]")
 	flags := make(map[string]*pflag.Flag)
 	c.cmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
 		flags[flag.Name] = flag
@@ -271,19 +315,29 @@ func (c *CLI) configureSpinner() {
 		c.spinner = func(w io.Writer, message string, fn func() error) error {
 			return fn()
 		}
+		c.progress = func(w io.Writer, message string, fn func(progress vespa.ProgressFunc) error) error {
+			return fn(nil)
+		}
 	} else {
 		c.spinner = newSpinner
+		c.progress = newProgressSpinner
 	}
 }
 
 func (c *CLI) configureCommands() {
 	rootCmd := c.cmd
+	appCmd := newAppCmd()
 	authCmd := newAuthCmd()
 	certCmd := newCertCmd(c)
 	configCmd := newConfigCmd()
+	aliasCmd := newConfigAliasCmd()
 	documentCmd := newDocumentCmd(c)
+	modelCmd := newModelCmd()
 	prodCmd := newProdCmd()
 	statusCmd := newStatusCmd(c)
+	appCmd.AddCommand(newAppDescribeCmd(c))         // app describe
+	appCmd.AddCommand(newAppGenerateCmd(c))         // app generate
+	rootCmd.AddCommand(appCmd)                      // app
 	certCmd.AddCommand(newCertAddCmd(c))            // auth cert add
 	authCmd.AddCommand(certCmd)                     // auth cert
 	authCmd.AddCommand(newAPIKeyCmd(c))             // auth api-key
@@ -294,6 +348,11 @@ func (c *CLI) configureCommands() {
 	configCmd.AddCommand(newConfigGetCmd(c))        // config get
 	configCmd.AddCommand(newConfigSetCmd(c))        // config set
 	configCmd.AddCommand(newConfigUnsetCmd(c))      // config unset
+	configCmd.AddCommand(newConfigValidateCmd(c))   // config validate
+	aliasCmd.AddCommand(newConfigAliasSetCmd(c))    // config alias set
+	aliasCmd.AddCommand(newConfigAliasUnsetCmd(c))  // config alias unset
+	aliasCmd.AddCommand(newConfigAliasListCmd(c))   // config alias list
+	configCmd.AddCommand(aliasCmd)                  // config alias
 	rootCmd.AddCommand(configCmd)                   // config
 	rootCmd.AddCommand(newCurlCmd(c))               // curl
 	rootCmd.AddCommand(newDeployCmd(c))             // deploy
@@ -307,6 +366,9 @@ func (c *CLI) configureCommands() {
 	rootCmd.AddCommand(documentCmd)                 // document
 	rootCmd.AddCommand(newLogCmd(c))                // log
 	rootCmd.AddCommand(newManCmd(c))                // man
+	modelCmd.AddCommand(newModelPullCmd(c))         // model pull
+	modelCmd.AddCommand(newModelVerifyCmd(c))       // model verify
+	rootCmd.AddCommand(modelCmd)                    // model
 	rootCmd.AddCommand(newGendocCmd(c))             // gendoc
 	prodCmd.AddCommand(newProdInitCmd(c))           // prod init
 	prodCmd.AddCommand(newProdDeployCmd(c))         // prod deploy
@@ -320,6 +382,7 @@ func (c *CLI) configureCommands() {
 	rootCmd.AddCommand(newVisitCmd(c))              // visit
 	rootCmd.AddCommand(newFeedCmd(c))               // feed
 	rootCmd.AddCommand(newFetchCmd(c))              // fetch
+	c.registerCompletions()
 }
 
 func (c *CLI) bindWaitFlag(cmd *cobra.Command, defaultSecs int, value *int) {
@@ -393,14 +456,19 @@ func (c *CLI) target(opts targetOptions) (vespa.Target, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := c.configureTunnel(targetType); err != nil {
+		return nil, err
+	}
 	var target vespa.Target
 	switch targetType.name {
 	case vespa.TargetLocal, vespa.TargetCustom:
 		target, err = c.createCustomTarget(targetType.name, targetType.url)
 	case vespa.TargetCloud, vespa.TargetHosted:
 		target, err = c.createCloudTarget(targetType.name, opts, targetType.url)
+	case vespa.TargetK8s:
+		target, err = c.createK8sTarget(targetType.url)
 	default:
-		return nil, errHint(fmt.Errorf("invalid target: %s", targetType), "Valid targets are 'local', 'cloud', 'hosted' or an URL")
+		return nil, errHint(fmt.Errorf("invalid target: %s", targetType), "Valid targets are 'local', 'cloud', 'hosted', an URL or k8s://<namespace>/<release>")
 	}
 	if err != nil {
 		return nil, err
@@ -426,9 +494,12 @@ func (c *CLI) targetType(targetTypeRestriction int) (targetType, error) {
 		if err != nil {
 			return targetType{}, err
 		}
+	} else if strings.HasPrefix(tt.name, k8sScheme) {
+		tt.url = tt.name
+		tt.name = vespa.TargetK8s
 	}
 	unsupported := (targetTypeRestriction == cloudTargetOnly && tt.name != vespa.TargetCloud && tt.name != vespa.TargetHosted) ||
-		(targetTypeRestriction == localTargetOnly && tt.name != vespa.TargetLocal && tt.name != vespa.TargetCustom)
+		(targetTypeRestriction == localTargetOnly && tt.name != vespa.TargetLocal && tt.name != vespa.TargetCustom && tt.name != vespa.TargetK8s)
 	if unsupported {
 		return targetType{}, fmt.Errorf("command does not support %s target", tt.name)
 	}
@@ -462,12 +533,85 @@ func (c *CLI) createCustomTarget(targetType, customURL string) (vespa.Target, er
 	case vespa.TargetLocal:
 		return vespa.LocalTarget(c.httpClient, tlsOptions, c.retryInterval), nil
 	case vespa.TargetCustom:
-		return vespa.CustomTarget(c.httpClient, customURL, tlsOptions, c.retryInterval), nil
+		clusterURLs, err := c.customClusterURLs()
+		if err != nil {
+			return nil, err
+		}
+		return vespa.CustomTarget(c.httpClient, customURL, clusterURLs, tlsOptions, c.retryInterval), nil
 	default:
 		return nil, fmt.Errorf("invalid custom target: %s", targetType)
 	}
 }
 
+// configureTunnel routes all connections to the target through the SSH host given by the via option, if set.
+func (c *CLI) configureTunnel(targetType targetType) error {
+	via := c.config.via()
+	if via == "" || c.tunnel != nil {
+		return nil
+	}
+	if targetType.name == vespa.TargetK8s {
+		return fmt.Errorf("%s option cannot be used with %s target", viaFlag, targetType.name)
+	}
+	tunnel, err := sshtunnel.Parse(via)
+	if err != nil {
+		return err
+	}
+	if userHome, err := os.UserHomeDir(); err == nil {
+		tunnel.KnownHostsFiles = append(tunnel.KnownHostsFiles, filepath.Join(userHome, ".ssh", "known_hosts"))
+	}
+	tunnel.KnownHostsFiles = append(tunnel.KnownHostsFiles, "/etc/ssh/ssh_known_hosts")
+	tunnel.AgentSocket = c.Environment["SSH_AUTH_SOCK"]
+	c.tunnel = tunnel
+	httputil.ConfigureDialer(c.httpClient, tunnel.DialContext)
+	httpClientFactory := c.httpClientFactory
+	c.httpClientFactory = func(timeout time.Duration) httputil.Client {
+		client := httpClientFactory(timeout)
+		httputil.ConfigureDialer(client, tunnel.DialContext)
+		return client
+	}
+	return nil
+}
+
+// parseK8sTarget parses a target of the form k8s://<namespace>/<release>, where the namespace may be omitted. The
+// kubeconfig context to use may be given as a query parameter, as in k8s://<namespace>/<release>?context=<context>.
+func parseK8sTarget(value string) (vespa.K8sOptions, string, error) {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme+"://" != k8sScheme {
+		return vespa.K8sOptions{}, "", fmt.Errorf("invalid kubernetes target: %q", value)
+	}
+	release := strings.TrimPrefix(u.Path, "/")
+	if release == "" || strings.Contains(release, "/") {
+		return vespa.K8sOptions{}, "", fmt.Errorf("invalid kubernetes target: %q: must be on the form k8s://<namespace>/<release>", value)
+	}
+	return vespa.K8sOptions{Namespace: u.Host, Release: release}, u.Query().Get("context"), nil
+}
+
+func (c *CLI) createK8sTarget(targetURL string) (vespa.Target, error) {
+	options, kubeContext, err := parseK8sTarget(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	kubeconfig, err := k8s.DefaultConfigPath(c.Environment)
+	if err != nil {
+		return nil, err
+	}
+	config, err := k8s.LoadConfig(kubeconfig, kubeContext)
+	if err != nil {
+		return nil, errHint(err, "The kubeconfig is read from $KUBECONFIG, or ~/.kube/config if unset")
+	}
+	if options.Namespace == "" {
+		options.Namespace = config.Namespace
+	}
+	if options.Namespace == "" {
+		options.Namespace = "default"
+	}
+	tlsOptions, err := c.config.readTLSOptions(vespa.DefaultApplication, vespa.TargetK8s)
+	if err != nil {
+		return nil, err
+	}
+	return vespa.K8sTarget(c.httpClient, k8s.NewClient(config), options, tlsOptions, c.retryInterval)
+}
+
 func (c *CLI) cloudApiAuthenticator(deployment vespa.Deployment, system vespa.System) (vespa.Authenticator, error) {
 	apiKey, err := c.config.readAPIKey(c, deployment.Application.Tenant)
 	if err != nil {
@@ -583,6 +727,19 @@ func (c *CLI) isCloudCI() bool {
 	return ok
 }
 
+// customClusterURLs returns the URLs of container clusters configured for a custom target, if any. Endpoints set in
+// environment take precedence over those in config.
+func (c *CLI) customClusterURLs() (map[string]string, error) {
+	endpoints, err := c.endpointsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if endpoints != nil {
+		return endpoints, nil
+	}
+	return c.config.clusterURLs(), nil
+}
+
 func (c *CLI) endpointsFromEnv() (map[string]string, error) {
 	endpointsString := c.Environment["VESPA_CLI_ENDPOINTS"]
 	if endpointsString == "" {
@@ -604,6 +761,7 @@ func (c *CLI) endpointsFromEnv() (map[string]string, error) {
 
 // Run executes the CLI with given args. If args is nil, it defaults to os.Args[1:].
 func (c *CLI) Run(args ...string) error {
+	c.addPlugin(args)
 	c.cmd.SetArgs(args)
 	err := c.cmd.Execute()
 	if err != nil {
@@ -658,5 +816,10 @@ func (c *CLI) applicationPackageFrom(args []string, options vespa.PackageOptions
 	} else if len(args) > 1 {
 		return vespa.ApplicationPackage{}, fmt.Errorf("expected 0 or 1 arguments, got %d", len(args))
 	}
-	return vespa.FindApplicationPackage(path, options)
+	pkg, err := vespa.FindApplicationPackage(path, options)
+	if err != nil {
+		return vespa.ApplicationPackage{}, err
+	}
+	pkg.ModelCache = vespa.ModelCache{Dir: filepath.Join(c.config.cacheDir, "models")}
+	return pkg, nil
 }
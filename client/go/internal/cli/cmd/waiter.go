@@ -37,8 +37,15 @@ func (w *Waiter) Service(target vespa.Target, cluster string) (*vespa.Service, e
 	if err != nil {
 		return nil, err
 	}
-	if targetType.url != "" && cluster != "" {
-		return nil, fmt.Errorf("cluster cannot be specified when target is an URL")
+	if targetType.url != "" && targetType.name != vespa.TargetK8s && cluster != "" {
+		clusterURLs, err := w.cli.customClusterURLs()
+		if err != nil {
+			return nil, err
+		}
+		if targetType.name != vespa.TargetCustom || len(clusterURLs) == 0 {
+			return nil, errHint(fmt.Errorf("cluster cannot be specified when target is an URL"),
+				"Set cluster endpoints with 'vespa config set endpoint.<cluster> <url>' to use multiple clusters")
+		}
 	}
 	services, err := w.services(target)
 	if err != nil {
@@ -21,9 +21,12 @@ type Client interface {
 }
 
 type defaultClient struct {
-	client *http.Client
+	client      *http.Client
+	dialContext dialFunc
 }
 
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
 func (c *defaultClient) Do(request *http.Request, timeout time.Duration) (response *http.Response, error error) {
 	if c.client.Timeout != timeout { // Set wanted timeout
 		c.client.Timeout = timeout
@@ -71,12 +74,29 @@ func ForceHTTP2(client Client, certificates []tls.Certificate, caCertificate []b
 	if !ok {
 		return
 	}
-	var dialFunc func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error)
+	var dialTLSFunc func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error)
+	dial := c.dialContext
+	if dial == nil {
+		dialer := net.Dialer{}
+		dial = dialer.DialContext
+	}
 	if certificates == nil {
 		// No certificate, so force H2C (HTTP/2 over clear-text) by using a non-TLS Dialer
-		dialer := net.Dialer{}
-		dialFunc = func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
-			return dialer.DialContext(ctx, network, addr)
+		dialTLSFunc = func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		}
+	} else if c.dialContext != nil {
+		dialTLSFunc = func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			tlsConn := tls.Client(conn, cfg)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
 		}
 	}
 	// Use HTTP/2 transport explicitly. Connection reuse does not work properly when using regular http.Transport, even
@@ -86,11 +106,27 @@ func ForceHTTP2(client Client, certificates []tls.Certificate, caCertificate []b
 	c.client.Transport = &http2.Transport{
 		DisableCompression: true,
 		AllowHTTP:          true,
-		DialTLSContext:     dialFunc,
+		DialTLSContext:     dialTLSFunc,
 	}
 	ConfigureTLS(client, certificates, caCertificate, trustAll)
 }
 
+// ConfigureDialer configures the given client to open connections with dial, e.g. to route all connections through a
+// tunnel. The dialer is kept if the client is later configured with ForceHTTP2.
+func ConfigureDialer(client Client, dial func(ctx context.Context, network, addr string) (net.Conn, error)) {
+	c, ok := client.(*defaultClient)
+	if !ok {
+		return
+	}
+	c.dialContext = dial
+	if tr, ok := c.client.Transport.(*http.Transport); ok {
+		// Clone as this may be the shared default transport
+		tr = tr.Clone()
+		tr.DialContext = dial
+		c.client.Transport = tr
+	}
+}
+
 // NewClients creates a new HTTP client the given default timeout.
 func NewClient(timeout time.Duration) Client {
 	return &defaultClient{
@@ -0,0 +1,53 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package httputil
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestConfigureDialer(t *testing.T) {
+	server := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}), &http2.Server{}))
+	defer server.Close()
+
+	var dialed []string
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		// Route all connections to the test server, regardless of address
+		return net.Dial(network, server.Listener.Addr().String())
+	}
+	client := NewClient(time.Second)
+	ConfigureDialer(client, dial)
+	assertGet(t, client, "http://192.0.2.1:8080/", "HTTP/1.1")
+	assert.Equal(t, []string{"192.0.2.1:8080"}, dialed)
+	assert.NotSame(t, http.DefaultTransport, client.(*defaultClient).client.Transport)
+
+	// Dialer is used with HTTP/2
+	ForceHTTP2(client, nil, nil, false)
+	assertGet(t, client, "http://192.0.2.2:8080/", "HTTP/2.0")
+	assert.Equal(t, []string{"192.0.2.1:8080", "192.0.2.2:8080"}, dialed)
+}
+
+func assertGet(t *testing.T, client Client, url, expected string) {
+	t.Helper()
+	req, err := http.NewRequest("GET", url, nil)
+	require.Nil(t, err)
+	resp, err := client.Do(req, time.Second)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, expected, string(body))
+}
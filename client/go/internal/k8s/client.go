@@ -0,0 +1,174 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is a client for the Kubernetes API.
+type Client struct {
+	config     *Config
+	httpClient *http.Client
+}
+
+// Service is a Kubernetes service.
+type Service struct {
+	Name     string
+	Labels   map[string]string
+	Selector map[string]string
+	Ports    []ServicePort
+}
+
+// ServicePort is a port exposed by a Kubernetes service.
+type ServicePort struct {
+	Name string
+	Port int
+	// TargetPort is the port on the pod receiving traffic for this port. This is zero if the target port is named.
+	TargetPort int
+}
+
+// Pod is a Kubernetes pod.
+type Pod struct {
+	Name    string
+	Labels  map[string]string
+	Running bool
+}
+
+type metadata struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+}
+
+type serviceList struct {
+	Items []struct {
+		Metadata metadata `json:"metadata"`
+		Spec     struct {
+			Selector map[string]string `json:"selector"`
+			Ports    []struct {
+				Name       string          `json:"name"`
+				Port       int             `json:"port"`
+				TargetPort json.RawMessage `json:"targetPort"`
+			} `json:"ports"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+type podList struct {
+	Items []struct {
+		Metadata metadata `json:"metadata"`
+		Status   struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// NewClient creates a new client for the API server in config.
+func NewClient(config *Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config.TLSConfig
+	return &Client{config: config, httpClient: &http.Client{Transport: transport, Timeout: 30 * time.Second}}
+}
+
+// Config returns the configuration of this client.
+func (c *Client) Config() *Config { return c.config }
+
+// Services returns the services in namespace matching labelSelector.
+func (c *Client) Services(namespace, labelSelector string) ([]Service, error) {
+	var list serviceList
+	if err := c.get("/api/v1/namespaces/"+url.PathEscape(namespace)+"/services", labelSelector, &list); err != nil {
+		return nil, err
+	}
+	services := make([]Service, 0, len(list.Items))
+	for _, item := range list.Items {
+		service := Service{Name: item.Metadata.Name, Labels: item.Metadata.Labels, Selector: item.Spec.Selector}
+		for _, p := range item.Spec.Ports {
+			port := ServicePort{Name: p.Name, Port: p.Port}
+			// targetPort is either a port number or the name of a port in the pod spec
+			if targetPort, err := strconv.Atoi(string(p.TargetPort)); err == nil {
+				port.TargetPort = targetPort
+			} else if len(p.TargetPort) == 0 {
+				port.TargetPort = p.Port
+			}
+			service.Ports = append(service.Ports, port)
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// Pods returns the pods in namespace matching labelSelector.
+func (c *Client) Pods(namespace, labelSelector string) ([]Pod, error) {
+	var list podList
+	if err := c.get("/api/v1/namespaces/"+url.PathEscape(namespace)+"/pods", labelSelector, &list); err != nil {
+		return nil, err
+	}
+	pods := make([]Pod, 0, len(list.Items))
+	for _, item := range list.Items {
+		pods = append(pods, Pod{Name: item.Metadata.Name, Labels: item.Metadata.Labels, Running: item.Status.Phase == "Running"})
+	}
+	return pods, nil
+}
+
+// Selector returns labels formatted as a label selector.
+func Selector(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (c *Client) get(path, labelSelector string, v any) error {
+	u, err := url.Parse(c.config.Server + path)
+	if err != nil {
+		return err
+	}
+	if labelSelector != "" {
+		u.RawQuery = url.Values{"labelSelector": {labelSelector}}.Encode()
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	c.authenticate(req.Header)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not connect to kubernetes api: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %d from kubernetes api at %s: %s", resp.StatusCode, u.Path, apiErrorMessage(body))
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (c *Client) authenticate(header http.Header) {
+	if c.config.Token != "" {
+		header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+}
+
+// apiErrorMessage extracts the message of a Kubernetes status object, or returns body as is.
+func apiErrorMessage(body []byte) string {
+	var status struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &status); err == nil && status.Message != "" {
+		return status.Message
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,95 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package k8s
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/mock"
+)
+
+func newTestClient(t *testing.T, api *mock.KubernetesAPI) *Client {
+	config, err := LoadConfig(api.WriteKubeconfig(t, "test"), "")
+	require.Nil(t, err)
+	return NewClient(config)
+}
+
+func TestServicesAndPods(t *testing.T) {
+	api := mock.NewKubernetesAPI(t)
+	api.Token = "secret"
+	api.AddService("vespa", "my-release-query", map[string]string{"app.kubernetes.io/instance": "my-release"},
+		map[string]string{"app": "query"},
+		mock.KubernetesPort{Name: "http", Port: 80, TargetPort: 8080},
+		mock.KubernetesPort{Name: "state", Port: 19092, TargetPort: "state"},
+		mock.KubernetesPort{Port: 19071})
+	api.AddService("vespa", "other", map[string]string{"app.kubernetes.io/instance": "other"}, nil)
+	api.AddPod("vespa", "query-0", map[string]string{"app": "query"}, true, nil)
+	api.AddPod("vespa", "query-1", map[string]string{"app": "query"}, false, nil)
+	client := newTestClient(t, api)
+
+	services, err := client.Services("vespa", "app.kubernetes.io/instance=my-release")
+	require.Nil(t, err)
+	assert.Equal(t, []Service{{
+		Name:     "my-release-query",
+		Labels:   map[string]string{"app.kubernetes.io/instance": "my-release"},
+		Selector: map[string]string{"app": "query"},
+		Ports: []ServicePort{
+			{Name: "http", Port: 80, TargetPort: 8080},
+			{Name: "state", Port: 19092},
+			{Port: 19071, TargetPort: 19071},
+		},
+	}}, services)
+
+	pods, err := client.Pods("vespa", Selector(services[0].Selector))
+	require.Nil(t, err)
+	assert.Equal(t, []Pod{
+		{Name: "query-0", Labels: map[string]string{"app": "query"}, Running: true},
+		{Name: "query-1", Labels: map[string]string{"app": "query"}},
+	}, pods)
+
+	api.Token = "wrong"
+	_, err = client.Services("vespa", "")
+	assert.Equal(t, "got status 401 from kubernetes api at /api/v1/namespaces/vespa/services: Unauthorized", err.Error())
+}
+
+func TestSelector(t *testing.T) {
+	assert.Equal(t, "a=1,b=2", Selector(map[string]string{"b": "2", "a": "1"}))
+	assert.Equal(t, "", Selector(nil))
+}
+
+func TestPortForward(t *testing.T) {
+	pod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte("hello " + string(body)))
+	}))
+	defer pod.Close()
+	api := mock.NewKubernetesAPI(t)
+	api.Token = "secret"
+	api.AddPod("vespa", "query-0", nil, true, map[int]string{8080: strings.TrimPrefix(pod.URL, "http://")})
+	client := newTestClient(t, api)
+
+	forward, err := client.PortForward("vespa", "query-0", 8080)
+	require.Nil(t, err)
+	defer forward.Close()
+	httpClient := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	for _, name := range []string{"world", strings.Repeat("x", 100000)} {
+		resp, err := httpClient.Post("http://"+forward.Addr()+"/", "text/plain", strings.NewReader(name))
+		require.Nil(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.Nil(t, err)
+		assert.Equal(t, "hello "+name, string(body))
+	}
+
+	// Forwarding a port which the API server refuses closes the connection
+	forward2, err := client.PortForward("vespa", "query-0", 9090)
+	require.Nil(t, err)
+	defer forward2.Close()
+	_, err = httpClient.Get("http://" + forward2.Addr() + "/")
+	assert.NotNil(t, err)
+}
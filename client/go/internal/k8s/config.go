@@ -0,0 +1,240 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+// Package k8s is a minimal client for the Kubernetes API. It supports what the Vespa CLI needs for targeting Vespa
+// installations in Kubernetes: reading kubeconfig files, listing services and pods, and forwarding ports to pods.
+package k8s
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds what is needed to connect to a Kubernetes API server.
+type Config struct {
+	// Server is the URL of the API server.
+	Server string
+	// Namespace is the default namespace of the selected context. This may be empty.
+	Namespace string
+	// Token is the bearer token used for authentication, if any.
+	Token string
+	// TLSConfig is the TLS configuration used when connecting to the API server.
+	TLSConfig *tls.Config
+}
+
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string        `yaml:"token"`
+			TokenFile             string        `yaml:"tokenFile"`
+			ClientCertificate     string        `yaml:"client-certificate"`
+			ClientCertificateData string        `yaml:"client-certificate-data"`
+			ClientKey             string        `yaml:"client-key"`
+			ClientKeyData         string        `yaml:"client-key-data"`
+			Exec                  *execProvider `yaml:"exec"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// execProvider is a command which prints credentials, as used by e.g. managed Kubernetes services in the cloud.
+type execProvider struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	Env     []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+}
+
+type execCredential struct {
+	Status struct {
+		Token                 string `json:"token"`
+		ClientCertificateData string `json:"clientCertificateData"`
+		ClientKeyData         string `json:"clientKeyData"`
+	} `json:"status"`
+}
+
+// DefaultConfigPath returns the path of the kubeconfig file to use, given environment env.
+func DefaultConfigPath(env map[string]string) (string, error) {
+	if paths := filepath.SplitList(env["KUBECONFIG"]); len(paths) > 0 && paths[0] != "" {
+		return paths[0], nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// LoadConfig reads the kubeconfig file at path, and returns the configuration of given context. If context is empty,
+// the current context of the kubeconfig is used.
+func LoadConfig(path, context string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read kubeconfig: %w", err)
+	}
+	config, err := parseConfig(data, filepath.Dir(path), context)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func parseConfig(data []byte, dir, context string) (*Config, error) {
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, err
+	}
+	if context == "" {
+		context = kc.CurrentContext
+	}
+	if context == "" {
+		return nil, fmt.Errorf("no context selected")
+	}
+	config := &Config{}
+	clusterName, userName := "", ""
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == context {
+			clusterName, userName, config.Namespace = c.Context.Cluster, c.Context.User, c.Context.Namespace
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no such context: %s", context)
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	found = false
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		config.Server = strings.TrimRight(c.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		caPEM, err := readData(c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority, dir)
+		if err != nil {
+			return nil, fmt.Errorf("could not read certificate authority: %w", err)
+		}
+		if caPEM != nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caPEM) {
+				return nil, fmt.Errorf("invalid certificate authority of cluster %s", clusterName)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		found = true
+		break
+	}
+	if !found {
+		return nil, fmt.Errorf("no such cluster: %s", clusterName)
+	}
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		config.Token = u.User.Token
+		if u.User.TokenFile != "" {
+			token, err := os.ReadFile(resolvePath(u.User.TokenFile, dir))
+			if err != nil {
+				return nil, fmt.Errorf("could not read token: %w", err)
+			}
+			config.Token = strings.TrimSpace(string(token))
+		}
+		certPEM, err := readData(u.User.ClientCertificateData, u.User.ClientCertificate, dir)
+		if err != nil {
+			return nil, fmt.Errorf("could not read client certificate: %w", err)
+		}
+		keyPEM, err := readData(u.User.ClientKeyData, u.User.ClientKey, dir)
+		if err != nil {
+			return nil, fmt.Errorf("could not read client key: %w", err)
+		}
+		if u.User.Exec != nil {
+			credential, err := u.User.Exec.run()
+			if err != nil {
+				return nil, err
+			}
+			if credential.Status.Token != "" {
+				config.Token = credential.Status.Token
+			}
+			if credential.Status.ClientCertificateData != "" {
+				certPEM = []byte(credential.Status.ClientCertificateData)
+				keyPEM = []byte(credential.Status.ClientKeyData)
+			}
+		}
+		if certPEM != nil || keyPEM != nil {
+			kp, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate of user %s: %w", userName, err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{kp}
+		}
+		break
+	}
+	config.TLSConfig = tlsConfig
+	return config, nil
+}
+
+func (p *execProvider) run() (*execCredential, error) {
+	cmd := exec.Command(p.Command, p.Args...)
+	cmd.Env = os.Environ()
+	for _, env := range p.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not get credentials from %s: %w", p.Command, err)
+	}
+	var credential execCredential
+	if err := json.Unmarshal(out, &credential); err != nil {
+		return nil, fmt.Errorf("invalid credentials from %s: %w", p.Command, err)
+	}
+	return &credential, nil
+}
+
+// readData returns the base64-decoded data if set, or else the contents of file, if set.
+func readData(data, file, dir string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return os.ReadFile(resolvePath(file, dir))
+	}
+	return nil, nil
+}
+
+// resolvePath resolves path relative to dir, as paths in a kubeconfig are relative to the kubeconfig itself.
+func resolvePath(path, dir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
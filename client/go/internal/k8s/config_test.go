@@ -0,0 +1,77 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com:6443/
+    insecure-skip-tls-verify: true
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com:6443
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+    namespace: vespa
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: prod-user
+users:
+- name: dev-user
+  user:
+    token: secret
+- name: prod-user
+  user:
+    tokenFile: token.txt
+`
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	require.Nil(t, os.WriteFile(path, []byte(testKubeconfig), 0600))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "token.txt"), []byte("prod-secret\n"), 0600))
+
+	config, err := LoadConfig(path, "")
+	require.Nil(t, err)
+	assert.Equal(t, "https://dev.example.com:6443", config.Server)
+	assert.Equal(t, "vespa", config.Namespace)
+	assert.Equal(t, "secret", config.Token)
+	assert.True(t, config.TLSConfig.InsecureSkipVerify)
+
+	config, err = LoadConfig(path, "prod")
+	require.Nil(t, err)
+	assert.Equal(t, "https://prod.example.com:6443", config.Server)
+	assert.Equal(t, "", config.Namespace)
+	assert.Equal(t, "prod-secret", config.Token)
+	assert.False(t, config.TLSConfig.InsecureSkipVerify)
+
+	_, err = LoadConfig(path, "staging")
+	assert.Equal(t, "invalid kubeconfig "+path+": no such context: staging", err.Error())
+	_, err = LoadConfig(filepath.Join(dir, "missing"), "")
+	assert.NotNil(t, err)
+}
+
+func TestDefaultConfigPath(t *testing.T) {
+	path, err := DefaultConfigPath(map[string]string{"KUBECONFIG": "/tmp/a" + string(filepath.ListSeparator) + "/tmp/b"})
+	require.Nil(t, err)
+	assert.Equal(t, "/tmp/a", path)
+
+	path, err = DefaultConfigPath(map[string]string{})
+	require.Nil(t, err)
+	assert.Equal(t, filepath.Join(".kube", "config"), filepath.Join(filepath.Base(filepath.Dir(path)), filepath.Base(path)))
+}
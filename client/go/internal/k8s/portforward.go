@@ -0,0 +1,173 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package k8s
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// portForwardProtocol is the websocket subprotocol of the Kubernetes port-forward API. Each message is prefixed by a
+// channel number: 0 is the data channel and 1 the error channel of the forwarded port. The first message from the
+// server on each channel holds the forwarded port as a little-endian uint16.
+const portForwardProtocol = "v4.channel.k8s.io"
+
+const (
+	dataChannel  = 0
+	errorChannel = 1
+)
+
+// PortForward forwards connections accepted on a local address to a port of a pod, through the Kubernetes API server.
+type PortForward struct {
+	client    *Client
+	namespace string
+	pod       string
+	port      int
+	listener  net.Listener
+	wg        sync.WaitGroup
+}
+
+// PortForward starts forwarding connections to a random local port to given port of pod. Connections to the pod are
+// only established when connections are made to the local port.
+func (c *Client) PortForward(namespace, pod string, port int) (*PortForward, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	f := &PortForward{client: c, namespace: namespace, pod: pod, port: port, listener: listener}
+	f.wg.Add(1)
+	go f.serve()
+	return f, nil
+}
+
+// Addr returns the local address, as host:port, where connections are forwarded from.
+func (f *PortForward) Addr() string { return f.listener.Addr().String() }
+
+// Close stops accepting new connections.
+func (f *PortForward) Close() error {
+	err := f.listener.Close()
+	f.wg.Wait()
+	return err
+}
+
+func (f *PortForward) serve() {
+	defer f.wg.Done()
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.forward(conn)
+	}
+}
+
+func (f *PortForward) forward(conn net.Conn) {
+	defer conn.Close()
+	ws, err := f.dial()
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer conn.Close()
+		f.receive(ws, conn)
+	}()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := conn.Read(buf[1:])
+		if n > 0 {
+			buf[0] = dataChannel
+			if err := websocket.Message.Send(ws, buf[:n+1]); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	ws.Close()
+	<-done
+}
+
+// receive copies data received from the API server to w, until the connection is closed or an error is received.
+func (f *PortForward) receive(ws *websocket.Conn, w io.Writer) {
+	var portReceived [2]bool
+	for {
+		var msg []byte
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			return
+		}
+		if len(msg) == 0 {
+			continue
+		}
+		channel, data := msg[0], msg[1:]
+		if channel > errorChannel {
+			continue
+		}
+		if !portReceived[channel] {
+			portReceived[channel] = true
+			if len(data) < 2 || int(binary.LittleEndian.Uint16(data)) != f.port {
+				return
+			}
+			data = data[2:]
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if channel == errorChannel {
+			return
+		}
+		if _, err := w.Write(data); err != nil {
+			return
+		}
+	}
+}
+
+func (f *PortForward) dial() (*websocket.Conn, error) {
+	server, err := url.Parse(f.client.config.Server)
+	if err != nil {
+		return nil, err
+	}
+	location := *server
+	switch server.Scheme {
+	case "https":
+		location.Scheme = "wss"
+	case "http":
+		location.Scheme = "ws"
+	default:
+		return nil, fmt.Errorf("unsupported scheme of kubernetes api server: %s", server.Scheme)
+	}
+	location.Path = strings.TrimSuffix(server.Path, "/") + "/api/v1/namespaces/" + url.PathEscape(f.namespace) +
+		"/pods/" + url.PathEscape(f.pod) + "/portforward"
+	location.RawQuery = url.Values{"ports": {strconv.Itoa(f.port)}}.Encode()
+	config := &websocket.Config{
+		Location:  &location,
+		Origin:    server,
+		Version:   websocket.ProtocolVersionHybi13,
+		Protocol:  []string{portForwardProtocol},
+		TlsConfig: f.client.config.TLSConfig,
+		Header:    http.Header{},
+	}
+	f.client.authenticate(config.Header)
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		var dialErr *websocket.DialError
+		if errors.As(err, &dialErr) {
+			err = dialErr.Err
+		}
+		return nil, fmt.Errorf("could not forward port %d of pod %s: %w", f.port, f.pod, err)
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}
@@ -0,0 +1,51 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package mock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// FileServer is an HTTP server serving files from memory, standing in for a remote artifact repository.
+type FileServer struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	files    map[string][]byte
+	requests []string
+}
+
+// NewFileServer starts a file server serving files, keyed by path. The server is stopped when the test ends.
+func NewFileServer(t *testing.T, files map[string]string) *FileServer {
+	s := &FileServer{files: make(map[string][]byte)}
+	for path, content := range files {
+		s.files[path] = []byte(content)
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// URL returns the URL of the file at path.
+func (s *FileServer) URL(path string) string { return s.server.URL + path }
+
+// Requests returns the paths requested from this server.
+func (s *FileServer) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *FileServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Path)
+	content, ok := s.files[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(content)
+}
@@ -25,7 +25,8 @@ type HTTPClient struct {
 	LastRequest *http.Request
 
 	// ReadBody controls whether the client consumes the request body automatically. If true, LastBody will contain the
-	// body of the most recent request.
+	// body of the most recent request, and the body of each request in Requests is replaced by a copy which can be read
+	// after the request is done.
 	ReadBody bool
 
 	// LastBody is a copy of the last request payload sent through this.
@@ -86,6 +87,7 @@ func (c *HTTPClient) Do(request *http.Request, timeout time.Duration) (*http.Res
 			return nil, err
 		}
 		c.LastBody = body
+		request.Body = io.NopCloser(bytes.NewReader(body))
 	} else {
 		c.LastBody = nil
 	}
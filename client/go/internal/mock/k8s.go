@@ -0,0 +1,231 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package mock
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+// KubernetesAPI is a fake Kubernetes API server. It serves services and pods, and forwards ports of pods to local
+// addresses.
+type KubernetesAPI struct {
+	// Token is the bearer token required by this server. No authentication is required if empty.
+	Token string
+
+	server   *httptest.Server
+	mu       sync.Mutex
+	services map[string][]kubernetesObject
+	pods     map[string][]kubernetesObject
+	forwards map[string]string
+}
+
+// KubernetesPort is a port of a fake Kubernetes service.
+type KubernetesPort struct {
+	Name       string `json:"name,omitempty"`
+	Port       int    `json:"port"`
+	TargetPort any    `json:"targetPort,omitempty"`
+}
+
+type kubernetesObject struct {
+	Metadata struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels,omitempty"`
+	} `json:"metadata"`
+	Spec   any `json:"spec,omitempty"`
+	Status any `json:"status,omitempty"`
+}
+
+// NewKubernetesAPI starts a fake Kubernetes API server, which is stopped when test t completes.
+func NewKubernetesAPI(t *testing.T) *KubernetesAPI {
+	k := &KubernetesAPI{
+		services: make(map[string][]kubernetesObject),
+		pods:     make(map[string][]kubernetesObject),
+		forwards: make(map[string]string),
+	}
+	k.server = httptest.NewServer(http.HandlerFunc(k.handle))
+	t.Cleanup(k.server.Close)
+	return k
+}
+
+// URL returns the URL of this server.
+func (k *KubernetesAPI) URL() string { return k.server.URL }
+
+// WriteKubeconfig writes a kubeconfig pointing to this server, with given context as the current one, and returns its
+// path.
+func (k *KubernetesAPI) WriteKubeconfig(t *testing.T, context string) string {
+	t.Helper()
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: %[1]s
+clusters:
+- name: fake
+  cluster:
+    server: %[2]s
+contexts:
+- name: %[1]s
+  context:
+    cluster: fake
+    user: fake
+users:
+- name: fake
+  user:
+    token: %[3]q
+`, context, k.server.URL, k.Token)
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte(kubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// AddService adds a service to namespace. Pods matching selector receive traffic for the service.
+func (k *KubernetesAPI) AddService(namespace, name string, labels, selector map[string]string, ports ...KubernetesPort) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var service kubernetesObject
+	service.Metadata.Name = name
+	service.Metadata.Labels = labels
+	service.Spec = map[string]any{"selector": selector, "ports": ports}
+	k.services[namespace] = append(k.services[namespace], service)
+}
+
+// AddPod adds a pod to namespace. Ports of the pod are forwarded to the addresses in forwards.
+func (k *KubernetesAPI) AddPod(namespace, name string, labels map[string]string, running bool, forwards map[int]string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var pod kubernetesObject
+	pod.Metadata.Name = name
+	pod.Metadata.Labels = labels
+	phase := "Pending"
+	if running {
+		phase = "Running"
+	}
+	pod.Status = map[string]string{"phase": phase}
+	k.pods[namespace] = append(k.pods[namespace], pod)
+	for port, addr := range forwards {
+		k.forwards[namespace+"/"+name+":"+strconv.Itoa(port)] = addr
+	}
+}
+
+func (k *KubernetesAPI) handle(w http.ResponseWriter, r *http.Request) {
+	if k.Token != "" && r.Header.Get("Authorization") != "Bearer "+k.Token {
+		writeKubernetesStatus(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/"), "/")
+	k.mu.Lock()
+	switch {
+	case len(parts) == 2 && parts[1] == "services":
+		defer k.mu.Unlock()
+		writeKubernetesList(w, k.services[parts[0]], r.URL.Query().Get("labelSelector"))
+	case len(parts) == 2 && parts[1] == "pods":
+		defer k.mu.Unlock()
+		writeKubernetesList(w, k.pods[parts[0]], r.URL.Query().Get("labelSelector"))
+	case len(parts) == 4 && parts[1] == "pods" && parts[3] == "portforward":
+		port := r.URL.Query().Get("ports")
+		addr, ok := k.forwards[parts[0]+"/"+parts[2]+":"+port]
+		k.mu.Unlock()
+		if !ok {
+			writeKubernetesStatus(w, http.StatusNotFound, "pod "+parts[2]+" not found")
+			return
+		}
+		portNumber, _ := strconv.Atoi(port)
+		server := websocket.Server{
+			Handshake: func(config *websocket.Config, r *http.Request) error {
+				config.Protocol = []string{"v4.channel.k8s.io"}
+				return nil
+			},
+			Handler: func(ws *websocket.Conn) { forwardKubernetesPort(ws, portNumber, addr) },
+		}
+		server.ServeHTTP(w, r)
+	default:
+		k.mu.Unlock()
+		writeKubernetesStatus(w, http.StatusNotFound, "not found")
+	}
+}
+
+func forwardKubernetesPort(ws *websocket.Conn, port int, addr string) {
+	defer ws.Close()
+	ws.PayloadType = websocket.BinaryFrame
+	portBytes := binary.LittleEndian.AppendUint16(nil, uint16(port))
+	for _, channel := range []byte{0, 1} {
+		if err := websocket.Message.Send(ws, append([]byte{channel}, portBytes...)); err != nil {
+			return
+		}
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		websocket.Message.Send(ws, append([]byte{1}, err.Error()...))
+		return
+	}
+	defer conn.Close()
+	go func() {
+		defer conn.Close()
+		for {
+			var msg []byte
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+			if len(msg) > 1 && msg[0] == 0 {
+				if _, err := conn.Write(msg[1:]); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := conn.Read(buf[1:])
+		if n > 0 {
+			buf[0] = 0
+			if err := websocket.Message.Send(ws, buf[:n+1]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeKubernetesList(w http.ResponseWriter, items []kubernetesObject, labelSelector string) {
+	matching := []kubernetesObject{}
+	for _, item := range items {
+		if matchesKubernetesSelector(item.Metadata.Labels, labelSelector) {
+			matching = append(matching, item)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": matching})
+}
+
+func matchesKubernetesSelector(labels map[string]string, selector string) bool {
+	if selector == "" {
+		return true
+	}
+	for _, requirement := range strings.Split(selector, ",") {
+		key, value, _ := strings.Cut(requirement, "=")
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func writeKubernetesStatus(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"kind": "Status", "code": status, "message": message})
+}
@@ -1,11 +1,19 @@
 // Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
 package mock
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+)
 
 type Exec struct {
 	ProgramPath    string
 	CombinedOutput string
+
+	// The program, arguments and environment of the last call to Exec
+	LastName string
+	LastArgs []string
+	LastEnv  []string
 }
 
 func (c *Exec) LookPath(name string) (string, error) {
@@ -18,3 +26,11 @@ func (c *Exec) LookPath(name string) (string, error) {
 func (c *Exec) Run(name string, args ...string) ([]byte, error) {
 	return []byte(c.CombinedOutput), nil
 }
+
+func (c *Exec) Exec(name string, args []string, env []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c.LastName = name
+	c.LastArgs = args
+	c.LastEnv = env
+	_, err := io.WriteString(stdout, c.CombinedOutput)
+	return err
+}
@@ -0,0 +1,219 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+// Package sshtunnel opens connections through an SSH jump host, such as a bastion. A single SSH connection to the host
+// is shared by all connections opened through a tunnel. The host key is verified against known_hosts, and
+// authentication uses the keys of the running SSH agent.
+package sshtunnel
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const defaultPort = 22
+
+// Tunnel opens connections through an SSH host.
+type Tunnel struct {
+	// User is the user to log in as. The current user is used if empty.
+	User string
+	// Host is the SSH host to connect through.
+	Host string
+	// Port is the SSH port of Host. Port 22 is used if zero.
+	Port int
+	// KnownHostsFiles are the files holding known host keys, in the format used by OpenSSH. Files which do not exist
+	// are ignored.
+	KnownHostsFiles []string
+	// AgentSocket is the socket of the SSH agent used for authentication, typically given by $SSH_AUTH_SOCK.
+	AgentSocket string
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// Parse parses a tunnel specification of the form ssh://[user@]host[:port].
+func Parse(spec string) (*Tunnel, error) {
+	u, err := url.Parse(spec)
+	if err != nil || u.Scheme != "ssh" || u.Hostname() == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return nil, fmt.Errorf("invalid ssh tunnel: %q: must be on the form ssh://[user@]host[:port]", spec)
+	}
+	t := &Tunnel{Host: u.Hostname()}
+	if u.User != nil {
+		t.User = u.User.Username()
+	}
+	if port := u.Port(); port != "" {
+		if t.Port, err = strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("invalid ssh tunnel: %q: invalid port", spec)
+		}
+	}
+	return t, nil
+}
+
+// String returns the specification of this tunnel.
+func (t *Tunnel) String() string {
+	u := url.URL{Scheme: "ssh", Host: t.Host}
+	if t.Port != 0 {
+		u.Host = net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	}
+	if t.User != "" {
+		u.User = url.User(t.User)
+	}
+	return u.String()
+}
+
+// DialContext opens a connection to addr, as seen from the SSH host. The network must be tcp. Context ctx only applies
+// to opening the connection: once opened, the connection is not affected by ctx.
+func (t *Tunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if !strings.HasPrefix(network, "tcp") {
+		return nil, fmt.Errorf("unsupported network for ssh tunnel: %s", network)
+	}
+	client, err := t.sshClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ssh tunnel to %s failed: %w", addr, err)
+	}
+	conn, err := client.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh tunnel to %s failed: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// Close closes the SSH connection of this tunnel, and all connections opened through it.
+func (t *Tunnel) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == nil {
+		return nil
+	}
+	err := t.client.Close()
+	t.client = nil
+	return err
+}
+
+// sshClient returns the SSH connection of this tunnel, connecting if there is none.
+func (t *Tunnel) sshClient(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+	port := t.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	hostPort := net.JoinHostPort(t.Host, strconv.Itoa(port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %s: %w", t, err)
+	}
+	config, closeAgent, err := t.clientConfig(hostPort)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer closeAgent()
+	// Abort the handshake if ctx is done before it completes, but never close the connection after that
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, hostPort, config)
+	if !stop() {
+		if err == nil {
+			clientConn.Close()
+		}
+		return nil, fmt.Errorf("could not connect to %s: %w", t, ctx.Err())
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not connect to %s: %w", t, err)
+	}
+	client := ssh.NewClient(clientConn, chans, reqs)
+	t.client = client
+	go func() {
+		// Connect again on the next dial if the SSH connection is lost
+		client.Wait()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.client == client {
+			t.client = nil
+		}
+	}()
+	return client, nil
+}
+
+// clientConfig returns the configuration for connecting to the SSH host at hostPort, and a function closing the
+// connection to the SSH agent once connected.
+func (t *Tunnel) clientConfig(hostPort string) (*ssh.ClientConfig, func(), error) {
+	var knownHostsFiles []string
+	for _, f := range t.KnownHostsFiles {
+		if _, err := os.Stat(f); err == nil {
+			knownHostsFiles = append(knownHostsFiles, f)
+		}
+	}
+	if len(knownHostsFiles) == 0 {
+		return nil, nil, fmt.Errorf("no known_hosts file found in %s: connect to %s with ssh once to add its host key", strings.Join(t.KnownHostsFiles, ", "), t.Host)
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFiles...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read known hosts: %w", err)
+	}
+	if t.AgentSocket == "" {
+		return nil, nil, fmt.Errorf("no ssh agent found: SSH_AUTH_SOCK is not set")
+	}
+	agentConn, err := net.Dial("unix", t.AgentSocket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not connect to ssh agent: %w", err)
+	}
+	userName := t.User
+	if userName == "" {
+		current, err := user.Current()
+		if err != nil {
+			agentConn.Close()
+			return nil, nil, fmt.Errorf("could not determine ssh user: %w", err)
+		}
+		userName = current.Username
+	}
+	config := &ssh.ClientConfig{
+		User:              userName,
+		Auth:              []ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers)},
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: knownHostKeyAlgorithms(hostKeyCallback, hostPort),
+	}
+	return config, func() { agentConn.Close() }, nil
+}
+
+// knownHostKeyAlgorithms returns the algorithms of the keys known for hostPort. Asking the SSH host for a key of one
+// of these algorithms avoids a mismatch when the host has several keys, of which only some are known.
+func knownHostKeyAlgorithms(hostKeyCallback ssh.HostKeyCallback, hostPort string) []string {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil
+	}
+	probeKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if err := hostKeyCallback(hostPort, &net.TCPAddr{}, probeKey); !errors.As(err, &keyErr) {
+		return nil
+	}
+	var algorithms []string
+	for _, known := range keyErr.Want {
+		if known.Key.Type() == ssh.KeyAlgoRSA {
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256)
+		}
+		algorithms = append(algorithms, known.Key.Type())
+	}
+	return algorithms
+}
@@ -0,0 +1,285 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package sshtunnel
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestParse(t *testing.T) {
+	tunnel, err := Parse("ssh://deployer@bastion.example.com:2222")
+	require.Nil(t, err)
+	assert.Equal(t, &Tunnel{User: "deployer", Host: "bastion.example.com", Port: 2222}, tunnel)
+	assert.Equal(t, "ssh://deployer@bastion.example.com:2222", tunnel.String())
+
+	tunnel, err = Parse("ssh://bastion")
+	require.Nil(t, err)
+	assert.Equal(t, &Tunnel{Host: "bastion"}, tunnel)
+	assert.Equal(t, "ssh://bastion", tunnel.String())
+
+	for _, spec := range []string{"bastion", "http://bastion", "ssh://", "ssh://bastion/path", "ssh://bastion:port"} {
+		_, err := Parse(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+// sshServer is an SSH server forwarding direct-tcpip channels, as a bastion host does.
+type sshServer struct {
+	addr        string
+	hostKey     ssh.Signer
+	connections atomic.Int32
+}
+
+func newSSHServer(t *testing.T, authorizedKey ssh.PublicKey) *sshServer {
+	_, hostKey, err := ed25519.GenerateKey(nil)
+	require.Nil(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	require.Nil(t, err)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "deployer" && bytes.Equal(key.Marshal(), authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostSigner)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	server := &sshServer{addr: listener.Addr().String(), hostKey: hostSigner}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *sshServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer serverConn.Close()
+	s.connections.Add(1)
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		targetConn, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			targetConn.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go func() {
+			io.Copy(channel, targetConn)
+			channel.CloseWrite()
+		}()
+		go func() {
+			io.Copy(targetConn, channel)
+			targetConn.Close()
+		}()
+	}
+}
+
+// startAgent starts an SSH agent holding a new key, and returns its socket and public key.
+func startAgent(t *testing.T) (string, ssh.PublicKey) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.Nil(t, err)
+	keyring := agent.NewKeyring()
+	require.Nil(t, keyring.Add(agent.AddedKey{PrivateKey: key}))
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				agent.ServeAgent(keyring, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	signers, err := keyring.Signers()
+	require.Nil(t, err)
+	return socket, signers[0].PublicKey()
+}
+
+// startEchoServer starts a server echoing back everything sent to it.
+func startEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func writeKnownHosts(t *testing.T, addr string, key ssh.PublicKey) string {
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	require.Nil(t, os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{knownhosts.Normalize(addr)}, key)+"\n"), 0600))
+	return knownHosts
+}
+
+func newTunnel(t *testing.T, server *sshServer, agentSocket, knownHosts string) *Tunnel {
+	host, port, err := net.SplitHostPort(server.addr)
+	require.Nil(t, err)
+	tunnel, err := Parse("ssh://deployer@" + net.JoinHostPort(host, port))
+	require.Nil(t, err)
+	tunnel.AgentSocket = agentSocket
+	tunnel.KnownHostsFiles = []string{filepath.Join(t.TempDir(), "missing"), knownHosts}
+	t.Cleanup(func() { tunnel.Close() })
+	return tunnel
+}
+
+func assertEcho(t *testing.T, conn net.Conn, message string) {
+	t.Helper()
+	_, err := conn.Write([]byte(message))
+	require.Nil(t, err)
+	buf := make([]byte, len(message))
+	_, err = io.ReadFull(conn, buf)
+	require.Nil(t, err)
+	assert.Equal(t, message, string(buf))
+}
+
+func TestDial(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ssh agent requires unix sockets")
+	}
+	agentSocket, userKey := startAgent(t)
+	server := newSSHServer(t, userKey)
+	echo := startEchoServer(t)
+	tunnel := newTunnel(t, server, agentSocket, writeKnownHosts(t, server.addr, server.hostKey.PublicKey()))
+
+	conn1, err := tunnel.DialContext(context.Background(), "tcp", echo)
+	require.Nil(t, err)
+	defer conn1.Close()
+	assertEcho(t, conn1, "hello")
+
+	// Connections share a single SSH connection, and are not affected by the dial context ending
+	ctx, cancel := context.WithCancel(context.Background())
+	conn2, err := tunnel.DialContext(ctx, "tcp", echo)
+	require.Nil(t, err)
+	defer conn2.Close()
+	cancel()
+	assertEcho(t, conn2, "world")
+	assertEcho(t, conn1, "again")
+	assert.Equal(t, int32(1), server.connections.Load())
+
+	// A new SSH connection is made if the previous one is closed
+	require.Nil(t, tunnel.Close())
+	conn3, err := tunnel.DialContext(context.Background(), "tcp", echo)
+	require.Nil(t, err)
+	defer conn3.Close()
+	assertEcho(t, conn3, "hello")
+	assert.Equal(t, int32(2), server.connections.Load())
+
+	// Dialing with a context which is already done fails
+	_, err = tunnel.DialContext(ctx, "tcp", echo)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = tunnel.DialContext(context.Background(), "udp", echo)
+	assert.Equal(t, "unsupported network for ssh tunnel: udp", err.Error())
+}
+
+func TestDialFailures(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ssh agent requires unix sockets")
+	}
+	agentSocket, userKey := startAgent(t)
+	server := newSSHServer(t, userKey)
+	echo := startEchoServer(t)
+
+	// Unknown host key
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	require.Nil(t, err)
+	otherSigner, err := ssh.NewSignerFromKey(otherKey)
+	require.Nil(t, err)
+	tunnel := newTunnel(t, server, agentSocket, writeKnownHosts(t, server.addr, otherSigner.PublicKey()))
+	_, err = tunnel.DialContext(context.Background(), "tcp", echo)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ssh tunnel to "+echo+" failed: could not connect to "+tunnel.String()+": ")
+	assert.Contains(t, err.Error(), "knownhosts: key mismatch")
+
+	// Unauthorized user key
+	otherAgentSocket, _ := startAgent(t)
+	tunnel = newTunnel(t, server, otherAgentSocket, writeKnownHosts(t, server.addr, server.hostKey.PublicKey()))
+	_, err = tunnel.DialContext(context.Background(), "tcp", echo)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unable to authenticate")
+
+	// No agent
+	tunnel = newTunnel(t, server, "", writeKnownHosts(t, server.addr, server.hostKey.PublicKey()))
+	_, err = tunnel.DialContext(context.Background(), "tcp", echo)
+	require.NotNil(t, err)
+	assert.Equal(t, "ssh tunnel to "+echo+" failed: no ssh agent found: SSH_AUTH_SOCK is not set", err.Error())
+
+	// No known hosts
+	tunnel = newTunnel(t, server, agentSocket, filepath.Join(t.TempDir(), "known_hosts"))
+	_, err = tunnel.DialContext(context.Background(), "tcp", echo)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no known_hosts file found in ")
+
+	// Forwarding fails
+	tunnel = newTunnel(t, server, agentSocket, writeKnownHosts(t, server.addr, server.hostKey.PublicKey()))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	closedAddr := listener.Addr().String()
+	listener.Close()
+	_, err = tunnel.DialContext(context.Background(), "tcp", closedAddr)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ssh tunnel to "+closedAddr+" failed: ssh: rejected: connect failed")
+}
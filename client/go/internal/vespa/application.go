@@ -4,12 +4,17 @@ package vespa
 import (
 	"archive/zip"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/vespa-engine/vespa/client/go/internal/ioutil"
 	"github.com/vespa-engine/vespa/client/go/internal/vespa/ignore"
@@ -18,6 +23,8 @@ import (
 type ApplicationPackage struct {
 	Path     string
 	TestPath string
+	// ModelCache holds the models listed in the model manifest of the package, which are added when zipping it.
+	ModelCache ModelCache
 }
 
 func (ap *ApplicationPackage) HasCertificate() bool { return ap.hasFile("security", "clients.pem") }
@@ -36,7 +43,7 @@ func (ap *ApplicationPackage) hasFile(pathSegment ...string) bool {
 	if !ap.IsZip() {
 		return ioutil.Exists(filepath.Join(append([]string{ap.Path}, pathSegment...)...))
 	}
-	zipName := filepath.Join(pathSegment...)
+	zipName := path.Join(pathSegment...)
 	return ap.hasZipEntry(func(name string) bool { return zipName == name })
 }
 
@@ -83,23 +90,89 @@ func (ap *ApplicationPackage) Validate() error {
 
 func isZip(filename string) bool { return filepath.Ext(filename) == ".zip" }
 
-func zipDir(dir string, destination string, ignores *ignore.List) error {
+// zipEpoch is the modification time of all entries in zips created by zipDir. This is the earliest time which can be
+// represented in a zip file.
+var zipEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// PackageFile is a file or directory in an application package.
+type PackageFile struct {
+	// Path is the slash-separated path of the file, relative to the application package.
+	Path string
+	// Dir is whether this is a directory. Only excluded directories are listed.
+	Dir bool
+	// ExcludedBy is the ignore rule excluding this file from deployment, or nil if the file is deployed.
+	ExcludedBy *ignore.Rule
+	// Model is the entry in the model manifest this file is added from, or nil if the file is in the package.
+	Model *Model
+
+	source string // Path of the file on disk
+	mode   os.FileMode
+}
+
+// Files returns the files of this application package, sorted by path, including those excluded by ignore files.
+// The contents of excluded directories are not listed.
+func (ap *ApplicationPackage) Files() ([]PackageFile, error) {
+	if ap.IsZip() {
+		r, err := zip.OpenReader(ap.Path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open application package at '%s': %w", ap.Path, err)
+		}
+		defer r.Close()
+		var files []PackageFile
+		for _, f := range r.File {
+			if !f.FileInfo().IsDir() {
+				files = append(files, PackageFile{Path: f.Name})
+			}
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+		return files, nil
+	}
+	return ap.packageFiles(ap.Path, true)
+}
+
+// packageFiles returns the files in package directory dir, and the files of its models if includeModels is true.
+func (ap *ApplicationPackage) packageFiles(dir string, includeModels bool) ([]PackageFile, error) {
+	ignores, err := ignore.ReadTree(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", ignore.FileName, err)
+	}
+	files, err := listDir(dir, ignores)
+	if err != nil || !includeModels {
+		return files, err
+	}
+	models, err := ap.Models()
+	if err != nil || len(models) == 0 {
+		return files, err
+	}
+	if ap.ModelCache.Dir == "" {
+		return nil, fmt.Errorf("application package has models in %s, but no model cache is configured", ModelManifestFile)
+	}
+	for _, f := range files {
+		for _, m := range models {
+			if f.ExcludedBy == nil && f.Path == m.Path {
+				return nil, fmt.Errorf("%s is listed in %s, but also exists in the application package", m.Path, ModelManifestFile)
+			}
+		}
+	}
+	for i := range models {
+		m := &models[i]
+		files = append(files, PackageFile{Path: m.Path, Model: m, source: ap.ModelCache.Path(*m), mode: 0644})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// listDir returns the files in dir, sorted by path, with those matching ignores marked as excluded.
+func listDir(dir string, ignores *ignore.List) ([]PackageFile, error) {
 	if !ioutil.Exists(dir) {
 		message := "'" + dir + "' should be an application package zip or dir, but does not exist"
-		return errors.New(message)
+		return nil, errors.New(message)
 	}
 	if !ioutil.IsDir(dir) {
 		message := "'" + dir + "' should be an application package dir, but is a (non-zip) file"
-		return errors.New(message)
+		return nil, errors.New(message)
 	}
-	file, err := os.Create(destination)
-	if err != nil {
-		message := "Could not create a temporary zip file for the application package: " + err.Error()
-		return errors.New(message)
-	}
-	defer file.Close()
-	w := zip.NewWriter(file)
-	defer w.Close()
+	var files []PackageFile
 	walker := func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -108,7 +181,12 @@ func zipDir(dir string, destination string, ignores *ignore.List) error {
 		if err != nil {
 			return err
 		}
-		if ignores.Match(zipPath) {
+		if zipPath == "." {
+			return nil
+		}
+		zipPath = filepath.ToSlash(zipPath)
+		if rule := ignores.MatchRule(zipPath, info.IsDir()); rule != nil {
+			files = append(files, PackageFile{Path: zipPath, Dir: info.IsDir(), ExcludedBy: rule})
 			if info.IsDir() {
 				return filepath.SkipDir
 			}
@@ -117,25 +195,119 @@ func zipDir(dir string, destination string, ignores *ignore.List) error {
 		if info.IsDir() {
 			return nil
 		}
-		srcFile, err := os.Open(path)
-		if err != nil {
-			return err
+		mode := os.FileMode(0644)
+		if info.Mode()&0111 != 0 {
+			mode = 0755
 		}
-		defer srcFile.Close()
-		zipFile, err := w.Create(zipPath)
-		if err != nil {
-			return err
+		files = append(files, PackageFile{Path: zipPath, source: path, mode: mode})
+		return nil
+	}
+	if err := filepath.Walk(dir, walker); err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// zipDir writes the files in dir, except those matching ignores, to a zip at destination.
+func zipDir(dir string, destination string, ignores *ignore.List) error {
+	files, err := listDir(dir, ignores)
+	if err != nil {
+		return err
+	}
+	return zipFiles(files, destination)
+}
+
+// zipFiles writes files, except those which are excluded, to a zip at destination. The zip is reproducible: Entries
+// are sorted by name, have a fixed modification time and normalized permissions, such that zipping the same files
+// twice produces identical bytes.
+func zipFiles(files []PackageFile, destination string) error {
+	file, err := os.Create(destination)
+	if err != nil {
+		message := "Could not create a temporary zip file for the application package: " + err.Error()
+		return errors.New(message)
+	}
+	defer file.Close()
+	w := zip.NewWriter(file)
+	for _, f := range files {
+		if f.ExcludedBy != nil {
+			continue
 		}
-		_, err = io.Copy(zipFile, srcFile)
+		header := &zip.FileHeader{Name: f.Path, Method: zip.Deflate, Modified: zipEpoch}
+		header.SetMode(f.mode)
+		sum, err := copyToZip(w, header, f.source)
 		if err != nil {
+			if f.Model != nil && os.IsNotExist(err) {
+				return fmt.Errorf("%s: %w", f.Path, ErrModelNotCached)
+			}
 			return err
 		}
-		return nil
+		if f.Model != nil && sum != f.Model.SHA256 {
+			return fmt.Errorf("%s: checksum mismatch: expected %s, got %s: %w", f.Path, f.Model.SHA256, sum, ErrModelCorrupt)
+		}
+	}
+	return w.Close()
+}
+
+// copyToZip copies the file at path to w, and returns its SHA-256 checksum, in hex.
+func copyToZip(w *zip.Writer, header *zip.FileHeader, path string) (string, error) {
+	srcFile, err := os.Open(path)
+	if err != nil {
+		return "", err
 	}
-	return filepath.Walk(dir, walker)
+	defer srcFile.Close()
+	zipFile, err := w.CreateHeader(header)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(zipFile, h), srcFile); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Hash returns the SHA-256 hash, in hex, of the zip which would be deployed for this application package. Since
+// package zips are reproducible, the hash only changes when the contents of the package change.
+func (ap *ApplicationPackage) Hash() (string, error) {
+	r, err := ap.zipReader(false)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("could not read application package: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
-func (ap *ApplicationPackage) openZip(name string) (io.ReadCloser, error) {
+// Zipped returns this application package as a zip file, and a function removing the zip file when it is no longer
+// needed. The zip can then be hashed and deployed without zipping the package again. A package which is already a zip
+// file is returned as is. Tests are not included.
+func (ap *ApplicationPackage) Zipped() (ApplicationPackage, func(), error) {
+	if ap.IsZip() {
+		return ApplicationPackage{Path: ap.Path}, func() {}, nil
+	}
+	dir, err := os.MkdirTemp("", "vespa")
+	if err != nil {
+		return ApplicationPackage{}, nil, fmt.Errorf("could not create a temporary zip file for the application package: %w", err)
+	}
+	remove := func() { os.RemoveAll(dir) }
+	files, err := ap.packageFiles(ap.Path, true)
+	if err != nil {
+		remove()
+		return ApplicationPackage{}, nil, err
+	}
+	zipName := filepath.Join(dir, "application.zip")
+	if err := zipFiles(files, zipName); err != nil {
+		remove()
+		return ApplicationPackage{}, nil, err
+	}
+	return ApplicationPackage{Path: zipName}, remove, nil
+}
+
+func (ap *ApplicationPackage) openZip(name string) (*os.File, error) {
 	f, err := os.Open(name)
 	if err != nil {
 		return nil, fmt.Errorf("could not open application package at '%s': %w", ap.Path, err)
@@ -143,7 +315,9 @@ func (ap *ApplicationPackage) openZip(name string) (io.ReadCloser, error) {
 	return f, nil
 }
 
-func (ap *ApplicationPackage) zipReader(test bool) (io.ReadCloser, error) {
+// zipReader opens the zip of this application package, or of its tests if test is true. The zip of a package
+// directory is written to a temporary file.
+func (ap *ApplicationPackage) zipReader(test bool) (*os.File, error) {
 	path := ap.Path
 	if test {
 		path = ap.TestPath
@@ -159,11 +333,11 @@ func (ap *ApplicationPackage) zipReader(test bool) (io.ReadCloser, error) {
 		tmp.Close()
 		os.Remove(tmp.Name())
 	}()
-	ignores, err := ignore.ReadFile(filepath.Join(path, ".vespaignore"))
+	files, err := ap.packageFiles(path, !test)
 	if err != nil {
-		return nil, fmt.Errorf("could not read .vespaignore: %w", err)
+		return nil, err
 	}
-	if err := zipDir(path, tmp.Name(), ignores); err != nil {
+	if err := zipFiles(files, tmp.Name()); err != nil {
 		return nil, err
 	}
 	return ap.openZip(tmp.Name())
@@ -0,0 +1,165 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespa
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BundleManifestFile is the name of the manifest inside an exported application package.
+const BundleManifestFile = "bundle-manifest.json"
+
+// BundleManifest describes the contents and origin of an exported application package.
+type BundleManifest struct {
+	// Created is the time the bundle was exported.
+	Created time.Time `json:"created"`
+	// CLIVersion is the version of Vespa CLI which exported the bundle.
+	CLIVersion string `json:"cliVersion,omitempty"`
+	// Source is the path of the application package the bundle was exported from.
+	Source string `json:"source,omitempty"`
+	// Submission holds metadata about the source code of the application package.
+	Submission Submission `json:"submission"`
+	// Files holds the SHA-256 checksum of each file in the bundle, except the manifest itself.
+	Files map[string]string `json:"files"`
+}
+
+// ExportBundle writes the zip which would be deployed for application package pkg to destination, with manifest
+// added. The checksums of the manifest are computed from the written files.
+func ExportBundle(pkg ApplicationPackage, manifest BundleManifest, destination string) (BundleManifest, error) {
+	zipReader, err := pkg.zipReader(false)
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	defer zipReader.Close()
+	data, err := io.ReadAll(zipReader)
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	src, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return BundleManifest{}, fmt.Errorf("invalid application package zip: %w", err)
+	}
+	tmpFile := destination + ".tmp"
+	f, err := os.Create(tmpFile)
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	defer os.Remove(tmpFile)
+	defer f.Close()
+	w := zip.NewWriter(f)
+	manifest.Files = make(map[string]string)
+	for _, file := range src.File {
+		if file.Name == BundleManifestFile || file.FileInfo().IsDir() {
+			continue
+		}
+		checksum, err := zipChecksum(file)
+		if err != nil {
+			return BundleManifest{}, err
+		}
+		manifest.Files[file.Name] = checksum
+		// Copy the compressed data as is, so that the bundle contains exactly what would be deployed
+		if err := w.Copy(file); err != nil {
+			return BundleManifest{}, err
+		}
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	mw, err := w.Create(BundleManifestFile)
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	if _, err := mw.Write(append(manifestJSON, '\n')); err != nil {
+		return BundleManifest{}, err
+	}
+	if err := w.Close(); err != nil {
+		return BundleManifest{}, err
+	}
+	if err := f.Close(); err != nil {
+		return BundleManifest{}, err
+	}
+	return manifest, os.Rename(tmpFile, destination)
+}
+
+// VerifyBundle verifies that the files in the bundle at path match the checksums of its manifest, and that no files
+// are missing or have been added.
+func VerifyBundle(path string) (BundleManifest, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return BundleManifest{}, fmt.Errorf("could not open bundle %s: %w", path, err)
+	}
+	defer r.Close()
+	var manifest BundleManifest
+	found := false
+	for _, file := range r.File {
+		if file.Name != BundleManifestFile {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return BundleManifest{}, err
+		}
+		err = json.NewDecoder(rc).Decode(&manifest)
+		rc.Close()
+		if err != nil {
+			return BundleManifest{}, fmt.Errorf("invalid manifest in bundle %s: %w", path, err)
+		}
+		found = true
+	}
+	if !found {
+		return BundleManifest{}, fmt.Errorf("bundle %s has no %s", path, BundleManifestFile)
+	}
+	var problems []string
+	seen := make(map[string]bool)
+	for _, file := range r.File {
+		if file.Name == BundleManifestFile || file.FileInfo().IsDir() {
+			continue
+		}
+		seen[file.Name] = true
+		want, ok := manifest.Files[file.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: not in manifest", file.Name))
+			continue
+		}
+		got, err := zipChecksum(file)
+		if err != nil {
+			return BundleManifest{}, err
+		}
+		if got != want {
+			problems = append(problems, fmt.Sprintf("%s: checksum mismatch: expected %s, got %s", file.Name, want, got))
+		}
+	}
+	for name := range manifest.Files {
+		if !seen[name] {
+			problems = append(problems, fmt.Sprintf("%s: missing from bundle", name))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return BundleManifest{}, fmt.Errorf("bundle %s does not match its manifest:\n%s", path, strings.Join(problems, "\n"))
+	}
+	return manifest, nil
+}
+
+func zipChecksum(file *zip.File) (string, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("could not read %s: %w", file.Name, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
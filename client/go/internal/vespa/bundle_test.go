@@ -0,0 +1,100 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespa
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportAndVerifyBundle(t *testing.T) {
+	appDir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "services.xml"), []byte("<services/>"), 0644))
+	require.Nil(t, os.MkdirAll(filepath.Join(appDir, "schemas"), 0755))
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "schemas", "music.sd"), []byte("schema music {}"), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "notes.txt"), []byte("ignored"), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, ".vespaignore"), []byte("notes.txt\n.vespaignore\n"), 0644))
+
+	bundle := filepath.Join(t.TempDir(), "bundle.zip")
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	manifest, err := ExportBundle(ApplicationPackage{Path: appDir},
+		BundleManifest{Created: created, Source: appDir, Submission: Submission{Commit: "abc123"}}, bundle)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"services.xml":     "baf597c5d96bf3552b097c70e67c89c7823559cb5bcb3be86d6d632f169b2699",
+		"schemas/music.sd": "074770e4993b2b0caea373e00b0576ef3eae266558c52e97c3bf687f5942421b",
+	}, manifest.Files)
+	assert.Equal(t, []string{BundleManifestFile, "schemas/music.sd", "services.xml"}, zipNames(t, bundle))
+
+	verified, err := VerifyBundle(bundle)
+	require.Nil(t, err)
+	assert.Equal(t, manifest, verified)
+	assert.Equal(t, "abc123", verified.Submission.Commit)
+	assert.True(t, created.Equal(verified.Created))
+
+	// Exporting an exported bundle keeps its files and replaces the manifest
+	bundle2 := filepath.Join(t.TempDir(), "bundle.zip")
+	manifest2, err := ExportBundle(ApplicationPackage{Path: bundle}, BundleManifest{Created: created}, bundle2)
+	require.Nil(t, err)
+	assert.Equal(t, manifest.Files, manifest2.Files)
+
+	// Tampered bundle
+	tampered := filepath.Join(t.TempDir(), "tampered.zip")
+	writeZip(t, tampered, bundle, map[string]string{"services.xml": "<services version='2'/>", "extra.txt": "x"})
+	_, err = VerifyBundle(tampered)
+	require.NotNil(t, err)
+	assert.Equal(t, "bundle "+tampered+" does not match its manifest:\n"+
+		"extra.txt: not in manifest\n"+
+		"services.xml: checksum mismatch: expected "+manifest.Files["services.xml"]+", got 210a6a95c38164dc74711dac0bb0774d089b5525ce4ec97d07a28b982f7dd4c4", err.Error())
+
+	// Missing bundle
+	_, err = VerifyBundle(bundle2 + ".missing")
+	assert.NotNil(t, err)
+}
+
+func zipNames(t *testing.T, path string) []string {
+	r, err := zip.OpenReader(path)
+	require.Nil(t, err)
+	defer r.Close()
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeZip writes a copy of the zip at src to dst, with the contents of files replaced or added.
+func writeZip(t *testing.T, dst, src string, files map[string]string) {
+	r, err := zip.OpenReader(src)
+	require.Nil(t, err)
+	defer r.Close()
+	f, err := os.Create(dst)
+	require.Nil(t, err)
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, file := range r.File {
+		if _, ok := files[file.Name]; ok {
+			continue
+		}
+		require.Nil(t, w.Copy(file))
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fw, err := w.Create(name)
+		require.Nil(t, err)
+		_, err = fw.Write([]byte(files[name]))
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+}
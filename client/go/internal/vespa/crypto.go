@@ -117,7 +117,7 @@ func (rs *RequestSigner) Authenticate(request *http.Request) error { return rs.S
 // SignRequest signs the given HTTP request using the private key in rs
 func (rs *RequestSigner) SignRequest(request *http.Request) error {
 	timestamp := rs.now().UTC().Format(time.RFC3339)
-	contentHash, body, err := contentHash(request.Body)
+	contentHash, body, err := requestContentHash(request)
 	if err != nil {
 		return err
 	}
@@ -203,6 +203,24 @@ func FingerprintMD5(pemPublicKey []byte) (string, error) {
 
 }
 
+// requestContentHash returns the content hash of the body of request, and the body to send. A body which can be
+// replayed is hashed from a copy, instead of being read into memory.
+func requestContentHash(request *http.Request) (string, io.Reader, error) {
+	if request.GetBody == nil {
+		return contentHash(request.Body)
+	}
+	body, err := request.GetBody()
+	if err != nil {
+		return "", nil, err
+	}
+	defer body.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, body); err != nil {
+		return "", nil, err
+	}
+	return base64.StdEncoding.EncodeToString(hasher.Sum(nil)), request.Body, nil
+}
+
 func contentHash(r io.Reader) (string, io.Reader, error) {
 	if r == nil {
 		r = strings.NewReader("") // Request without body
@@ -3,6 +3,7 @@ package vespa
 
 import (
 	"encoding/base64"
+	"io"
 	"math/rand"
 	"net/http"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateKeyPair(t *testing.T) {
@@ -43,6 +45,18 @@ func TestSignRequest(t *testing.T) {
 
 	assert.Equal(t, "1970-01-01T00:00:00Z", req.Header.Get("X-Timestamp"))
 	assert.Equal(t, "Iw2DWNyOiJC0xY3utikS7i8gNXrpKlzIYbmOaP4xrLU=", req.Header.Get("X-Content-Hash"))
+	body, err := io.ReadAll(req.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "body", string(body))
+
+	// Body which cannot be replayed
+	req.Body = io.NopCloser(strings.NewReader("body"))
+	req.GetBody = nil
+	require.Nil(t, rs.SignRequest(req))
+	assert.Equal(t, "Iw2DWNyOiJC0xY3utikS7i8gNXrpKlzIYbmOaP4xrLU=", req.Header.Get("X-Content-Hash"))
+	body, err = io.ReadAll(req.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "body", string(body))
 	assert.Equal(t, "my-key", req.Header.Get("X-Key-Id"))
 	key := req.Header.Get("X-Key")
 	assert.NotEmpty(t, key)
@@ -10,7 +10,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
@@ -52,6 +51,8 @@ type DeploymentOptions struct {
 	Target             Target
 	ApplicationPackage ApplicationPackage
 	Version            version.Version
+	// Progress is called as the application package is uploaded, if non-nil.
+	Progress ProgressFunc
 }
 
 type Submission struct {
@@ -356,23 +357,6 @@ func Deploy(deployment DeploymentOptions) (PrepareResult, error) {
 	return uploadApplicationPackage(u, deployment)
 }
 
-func copyToPart(dst *multipart.Writer, src io.Reader, fieldname, filename string) error {
-	var part io.Writer
-	var err error
-	if filename == "" {
-		part, err = dst.CreateFormField(fieldname)
-	} else {
-		part, err = dst.CreateFormFile(fieldname, filename)
-	}
-	if err != nil {
-		return err
-	}
-	if _, err := io.Copy(part, src); err != nil {
-		return err
-	}
-	return nil
-}
-
 func Submit(opts DeploymentOptions, submission Submission) (int64, error) {
 	if !opts.Target.IsCloud() {
 		return 0, fmt.Errorf("%s: deploy is unsupported by %s target", opts, opts.Target.Type())
@@ -385,20 +369,21 @@ func Submit(opts DeploymentOptions, submission Submission) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	var body bytes.Buffer
-	writer := multipart.NewWriter(&body)
 	submitOptions, err := json.Marshal(submission)
 	if err != nil {
 		return 0, err
 	}
-	if err := copyToPart(writer, bytes.NewReader(submitOptions), "submitOptions", ""); err != nil {
+	body := newMultipartBody()
+	defer body.Close()
+	if err := body.addField("submitOptions", submitOptions); err != nil {
 		return 0, err
 	}
 	applicationZip, err := opts.ApplicationPackage.zipReader(false)
 	if err != nil {
 		return 0, err
 	}
-	if err := copyToPart(writer, applicationZip, "applicationZip", "application.zip"); err != nil {
+	if err := body.addFormFile("applicationZip", "application.zip", applicationZip); err != nil {
+		applicationZip.Close()
 		return 0, err
 	}
 	if opts.ApplicationPackage.HasTests() {
@@ -406,21 +391,16 @@ func Submit(opts DeploymentOptions, submission Submission) (int64, error) {
 		if err != nil {
 			return 0, err
 		}
-		if err := copyToPart(writer, testApplicationZip, "applicationTestZip", "application-test.zip"); err != nil {
+		if err := body.addFormFile("applicationTestZip", "application-test.zip", testApplicationZip); err != nil {
+			testApplicationZip.Close()
 			return 0, err
 		}
 	}
-	if err := writer.Close(); err != nil {
+	if err := body.finish(); err != nil {
 		return 0, err
 	}
-	request := &http.Request{
-		URL:    u,
-		Method: "POST",
-		Body:   io.NopCloser(&body),
-		Header: make(http.Header),
-	}
-	request.Header.Set("Content-Type", writer.FormDataContentType())
-	response, err := deployServiceDo(request, time.Minute*40, opts)
+	// Submissions are never retried, as a failed request may still have created a build
+	request, response, err := upload(u, body, opts, time.Minute*40, false)
 	if err != nil {
 		return 0, err
 	}
@@ -459,56 +439,46 @@ func checkDeploymentOpts(opts DeploymentOptions) error {
 	return nil
 }
 
-func newDeploymentRequest(url *url.URL, opts DeploymentOptions) (*http.Request, error) {
-	zipReader, err := opts.ApplicationPackage.zipReader(false)
+// newDeploymentBody returns the body of a request deploying the application package of opts.
+func newDeploymentBody(opts DeploymentOptions) (*uploadBody, error) {
+	zipFile, err := opts.ApplicationPackage.zipReader(false)
 	if err != nil {
 		return nil, err
 	}
-	var body io.Reader
-	header := http.Header{}
-	if opts.Target.IsCloud() {
-		var buf bytes.Buffer
-		form := multipart.NewWriter(&buf)
-		formFile, err := form.CreateFormFile("applicationZip", filepath.Base(opts.ApplicationPackage.Path))
+	if !opts.Target.IsCloud() {
+		body, err := newZipBody(zipFile)
 		if err != nil {
+			zipFile.Close()
 			return nil, err
 		}
-		if _, err := io.Copy(formFile, zipReader); err != nil {
-			return nil, err
-		}
-		if !opts.Version.IsZero() {
-			deployOptions := fmt.Sprintf(`{"vespaVersion":"%s"}`, opts.Version.String())
-			if err := form.WriteField("deployOptions", deployOptions); err != nil {
-				return nil, err
-			}
-		}
-		if err := form.Close(); err != nil {
+		return body, nil
+	}
+	body := newMultipartBody()
+	if err := body.addFormFile("applicationZip", filepath.Base(opts.ApplicationPackage.Path), zipFile); err != nil {
+		zipFile.Close()
+		return nil, err
+	}
+	if !opts.Version.IsZero() {
+		deployOptions := fmt.Sprintf(`{"vespaVersion":"%s"}`, opts.Version.String())
+		if err := body.addField("deployOptions", []byte(deployOptions)); err != nil {
+			body.Close()
 			return nil, err
 		}
-		header.Set("Content-Type", form.FormDataContentType())
-		body = &buf
-	} else {
-		header.Set("Content-Type", "application/zip")
-		body = zipReader
-	}
-	return &http.Request{
-		URL:    url,
-		Method: "POST",
-		Header: header,
-		Body:   io.NopCloser(body),
-	}, nil
+	}
+	if err := body.finish(); err != nil {
+		body.Close()
+		return nil, err
+	}
+	return body, nil
 }
 
 func uploadApplicationPackage(url *url.URL, opts DeploymentOptions) (PrepareResult, error) {
-	request, err := newDeploymentRequest(url, opts)
-	if err != nil {
-		return PrepareResult{}, err
-	}
-	service, err := opts.Target.DeployService()
+	body, err := newDeploymentBody(opts)
 	if err != nil {
 		return PrepareResult{}, err
 	}
-	response, err := service.Do(request, time.Minute*40)
+	defer body.Close()
+	request, response, err := upload(url, body, opts, time.Minute*40, true)
 	if err != nil {
 		return PrepareResult{}, err
 	}
@@ -3,6 +3,7 @@ package vespa
 
 import (
 	"archive/zip"
+	"bytes"
 	"io"
 	"mime"
 	"mime/multipart"
@@ -11,6 +12,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -20,7 +22,7 @@ import (
 )
 
 func TestDeploy(t *testing.T) {
-	httpClient := mock.HTTPClient{}
+	httpClient := mock.HTTPClient{ReadBody: true}
 	target := LocalTarget(&httpClient, TLSOptions{}, 0)
 	appDir, _ := mock.ApplicationPackageDir(t, false, false)
 	opts := DeploymentOptions{
@@ -39,7 +41,7 @@ func TestDeploy(t *testing.T) {
 }
 
 func TestDeployCloud(t *testing.T) {
-	httpClient := mock.HTTPClient{}
+	httpClient := mock.HTTPClient{ReadBody: true}
 	target, _ := createCloudTarget(t, io.Discard)
 	cloudTarget, ok := target.(*cloudTarget)
 	require.True(t, ok)
@@ -72,7 +74,7 @@ func TestDeployCloud(t *testing.T) {
 }
 
 func TestSubmit(t *testing.T) {
-	httpClient := mock.HTTPClient{}
+	httpClient := mock.HTTPClient{ReadBody: true}
 	target, _ := createCloudTarget(t, io.Discard)
 	cloudTarget, ok := target.(*cloudTarget)
 	require.True(t, ok)
@@ -277,6 +279,134 @@ type pkgFixture struct {
 	fail             bool
 }
 
+func TestApplicationPackageHash(t *testing.T) {
+	files := map[string]string{
+		"services.xml":       "<services/>",
+		"schemas/music.sd":   "schema music {}",
+		"schemas/a/album.sd": "schema album {}",
+		"hooks/run.sh":       "#!/bin/sh",
+	}
+	writePackage := func(names ...string) ApplicationPackage {
+		dir := t.TempDir()
+		for _, name := range names {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+			mode := os.FileMode(0600)
+			if filepath.Ext(name) == ".sh" {
+				mode = 0700
+			}
+			require.Nil(t, os.WriteFile(path, []byte(files[name]), mode))
+		}
+		return ApplicationPackage{Path: dir}
+	}
+	pkg := writePackage("services.xml", "schemas/music.sd", "schemas/a/album.sd", "hooks/run.sh")
+	hash, err := pkg.Hash()
+	require.Nil(t, err)
+	assert.Len(t, hash, 64)
+
+	// Zip has sorted entries with fixed time and permissions
+	r, err := pkg.zipReader(false)
+	require.Nil(t, err)
+	data, err := io.ReadAll(r)
+	require.Nil(t, err)
+	r.Close()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.Nil(t, err)
+	var entries []string
+	for _, f := range zr.File {
+		entries = append(entries, f.Name+" "+f.Mode().String())
+		assert.True(t, zipEpoch.Equal(f.Modified), f.Name)
+	}
+	assert.Equal(t, []string{
+		"hooks/run.sh -rwxr-xr-x",
+		"schemas/a/album.sd -rw-r--r--",
+		"schemas/music.sd -rw-r--r--",
+		"services.xml -rw-r--r--",
+	}, entries)
+
+	// Same files, written at a different time and in a different order
+	later := time.Now().Add(time.Hour)
+	require.Nil(t, os.Chtimes(filepath.Join(pkg.Path, "services.xml"), later, later))
+	pkg2 := writePackage("schemas/music.sd", "hooks/run.sh", "services.xml", "schemas/a/album.sd")
+	for _, p := range []ApplicationPackage{pkg, pkg2} {
+		h, err := p.Hash()
+		require.Nil(t, err)
+		assert.Equal(t, hash, h)
+	}
+
+	// Changed contents
+	require.Nil(t, os.WriteFile(filepath.Join(pkg2.Path, "services.xml"), []byte("<services version='1.0'/>"), 0644))
+	hash2, err := pkg2.Hash()
+	require.Nil(t, err)
+	assert.NotEqual(t, hash, hash2)
+
+	// Zipped package has the same hash
+	zipped, remove, err := pkg.Zipped()
+	require.Nil(t, err)
+	assert.True(t, zipped.IsZip())
+	zippedHash, err := zipped.Hash()
+	require.Nil(t, err)
+	assert.Equal(t, hash, zippedHash)
+	remove()
+	assert.NoFileExists(t, zipped.Path)
+}
+
+func TestApplicationPackageFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".vespaignore":               "*.txt\n!README.txt\n/models/\n",
+		"README.txt":                 "readme",
+		"notes.txt":                  "notes",
+		"services.xml":               "<services/>",
+		"models/model.onnx":          "onnx",
+		"schemas/.vespaignore":       "!music.txt\ndraft/\n",
+		"schemas/music.txt":          "music",
+		"schemas/music.sd":           "schema music {}",
+		"schemas/draft/.vespaignore": "!*",
+		"schemas/draft/album.sd":     "schema album {}",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	pkg := ApplicationPackage{Path: dir}
+	files, err := pkg.Files()
+	require.Nil(t, err)
+	var listed []string
+	for _, f := range files {
+		s := f.Path
+		if f.ExcludedBy != nil {
+			s += " " + f.ExcludedBy.String()
+		}
+		listed = append(listed, s)
+	}
+	assert.Equal(t, []string{
+		".vespaignore",
+		"README.txt",
+		"models .vespaignore:3:/models/",
+		"notes.txt .vespaignore:1:*.txt",
+		"schemas/.vespaignore",
+		"schemas/draft schemas/.vespaignore:2:draft/",
+		"schemas/music.sd",
+		"schemas/music.txt",
+		"services.xml",
+	}, listed)
+
+	// Zipped package contains the files which are not excluded
+	r, err := pkg.zipReader(false)
+	require.Nil(t, err)
+	data, err := io.ReadAll(r)
+	require.Nil(t, err)
+	r.Close()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.Nil(t, err)
+	var zipped []string
+	for _, f := range zr.File {
+		zipped = append(zipped, f.Name)
+	}
+	assert.Equal(t, []string{".vespaignore", "README.txt", "schemas/.vespaignore", "schemas/music.sd", "schemas/music.txt", "services.xml"}, zipped)
+}
+
 func assertFindApplicationPackage(t *testing.T, zipOrDir string, fixture pkgFixture) {
 	t.Helper()
 	if fixture.existingFile != "" {
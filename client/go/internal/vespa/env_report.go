@@ -0,0 +1,120 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+// report where environment variables get their values from
+
+package vespa
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/vespa-engine/vespa/client/go/internal/admin/envvars"
+)
+
+// Where the effective value of an environment variable comes from
+const (
+	EnvFromProcess  = "process"  // inherited from the process environment
+	EnvFromOverride = "override" // "override" in default-env.txt
+	EnvFromFallback = "fallback" // "fallback" in default-env.txt
+	EnvFromUnset    = "unset"    // "unset" in default-env.txt
+	EnvFromComputed = "computed" // computed when loading the environment, like PATH
+)
+
+// Replaces values of variables which may hold credentials, like VESPA_CLI_API_KEY
+const EnvRedacted = "(redacted)"
+
+type EnvReportEntry struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	// value from a default-env.txt fallback that was not used
+	IgnoredFallback string `json:"ignored-fallback,omitempty"`
+}
+
+type envReportReceiver struct {
+	entries   map[string]*EnvReportEntry
+	computing bool
+}
+
+func (p *envReportReceiver) set(varName, varVal, source string) {
+	if p.computing {
+		source = EnvFromComputed
+	}
+	p.entries[varName] = &EnvReportEntry{Name: varName, Value: varVal, Source: source}
+}
+
+func (p *envReportReceiver) fallbackVar(varName, varVal string) {
+	old := p.currentValue(varName)
+	if old == "" {
+		p.set(varName, varVal, EnvFromFallback)
+		return
+	}
+	if _, seen := p.entries[varName]; !seen {
+		p.entries[varName] = &EnvReportEntry{Name: varName, Value: old, Source: EnvFromProcess}
+	}
+	if !p.computing && old != varVal {
+		p.entries[varName].IgnoredFallback = varVal
+	}
+}
+func (p *envReportReceiver) overrideVar(varName, varVal string) {
+	if p.computing && p.currentValue(varName) == varVal {
+		return
+	}
+	p.set(varName, varVal, EnvFromOverride)
+}
+func (p *envReportReceiver) unsetVar(varName string) {
+	p.set(varName, "", EnvFromUnset)
+}
+func (p *envReportReceiver) currentValue(varName string) string {
+	if entry, ok := p.entries[varName]; ok {
+		return entry.Value
+	}
+	return os.Getenv(varName)
+}
+
+// true for variables whose names suggest they hold credentials
+func isSecretEnv(varName string) bool {
+	upper := strings.ToUpper(varName)
+	for _, word := range []string{"KEY", "SECRET", "TOKEN", "PASSWORD"} {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(value string) string {
+	if value == "" {
+		return value
+	}
+	return EnvRedacted
+}
+
+// Compute the environment as LoadDefaultEnv would set it up, without
+// modifying the current process environment, and report the origin of
+// each value. Includes all variables mentioned in default-env.txt and
+// all VESPA_* variables. Values of variables which may hold credentials
+// are redacted.
+func DefaultEnvReport() ([]EnvReportEntry, error) {
+	r := &envReportReceiver{entries: make(map[string]*EnvReportEntry)}
+	err := loadDefaultEnvTo(r)
+	r.computing = true
+	r.fallbackVar(envvars.VESPA_HOME, FindHome())
+	ensureGoodPath(r)
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if _, seen := r.entries[name]; !seen && (strings.HasPrefix(name, "VESPA_") || name == envvars.PATH) {
+			r.entries[name] = &EnvReportEntry{Name: name, Value: value, Source: EnvFromProcess}
+		}
+	}
+	result := make([]EnvReportEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		if isSecretEnv(entry.Name) {
+			entry.Value = redact(entry.Value)
+			entry.IgnoredFallback = redact(entry.IgnoredFallback)
+		}
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, err
+}
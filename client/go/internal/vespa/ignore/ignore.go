@@ -1,3 +1,4 @@
+// Package ignore implements ignore lists with the semantics of gitignore, see https://git-scm.com/docs/gitignore.
 package ignore
 
 import (
@@ -5,31 +6,101 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
-// List is a list of ignore patterns.
-type List struct{ patterns []string }
+// FileName is the name of ignore files read by ReadTree.
+const FileName = ".vespaignore"
 
-// Match returns whether path matches any pattern in this list.
+// Rule is a single pattern in an ignore file.
+type Rule struct {
+	// Source is the ignore file containing this rule.
+	Source string
+	// Line is the line number of this rule in Source.
+	Line int
+	// Pattern is the pattern of this rule, as written.
+	Pattern string
+
+	base     string // Directory relative to which the pattern applies
+	negate   bool   // Pattern re-includes matching paths
+	dirOnly  bool   // Pattern only matches directories
+	anchored bool   // Pattern matches the full path, and not only the last element
+	re       *regexp.Regexp
+}
+
+// String returns the location and pattern of this rule, in the same format as 'git check-ignore -v'.
+func (r *Rule) String() string { return fmt.Sprintf("%s:%d:%s", r.Source, r.Line, r.Pattern) }
+
+func (r *Rule) match(name string, dir bool) bool {
+	if r.dirOnly && !dir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(name, r.base+"/") {
+			return false
+		}
+		name = name[len(r.base)+1:]
+	}
+	if !r.anchored {
+		name = path.Base(name)
+	}
+	return r.re.MatchString(name)
+}
+
+// List is a list of ignore rules. Later rules take precedence over earlier ones.
+type List struct{ rules []*Rule }
+
+// Match returns whether path is excluded by this list. A path ending with a separator is matched as a directory.
 func (l *List) Match(path string) bool {
-	for _, pattern := range l.patterns {
-		if ok, _ := filepath.Match(pattern, path); ok {
-			return true
+	path = filepath.ToSlash(path)
+	dir := strings.HasSuffix(path, "/")
+	return l.MatchRule(strings.TrimSuffix(path, "/"), dir) != nil
+}
+
+// MatchRule returns the rule excluding path, or nil if path is not excluded. As with git, a path inside an excluded
+// directory is always excluded, in which case the rule excluding the directory is returned.
+func (l *List) MatchRule(path string, dir bool) *Rule {
+	path = filepath.ToSlash(path)
+	if path == "" || path == "." {
+		return nil
+	}
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		if rule := l.decide(strings.Join(parts[:i], "/"), true); rule != nil {
+			return rule
 		}
-		// A directory exclude applies to all subpaths
-		if strings.HasSuffix(pattern, string(filepath.Separator)) && strings.HasPrefix(path, pattern) {
-			return true
+	}
+	return l.decide(path, dir)
+}
+
+func (l *List) decide(path string, dir bool) *Rule {
+	var last *Rule
+	for _, rule := range l.rules {
+		if rule.match(path, dir) {
+			last = rule
 		}
 	}
-	return false
+	if last == nil || last.negate {
+		return nil
+	}
+	return last
 }
 
 // Read reads an ignore list from reader r.
 func Read(r io.Reader) (*List, error) {
+	rules, err := read(r, FileName, "")
+	if err != nil {
+		return nil, err
+	}
+	return &List{rules: rules}, nil
+}
+
+func read(r io.Reader, source, base string) ([]*Rule, error) {
 	scanner := bufio.NewScanner(r)
-	ignore := List{}
+	var rules []*Rule
 	line := 0
 	for scanner.Scan() {
 		line++
@@ -37,26 +108,181 @@ func Read(r io.Reader) (*List, error) {
 		if pattern == "" || strings.HasPrefix(pattern, "#") {
 			continue
 		}
-		if _, err := filepath.Match(pattern, ""); err != nil {
+		rule, err := parseRule(pattern)
+		if err != nil {
 			return nil, fmt.Errorf("line %d: bad pattern: %s: %w", line, pattern, err)
 		}
-		ignore.patterns = append(ignore.patterns, pattern)
+		rule.Source = source
+		rule.Line = line
+		rule.base = base
+		rules = append(rules, rule)
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
-	return &ignore, nil
+	return rules, nil
+}
+
+func parseRule(pattern string) (*Rule, error) {
+	rule := &Rule{Pattern: pattern}
+	p := pattern
+	if strings.HasPrefix(p, "!") {
+		rule.negate = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") && !strings.HasSuffix(p, `\/`) {
+		rule.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if strings.HasPrefix(p, "/") {
+		rule.anchored = true
+		p = p[1:]
+	}
+	if strings.Contains(p, "/") {
+		rule.anchored = true
+	}
+	if p == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	expr, err := translate(p)
+	if err != nil {
+		return nil, err
+	}
+	if rule.re, err = regexp.Compile(expr); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// translate converts glob pattern p to an equivalent regular expression.
+func translate(p string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch c {
+		case '*':
+			if strings.HasPrefix(p[i:], "**") && (i == 0 || p[i-1] == '/') && (i+2 == len(p) || p[i+2] == '/') {
+				switch {
+				case i+2 == len(p): // Trailing "**" matches everything inside
+					sb.WriteString(".*")
+				default: // "**/" matches zero or more directories
+					sb.WriteString("(?:.*/)?")
+					i++
+				}
+				i++
+				continue
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := classEnd(p, i)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated character class")
+			}
+			class := p[i+1 : end]
+			sb.WriteString("[")
+			if strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^") {
+				sb.WriteString("^")
+				class = class[1:]
+			}
+			sb.WriteString(class)
+			sb.WriteString("]")
+			i = end
+		case '\\':
+			if i+1 == len(p) {
+				return "", fmt.Errorf("trailing backslash")
+			}
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(p[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String(), nil
+}
+
+// classEnd returns the index of the bracket closing the character class starting at p[start], or -1 if there is none.
+func classEnd(p string, start int) int {
+	i := start + 1
+	if i < len(p) && (p[i] == '!' || p[i] == '^') {
+		i++
+	}
+	if i < len(p) && p[i] == ']' { // A leading bracket is part of the class
+		i++
+	}
+	for ; i < len(p); i++ {
+		switch p[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+	return -1
 }
 
 // ReadFile reads an ignore list from the named file. Reading a non-existent file returns an empty list, and no error.
 func ReadFile(name string) (*List, error) {
+	rules, err := readFile(name, name, "")
+	if err != nil {
+		return nil, err
+	}
+	return &List{rules: rules}, nil
+}
+
+func readFile(name, source, base string) ([]*Rule, error) {
 	f, err := os.Open(name)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return &List{}, nil
+			return nil, nil
 		}
 		return nil, err
 	}
 	defer f.Close()
-	return Read(f)
+	return read(f, source, base)
+}
+
+// ReadTree reads the ignore files named FileName in dir and its subdirectories. The rules of a file apply to the
+// directory containing it, and take precedence over rules in files of parent directories. As with git, ignore files in
+// excluded directories are not read.
+func ReadTree(dir string) (*List, error) {
+	list := &List{}
+	err := filepath.WalkDir(dir, func(name string, d os.DirEntry, err error) error {
+		if err != nil {
+			if name == dir && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		base := filepath.ToSlash(rel)
+		if base == "." {
+			base = ""
+		} else if list.MatchRule(base, true) != nil {
+			return filepath.SkipDir
+		}
+		source := path.Join(base, FileName)
+		rules, err := readFile(filepath.Join(name, FileName), source, base)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		// Parent directories are visited first, so rules of deeper files are added last and take precedence
+		list.rules = append(list.rules, rules...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
 }
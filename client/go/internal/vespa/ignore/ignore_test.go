@@ -1,6 +1,8 @@
 package ignore
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -30,7 +32,8 @@ bar/
 	assertMatch(t, list, "foobar", true)
 	assertMatch(t, list, "foo/bar", true)
 	assertMatch(t, list, "foo/bar/baz", true)
-	assertMatch(t, list, "foo/bar/bax", false)
+	assertMatch(t, list, "foo/bar/bax", true) // Inside excluded directory foo
+	assertMatch(t, list, "x/foo", true)
 	assertMatch(t, list, "bar", false)
 	assertMatch(t, list, "bar/", true)
 	assertMatch(t, list, "bar/x", true)
@@ -43,6 +46,107 @@ bar/
 	}
 }
 
+func TestGitignoreSemantics(t *testing.T) {
+	f := `
+*.log
+!important.log
+build/
+/root.txt
+docs/*.md
+**/cache
+models/**
+a/**/z
+\#hash
+\!bang
+file[0-9].txt
+`
+	list, err := Read(strings.NewReader(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Negation
+	assertMatch(t, list, "x.log", true)
+	assertMatch(t, list, "sub/x.log", true)
+	assertMatch(t, list, "important.log", false)
+	assertMatch(t, list, "sub/important.log", false)
+	// Directory only
+	assertMatch(t, list, "build", false)
+	assertMatch(t, list, "build/", true)
+	assertMatch(t, list, "src/build/", true)
+	assertMatch(t, list, "src/build/out.jar", true)
+	// Anchored
+	assertMatch(t, list, "root.txt", true)
+	assertMatch(t, list, "sub/root.txt", false)
+	assertMatch(t, list, "docs/a.md", true)
+	assertMatch(t, list, "docs/sub/a.md", false)
+	assertMatch(t, list, "sub/docs/a.md", false)
+	// Double star
+	assertMatch(t, list, "cache", true)
+	assertMatch(t, list, "x/y/cache/", true)
+	assertMatch(t, list, "models", false)
+	assertMatch(t, list, "models/a/b.onnx", true)
+	assertMatch(t, list, "a/z", true)
+	assertMatch(t, list, "a/b/c/z", true)
+	assertMatch(t, list, "b/a/z", false)
+	// Escapes and character classes
+	assertMatch(t, list, "#hash", true)
+	assertMatch(t, list, "!bang", true)
+	assertMatch(t, list, "file1.txt", true)
+	assertMatch(t, list, "filex.txt", false)
+
+	// A file cannot be re-included if its directory is excluded
+	list, err = Read(strings.NewReader("build/\n!build/keep.txt\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatch(t, list, "build/keep.txt", true)
+	if rule := list.MatchRule("build/keep.txt", false); rule == nil || rule.String() != ".vespaignore:1:build/" {
+		t.Errorf("MatchRule(%q) = %v, want %s", "build/keep.txt", rule, ".vespaignore:1:build/")
+	}
+}
+
+func TestReadTree(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, FileName), "*.tmp\n/data/\n")
+	writeFile(t, filepath.Join(dir, "schemas", FileName), "# Schemas\n!keep.tmp\ndraft-*\n")
+	writeFile(t, filepath.Join(dir, "schemas", "nested", FileName), "!draft-ok.sd\n")
+	list, err := ReadTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatch(t, list, "a.tmp", true)
+	assertMatch(t, list, "schemas/a.tmp", true)
+	assertMatch(t, list, "schemas/keep.tmp", false)
+	assertMatch(t, list, "keep.tmp", true)
+	assertMatch(t, list, "schemas/draft-music.sd", true)
+	assertMatch(t, list, "draft-music.sd", false)
+	assertMatch(t, list, "schemas/nested/draft-ok.sd", false)
+	assertMatch(t, list, "schemas/nested/draft-no.sd", true)
+	assertMatch(t, list, "data/", true)
+	assertMatch(t, list, "schemas/data/", false)
+	if rule := list.MatchRule("schemas/draft-music.sd", false); rule == nil || rule.String() != "schemas/.vespaignore:3:draft-*" {
+		t.Errorf("MatchRule(%q) = %v, want %s", "schemas/draft-music.sd", rule, "schemas/.vespaignore:3:draft-*")
+	}
+
+	writeFile(t, filepath.Join(dir, "bad", FileName), "\nfoo[\n")
+	if _, err := ReadTree(dir); err == nil || err.Error() != "bad/.vespaignore: line 2: bad pattern: foo[: unterminated character class" {
+		t.Errorf("got error %v", err)
+	}
+	if list, err := ReadTree(filepath.Join(dir, "nonexistent")); err != nil || list.Match("foo") {
+		t.Errorf("got %v, %v, want empty list", list, err)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func assertMatch(t *testing.T, list *List, name string, match bool) {
 	if got := list.Match(name); got != match {
 		t.Errorf("Match(%q) = %t, want %t", name, got, match)
@@ -185,3 +185,47 @@ func TestLoadEnvNop(t *testing.T) {
 	assert.True(t, strings.Contains(path, td+"/vespa/bin:"))
 	assert.True(t, strings.Contains(path, ":"+td))
 }
+
+func TestDefaultEnvReport(t *testing.T) {
+	trace.AdjustVerbosity(0)
+	t.Setenv("VESPA_FOO", "was foo")
+	t.Setenv("VESPA_BAR", "was bar")
+	t.Setenv("VESPA_FOOBAR", "foobar")
+	t.Setenv("VESPA_OTHER", "other")
+	t.Setenv("VESPA_CLI_API_KEY", "my api key")
+	t.Setenv("VESPA_CLI_DATA_PLANE_KEY", "my data plane key")
+	t.Setenv("VESPA_DB_PASSWORD", "was password")
+	t.Setenv("VESPA_EMPTY_TOKEN", "")
+	os.Unsetenv("VESPA_QUUX")
+	setup(t, `
+override VESPA_FOO "new foo"
+fallback VESPA_BAR "new bar"
+fallback VESPA_QUUX "new quux"
+unset VESPA_FOOBAR
+fallback VESPA_DB_PASSWORD "new password"
+override VESPA_MY_SECRET "secret"
+`)
+	report, err := DefaultEnvReport()
+	assert.Nil(t, err)
+	entries := make(map[string]EnvReportEntry)
+	for _, entry := range report {
+		entries[entry.Name] = entry
+	}
+	assert.Equal(t, EnvReportEntry{Name: "VESPA_FOO", Value: "new foo", Source: EnvFromOverride}, entries["VESPA_FOO"])
+	assert.Equal(t, EnvReportEntry{Name: "VESPA_BAR", Value: "was bar", Source: EnvFromProcess, IgnoredFallback: "new bar"}, entries["VESPA_BAR"])
+	assert.Equal(t, EnvReportEntry{Name: "VESPA_QUUX", Value: "new quux", Source: EnvFromFallback}, entries["VESPA_QUUX"])
+	assert.Equal(t, EnvReportEntry{Name: "VESPA_FOOBAR", Value: "", Source: EnvFromUnset}, entries["VESPA_FOOBAR"])
+	assert.Equal(t, EnvReportEntry{Name: "VESPA_OTHER", Value: "other", Source: EnvFromProcess}, entries["VESPA_OTHER"])
+	assert.Equal(t, EnvReportEntry{Name: "VESPA_CLI_API_KEY", Value: EnvRedacted, Source: EnvFromProcess}, entries["VESPA_CLI_API_KEY"])
+	assert.Equal(t, EnvReportEntry{Name: "VESPA_CLI_DATA_PLANE_KEY", Value: EnvRedacted, Source: EnvFromProcess}, entries["VESPA_CLI_DATA_PLANE_KEY"])
+	assert.Equal(t, EnvReportEntry{Name: "VESPA_DB_PASSWORD", Value: EnvRedacted, Source: EnvFromProcess, IgnoredFallback: EnvRedacted}, entries["VESPA_DB_PASSWORD"])
+	assert.Equal(t, EnvReportEntry{Name: "VESPA_MY_SECRET", Value: EnvRedacted, Source: EnvFromOverride}, entries["VESPA_MY_SECRET"])
+	assert.Equal(t, EnvReportEntry{Name: "VESPA_EMPTY_TOKEN", Value: "", Source: EnvFromProcess}, entries["VESPA_EMPTY_TOKEN"])
+	assert.Equal(t, EnvFromProcess, entries["VESPA_HOME"].Source)
+	assert.Contains(t, entries["PATH"].Value, os.Getenv("VESPA_HOME")+"/bin")
+	assert.Equal(t, EnvFromComputed, entries["PATH"].Source)
+	// the process environment is untouched
+	assert.Equal(t, "was foo", os.Getenv("VESPA_FOO"))
+	assert.Equal(t, "", os.Getenv("VESPA_QUUX"))
+	assert.Equal(t, "my api key", os.Getenv("VESPA_CLI_API_KEY"))
+}
@@ -0,0 +1,182 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/vespa-engine/vespa/client/go/internal/httputil"
+)
+
+// ModelManifestFile is the name of the manifest listing models which are added to an application package when it is
+// deployed, instead of being stored in it.
+const ModelManifestFile = "models.json"
+
+// ErrModelNotCached is returned when zipping an application package with a model which is not in the model cache.
+var ErrModelNotCached = errors.New("model is not cached")
+
+// ErrModelCorrupt is returned when zipping an application package with a cached model which does not match its checksum.
+var ErrModelCorrupt = errors.New("cached model is corrupt")
+
+var sha256Pattern = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// Model is a large file, such as an ONNX model or a vocabulary, which is added to an application package when deployed.
+type Model struct {
+	// Path is the slash-separated path of the model in the application package.
+	Path string `json:"path"`
+	// URL is where the model is downloaded from. This is either an HTTP(S) URL, a file URL or a path to a local file,
+	// relative to the application package.
+	URL string `json:"url"`
+	// SHA256 is the checksum of the model, in hex.
+	SHA256 string `json:"sha256"`
+}
+
+type modelManifest struct {
+	Models []Model `json:"models"`
+}
+
+// Models returns the models listed in the model manifest of this application package, if any. Models are only
+// resolved for application package directories, so a zipped application package has no models.
+func (ap *ApplicationPackage) Models() ([]Model, error) {
+	if ap.IsZip() {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(ap.Path, ModelManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var manifest modelManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ModelManifestFile, err)
+	}
+	paths := make(map[string]bool)
+	for _, m := range manifest.Models {
+		if m.Path == "" || strings.HasPrefix(m.Path, "/") || strings.HasSuffix(m.Path, "/") || !validPath(m.Path) {
+			return nil, fmt.Errorf("invalid %s: invalid path: %q", ModelManifestFile, m.Path)
+		}
+		if paths[m.Path] {
+			return nil, fmt.Errorf("invalid %s: duplicate path: %s", ModelManifestFile, m.Path)
+		}
+		paths[m.Path] = true
+		if m.URL == "" {
+			return nil, fmt.Errorf("invalid %s: %s: missing url", ModelManifestFile, m.Path)
+		}
+		if !sha256Pattern.MatchString(m.SHA256) {
+			return nil, fmt.Errorf("invalid %s: %s: sha256 must be 64 lowercase hex characters", ModelManifestFile, m.Path)
+		}
+	}
+	return manifest.Models, nil
+}
+
+// ModelCache is a directory holding models, stored by checksum.
+type ModelCache struct {
+	Dir string
+}
+
+// Path returns the path of model m in this cache.
+func (c ModelCache) Path(m Model) string { return filepath.Join(c.Dir, m.SHA256) }
+
+// Has returns whether model m is in this cache. The checksum of the cached model is not verified.
+func (c ModelCache) Has(m Model) bool {
+	_, err := os.Stat(c.Path(m))
+	return err == nil
+}
+
+// Pull downloads model m into this cache, unless it is already cached. The URL of a local model is resolved relative
+// to the application package directory baseDir. Returns whether the model was downloaded.
+func (c ModelCache) Pull(client httputil.Client, m Model, baseDir string) (bool, error) {
+	if c.Has(m) {
+		return false, nil
+	}
+	src, err := openModel(client, m, baseDir)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return false, err
+	}
+	tmp, err := os.CreateTemp(c.Dir, m.SHA256+".*.tmp")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), src); err != nil {
+		return false, fmt.Errorf("could not download %s from %s: %w", m.Path, m.URL, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != m.SHA256 {
+		return false, fmt.Errorf("checksum mismatch for %s from %s: expected %s, got %s", m.Path, m.URL, m.SHA256, got)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp.Name(), c.Path(m))
+}
+
+// Verify verifies that model m is in this cache, and matches its checksum.
+func (c ModelCache) Verify(m Model) error {
+	f, err := os.Open(c.Path(m))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s: %w", m.Path, ErrModelNotCached)
+		}
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != m.SHA256 {
+		return fmt.Errorf("%s: checksum mismatch: expected %s, got %s", m.Path, m.SHA256, got)
+	}
+	return nil
+}
+
+func openModel(client httputil.Client, m Model, baseDir string) (io.ReadCloser, error) {
+	u, err := url.Parse(m.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url for %s: %w", m.Path, err)
+	}
+	switch {
+	case u.Scheme == "http" || u.Scheme == "https":
+		req, err := http.NewRequest("GET", m.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req, 0)
+		if err != nil {
+			return nil, fmt.Errorf("could not download %s from %s: %w", m.Path, m.URL, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("could not download %s from %s: got status %d", m.Path, m.URL, resp.StatusCode)
+		}
+		return resp.Body, nil
+	case u.Scheme == "file":
+		return os.Open(filepath.FromSlash(u.Path))
+	case u.Scheme == "" || len(u.Scheme) == 1: // Relative path, or absolute path with a drive letter
+		path := filepath.FromSlash(m.URL)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		return os.Open(path)
+	default:
+		return nil, fmt.Errorf("unsupported url for %s: %s", m.Path, m.URL)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespa
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/httputil"
+	"github.com/vespa-engine/vespa/client/go/internal/mock"
+)
+
+func TestModels(t *testing.T) {
+	appDir := t.TempDir()
+	pkg := ApplicationPackage{Path: appDir}
+	models, err := pkg.Models()
+	require.Nil(t, err)
+	assert.Nil(t, models)
+
+	checksum := sha256Hex("model")
+	writeModelManifest(t, appDir, Model{Path: "models/a.onnx", URL: "https://example.com/a.onnx", SHA256: checksum})
+	models, err = pkg.Models()
+	require.Nil(t, err)
+	assert.Equal(t, []Model{{Path: "models/a.onnx", URL: "https://example.com/a.onnx", SHA256: checksum}}, models)
+
+	assertInvalid := func(message string, models ...Model) {
+		t.Helper()
+		writeModelManifest(t, appDir, models...)
+		_, err := pkg.Models()
+		require.NotNil(t, err)
+		assert.Equal(t, message, err.Error())
+	}
+	assertInvalid(`invalid models.json: invalid path: "../a.onnx"`, Model{Path: "../a.onnx", URL: "a.onnx", SHA256: checksum})
+	assertInvalid(`invalid models.json: invalid path: "/a.onnx"`, Model{Path: "/a.onnx", URL: "a.onnx", SHA256: checksum})
+	assertInvalid(`invalid models.json: invalid path: ""`, Model{URL: "a.onnx", SHA256: checksum})
+	assertInvalid("invalid models.json: a.onnx: missing url", Model{Path: "a.onnx", SHA256: checksum})
+	assertInvalid("invalid models.json: a.onnx: sha256 must be 64 lowercase hex characters", Model{Path: "a.onnx", URL: "a.onnx", SHA256: "abc"})
+	assertInvalid("invalid models.json: duplicate path: a.onnx",
+		Model{Path: "a.onnx", URL: "a.onnx", SHA256: checksum},
+		Model{Path: "a.onnx", URL: "b.onnx", SHA256: checksum})
+
+	// Zipped packages have no models
+	models, err = (&ApplicationPackage{Path: filepath.Join(appDir, "app.zip")}).Models()
+	require.Nil(t, err)
+	assert.Nil(t, models)
+}
+
+func TestModelCache(t *testing.T) {
+	server := mock.NewFileServer(t, map[string]string{"/models/a.onnx": "model a", "/models/b.onnx": "model b"})
+	client := httputil.NewClient(time.Second)
+	appDir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "local.txt"), []byte("local"), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "local2.txt"), []byte("local2"), 0644))
+	cache := ModelCache{Dir: filepath.Join(t.TempDir(), "models")}
+
+	remote := Model{Path: "models/a.onnx", URL: server.URL("/models/a.onnx"), SHA256: sha256Hex("model a")}
+	local := Model{Path: "vocab.txt", URL: "local.txt", SHA256: sha256Hex("local")}
+	fileURL := Model{Path: "vocab2.txt", URL: "file://" + filepath.ToSlash(filepath.Join(appDir, "local2.txt")), SHA256: sha256Hex("local2")}
+	for _, m := range []Model{remote, local, fileURL} {
+		assert.False(t, cache.Has(m))
+		assert.True(t, errors.Is(cache.Verify(m), ErrModelNotCached))
+		pulled, err := cache.Pull(client, m, appDir)
+		require.Nil(t, err, m.Path)
+		assert.True(t, pulled)
+		assert.True(t, cache.Has(m))
+		assert.Nil(t, cache.Verify(m))
+	}
+	data, err := os.ReadFile(cache.Path(remote))
+	require.Nil(t, err)
+	assert.Equal(t, "model a", string(data))
+
+	// Cached models are not downloaded again
+	pulled, err := cache.Pull(client, remote, appDir)
+	require.Nil(t, err)
+	assert.False(t, pulled)
+	assert.Equal(t, []string{"/models/a.onnx"}, server.Requests())
+
+	// Downloaded model does not match checksum
+	wrong := Model{Path: "models/b.onnx", URL: server.URL("/models/b.onnx"), SHA256: sha256Hex("other")}
+	_, err = cache.Pull(client, wrong, appDir)
+	require.NotNil(t, err)
+	assert.Equal(t, fmt.Sprintf("checksum mismatch for models/b.onnx from %s: expected %s, got %s", wrong.URL, wrong.SHA256, sha256Hex("model b")), err.Error())
+	assert.False(t, cache.Has(wrong))
+	entries, err := os.ReadDir(cache.Dir)
+	require.Nil(t, err)
+	assert.Equal(t, 3, len(entries)) // No temporary files are left behind
+
+	// Missing model
+	missing := Model{Path: "models/c.onnx", URL: server.URL("/models/c.onnx"), SHA256: sha256Hex("c")}
+	_, err = cache.Pull(client, missing, appDir)
+	require.NotNil(t, err)
+	assert.Equal(t, "could not download models/c.onnx from "+missing.URL+": got status 404", err.Error())
+
+	// Corrupt cache
+	require.Nil(t, os.WriteFile(cache.Path(remote), []byte("corrupt"), 0644))
+	err = cache.Verify(remote)
+	require.NotNil(t, err)
+	assert.Equal(t, fmt.Sprintf("models/a.onnx: checksum mismatch: expected %s, got %s", remote.SHA256, sha256Hex("corrupt")), err.Error())
+}
+
+func TestApplicationPackageWithModels(t *testing.T) {
+	appDir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "services.xml"), []byte("<services/>"), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "model.onnx"), []byte("model"), 0644))
+	writeModelManifest(t, appDir, Model{Path: "models/model.onnx", URL: "model.onnx", SHA256: sha256Hex("model")})
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, ".vespaignore"), []byte("/model.onnx\n"), 0644))
+	pkg := ApplicationPackage{Path: appDir}
+	_, err := pkg.Hash()
+	require.NotNil(t, err)
+	assert.Equal(t, "application package has models in models.json, but no model cache is configured", err.Error())
+
+	pkg.ModelCache = ModelCache{Dir: t.TempDir()}
+	_, err = pkg.Hash()
+	assert.True(t, errors.Is(err, ErrModelNotCached))
+
+	models, err := pkg.Models()
+	require.Nil(t, err)
+	_, err = pkg.ModelCache.Pull(nil, models[0], appDir)
+	require.Nil(t, err)
+	r, err := pkg.zipReader(false)
+	require.Nil(t, err)
+	data, err := io.ReadAll(r)
+	require.Nil(t, err)
+	r.Close()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.Nil(t, err)
+	contents := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.Nil(t, err)
+		b, err := io.ReadAll(rc)
+		require.Nil(t, err)
+		rc.Close()
+		contents[f.Name] = string(b)
+	}
+	assert.Equal(t, "model", contents["models/model.onnx"])
+	assert.Equal(t, []string{".vespaignore", "models.json", "models/model.onnx", "services.xml"}, sortedKeys(contents))
+
+	// Corrupt cached model
+	require.Nil(t, os.WriteFile(pkg.ModelCache.Path(models[0]), []byte("mode"), 0644))
+	_, err = pkg.Hash()
+	require.NotNil(t, err)
+	assert.True(t, errors.Is(err, ErrModelCorrupt))
+	assert.Equal(t, fmt.Sprintf("models/model.onnx: checksum mismatch: expected %s, got %s: cached model is corrupt", sha256Hex("model"), sha256Hex("mode")), err.Error())
+	_, _, err = pkg.Zipped()
+	assert.True(t, errors.Is(err, ErrModelCorrupt))
+
+	// Model conflicts with a file in the package
+	require.Nil(t, os.MkdirAll(filepath.Join(appDir, "models"), 0755))
+	require.Nil(t, os.WriteFile(filepath.Join(appDir, "models", "model.onnx"), []byte("model"), 0644))
+	_, err = pkg.Files()
+	require.NotNil(t, err)
+	assert.Equal(t, "models/model.onnx is listed in models.json, but also exists in the application package", err.Error())
+}
+
+func writeModelManifest(t *testing.T, dir string, models ...Model) {
+	t.Helper()
+	data, err := json.Marshal(modelManifest{Models: models})
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(filepath.Join(dir, ModelManifestFile), data, 0644))
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
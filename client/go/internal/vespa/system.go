@@ -10,6 +10,13 @@ var PublicSystem = System{
 	ConsoleURL:     "https://console.vespa-cloud.com",
 	DefaultZone:    ZoneID{Environment: "dev", Region: "aws-us-east-1c"},
 	EndpointDomain: "vespa-app.cloud",
+	// The dev and perf zones of Vespa Cloud, as listed in its zone reference documentation. This list is maintained by
+	// hand, so it must be updated, together with TestPublicSystemZones, when zones are added or removed.
+	Zones: []ZoneID{
+		{Environment: "dev", Region: "aws-us-east-1c"},
+		{Environment: "dev", Region: "gcp-us-central1-f"},
+		{Environment: "perf", Region: "aws-us-east-1c"},
+	},
 }
 
 // PublicCDSystem represents the CD variant of the Vespa Cloud system.
@@ -54,6 +61,20 @@ type System struct {
 	AthenzDomain string
 	// EndpointDomain is the domain used for application endpoints in this system
 	EndpointDomain string
+	// Zones lists well-known zones for manual deployments to this system. This is used for shell completion and may
+	// be empty.
+	Zones []ZoneID
+}
+
+// KnownZones returns the zones known to exist in this system, starting with the default zone.
+func (s System) KnownZones() []ZoneID {
+	zones := []ZoneID{s.DefaultZone}
+	for _, z := range s.Zones {
+		if z != s.DefaultZone {
+			zones = append(zones, z)
+		}
+	}
+	return zones
 }
 
 // IsPublic returns whether system s is a public (Vespa Cloud) system.
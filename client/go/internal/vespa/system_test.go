@@ -0,0 +1,32 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespa
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPublicSystemZones pins the hand-maintained zones of Vespa Cloud. Change this together with PublicSystem.Zones
+// when zones are added to or removed from Vespa Cloud.
+func TestPublicSystemZones(t *testing.T) {
+	assert.Equal(t, []ZoneID{
+		{Environment: "dev", Region: "aws-us-east-1c"},
+		{Environment: "dev", Region: "gcp-us-central1-f"},
+		{Environment: "perf", Region: "aws-us-east-1c"},
+	}, PublicSystem.Zones)
+	for _, zone := range PublicSystem.Zones {
+		assert.Contains(t, []string{"dev", "perf"}, zone.Environment, "only zones for manual deployments are listed")
+	}
+}
+
+func TestKnownZones(t *testing.T) {
+	assert.Equal(t, PublicSystem.Zones, PublicSystem.KnownZones())
+	assert.Equal(t, []ZoneID{{Environment: "dev", Region: "aws-us-east-1c"}}, PublicCDSystem.KnownZones())
+
+	system := System{
+		DefaultZone: ZoneID{Environment: "dev", Region: "us-east-1"},
+		Zones:       []ZoneID{{Environment: "perf", Region: "us-east-3"}, {Environment: "dev", Region: "us-east-1"}},
+	}
+	assert.Equal(t, []ZoneID{{Environment: "dev", Region: "us-east-1"}, {Environment: "perf", Region: "us-east-3"}}, system.KnownZones())
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/vespa-engine/vespa/client/go/internal/curl"
 	"github.com/vespa-engine/vespa/client/go/internal/httputil"
 	"github.com/vespa-engine/vespa/client/go/internal/version"
+	"github.com/vespa-engine/vespa/client/go/internal/vespalog"
 )
 
 const (
@@ -32,6 +33,9 @@ const (
 	// A hosted Vespa target
 	TargetHosted = "hosted"
 
+	// A target for a Vespa service in Kubernetes
+	TargetK8s = "k8s"
+
 	// LatestDeployment waits for a deployment to converge to latest generation
 	LatestDeployment int64 = -1
 
@@ -149,6 +153,22 @@ type LogOptions struct {
 	Dequote bool
 	Writer  io.Writer
 	Level   int
+	Filter  vespalog.Filter // in addition to Level
+	Format  vespalog.Format
+}
+
+// formatter returns the formatter for log entries, showing times in UTC with microseconds, and hosts.
+func (o *LogOptions) formatter() vespalog.Formatter {
+	show := vespalog.DefaultShowFields()
+	show["usecs"] = true
+	show["host"] = true
+	return vespalog.Formatter{
+		Format:          o.Format,
+		Show:            show,
+		DequoteNewlines: o.Dequote,
+		Plain:           true,
+		Location:        time.UTC,
+	}
 }
 
 // Do sends request to this service. Authentication of the request happens automatically.
@@ -172,6 +192,22 @@ func (s *Service) Do(request *http.Request, timeout time.Duration) (*http.Respon
 	return resp, err
 }
 
+// AuthHeaders returns the headers which authenticate a request to this service, if any. Headers that sign the request,
+// instead of carrying a token, are only valid for a GET request to the base URL of this service.
+func (s *Service) AuthHeaders() (http.Header, error) {
+	if s.auth == nil {
+		return nil, nil
+	}
+	request, err := http.NewRequest("GET", s.BaseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.auth.Authenticate(request); err != nil {
+		return nil, fmt.Errorf("%w: %s", errAuth, err)
+	}
+	return request.Header, nil
+}
+
 // SetClient sets a custom HTTP client that this service should use.
 func (s *Service) SetClient(client httputil.Client) {
 	s.httpClient = client
@@ -280,11 +316,13 @@ func pollLogs(target Target, logsURL string, options LogOptions, retryInterval t
 		req.URL.RawQuery = q.Encode()
 		return req
 	}
+	formatter := options.formatter()
+	fmt.Fprint(options.Writer, formatter.Header())
 	logFunc := func(status int, response []byte) (bool, error) {
 		if ok, err := isOK(status); !ok {
 			return ok, err
 		}
-		logEntries, err := ReadLogEntries(bytes.NewReader(response))
+		logEntries, err := vespalog.ReadAll(bytes.NewReader(response))
 		if err != nil {
 			return false, err
 		}
@@ -292,10 +330,14 @@ func pollLogs(target Target, logsURL string, options LogOptions, retryInterval t
 			if !le.Time.After(lastFrom) {
 				continue
 			}
-			if LogLevel(le.Level) > options.Level {
+			if LogLevel(le.Level) > options.Level || !options.Filter.Match(le) {
 				continue
 			}
-			fmt.Fprintln(options.Writer, le.Format(options.Dequote))
+			rendered, err := formatter.Render(le)
+			if err != nil {
+				return false, err
+			}
+			fmt.Fprint(options.Writer, rendered)
 		}
 		if len(logEntries) > 0 {
 			lastFrom = logEntries[len(logEntries)-1].Time
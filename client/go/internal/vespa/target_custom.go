@@ -18,6 +18,7 @@ import (
 type customTarget struct {
 	targetType    string
 	baseURL       string
+	clusterURLs   map[string]string
 	httpClient    httputil.Client
 	tlsOptions    TLSOptions
 	retryInterval time.Duration
@@ -46,11 +47,14 @@ func LocalTarget(httpClient httputil.Client, tlsOptions TLSOptions, retryInterva
 	}
 }
 
-// CustomTarget creates a Target for a Vespa platform running at baseURL.
-func CustomTarget(httpClient httputil.Client, baseURL string, tlsOptions TLSOptions, retryInterval time.Duration) Target {
+// CustomTarget creates a Target for a Vespa platform running at baseURL. If clusterURLs is non-empty, it holds the URLs
+// of the container clusters of this target, keyed on cluster name. Otherwise baseURL is used for both deployment and
+// container requests.
+func CustomTarget(httpClient httputil.Client, baseURL string, clusterURLs map[string]string, tlsOptions TLSOptions, retryInterval time.Duration) Target {
 	return &customTarget{
 		targetType:    TargetCustom,
 		baseURL:       baseURL,
+		clusterURLs:   clusterURLs,
 		httpClient:    httpClient,
 		tlsOptions:    tlsOptions,
 		retryInterval: retryInterval,
@@ -131,7 +135,15 @@ func (t *customTarget) DeployService() (*Service, error) {
 
 func (t *customTarget) ContainerServices(timeout time.Duration) ([]*Service, error) {
 	if t.targetType == TargetCustom {
-		return []*Service{t.newService(t.baseURL, "", false)}, nil
+		if len(t.clusterURLs) == 0 {
+			return []*Service{t.newService(t.baseURL, "", false)}, nil
+		}
+		services := make([]*Service, 0, len(t.clusterURLs))
+		for cluster, url := range t.clusterURLs {
+			services = append(services, t.newService(url, cluster, false))
+		}
+		sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+		return services, nil
 	}
 	status, err := t.serviceStatus(AnyDeployment, timeout)
 	if err != nil {
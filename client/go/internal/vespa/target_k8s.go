@@ -0,0 +1,142 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vespa-engine/vespa/client/go/internal/httputil"
+	"github.com/vespa-engine/vespa/client/go/internal/k8s"
+)
+
+const (
+	// k8sReleaseLabel is the label identifying the resources of a release, e.g. installed by Helm
+	k8sReleaseLabel = "app.kubernetes.io/instance"
+
+	// k8sClusterLabel is the label holding the container cluster name of a service
+	k8sClusterLabel = "vespa.ai/cluster"
+
+	configServerPort = 19071
+	containerPort    = 8080
+)
+
+// K8sOptions holds the options for a Vespa installation in Kubernetes.
+type K8sOptions struct {
+	// Namespace is the namespace where Vespa is installed.
+	Namespace string
+	// Release is the release name of the installation, as given by the app.kubernetes.io/instance label.
+	Release string
+}
+
+type k8sTarget struct {
+	*customTarget
+	forwards []*k8s.PortForward
+}
+
+// K8sTarget creates a target for a Vespa installation in Kubernetes. Services of the installation are discovered
+// through the Kubernetes API, and requests to them are forwarded through local ports.
+//
+// The config server is the service exposing port 19071. Container clusters are services exposing port 8080, or a port
+// named http. The cluster name is given by the vespa.ai/cluster label, or the service name without the release prefix.
+func K8sTarget(httpClient httputil.Client, client *k8s.Client, options K8sOptions, tlsOptions TLSOptions, retryInterval time.Duration) (Target, error) {
+	services, err := client.Services(options.Namespace, k8sReleaseLabel+"="+options.Release)
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("no services found for release %s in namespace %s", options.Release, options.Namespace)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	target := &k8sTarget{
+		customTarget: &customTarget{
+			targetType:    TargetCustom,
+			clusterURLs:   make(map[string]string),
+			httpClient:    httpClient,
+			tlsOptions:    tlsOptions,
+			retryInterval: retryInterval,
+		},
+	}
+	for _, service := range services {
+		if port, ok := findPort(service, func(p k8s.ServicePort) bool { return p.Port == configServerPort }); ok {
+			if target.baseURL != "" {
+				continue
+			}
+			if target.baseURL, err = target.forward(client, options.Namespace, service, port); err != nil {
+				target.Close()
+				return nil, err
+			}
+		} else if port, ok := findPort(service, func(p k8s.ServicePort) bool { return p.Port == containerPort || p.Name == "http" }); ok {
+			cluster := service.Labels[k8sClusterLabel]
+			if cluster == "" {
+				cluster = strings.TrimPrefix(service.Name, options.Release+"-")
+			}
+			if target.clusterURLs[cluster], err = target.forward(client, options.Namespace, service, port); err != nil {
+				target.Close()
+				return nil, err
+			}
+		}
+	}
+	if target.baseURL == "" && len(target.clusterURLs) == 0 {
+		return nil, fmt.Errorf("no vespa services found for release %s in namespace %s", options.Release, options.Namespace)
+	}
+	return target, nil
+}
+
+func (t *k8sTarget) Type() string { return TargetK8s }
+
+func (t *k8sTarget) DeployService() (*Service, error) {
+	if t.baseURL == "" {
+		return nil, fmt.Errorf("no config server found: no service exposes port %d", configServerPort)
+	}
+	return t.customTarget.DeployService()
+}
+
+func (t *k8sTarget) ContainerServices(timeout time.Duration) ([]*Service, error) {
+	if len(t.clusterURLs) == 0 {
+		return nil, fmt.Errorf("no container clusters found: no service exposes port %d or a port named http", containerPort)
+	}
+	return t.customTarget.ContainerServices(timeout)
+}
+
+// Close stops forwarding ports to services of this target.
+func (t *k8sTarget) Close() error {
+	for _, f := range t.forwards {
+		f.Close()
+	}
+	return nil
+}
+
+// forward forwards a local port to port of a running pod backing service, and returns the local URL.
+func (t *k8sTarget) forward(client *k8s.Client, namespace string, service k8s.Service, port k8s.ServicePort) (string, error) {
+	pods, err := client.Pods(namespace, k8s.Selector(service.Selector))
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods {
+		if !pod.Running {
+			continue
+		}
+		podPort := port.TargetPort
+		if podPort == 0 { // Named target port, which requires reading the pod spec. Assume it equals the service port
+			podPort = port.Port
+		}
+		forward, err := client.PortForward(namespace, pod.Name, podPort)
+		if err != nil {
+			return "", err
+		}
+		t.forwards = append(t.forwards, forward)
+		return "http://" + forward.Addr(), nil
+	}
+	return "", fmt.Errorf("no running pods found for service %s in namespace %s", service.Name, namespace)
+}
+
+func findPort(service k8s.Service, matches func(port k8s.ServicePort) bool) (k8s.ServicePort, bool) {
+	for _, port := range service.Ports {
+		if matches(port) {
+			return port, true
+		}
+	}
+	return k8s.ServicePort{}, false
+}
@@ -5,12 +5,15 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/httputil"
+	"github.com/vespa-engine/vespa/client/go/internal/k8s"
 	"github.com/vespa-engine/vespa/client/go/internal/mock"
 	"github.com/vespa-engine/vespa/client/go/internal/version"
 )
@@ -68,17 +71,92 @@ func TestLocalTarget(t *testing.T) {
 
 func TestCustomTarget(t *testing.T) {
 	// Custom target always uses URL directly, without discovery
-	ct := CustomTarget(&mock.HTTPClient{}, "http://192.0.2.42", TLSOptions{}, 0)
+	ct := CustomTarget(&mock.HTTPClient{}, "http://192.0.2.42", nil, TLSOptions{}, 0)
 	assertServiceURL(t, "http://192.0.2.42", ct, "deploy")
 	assertServiceURL(t, "http://192.0.2.42", ct, "")
-	ct2 := CustomTarget(&mock.HTTPClient{}, "http://192.0.2.42:60000", TLSOptions{}, 0)
+	ct2 := CustomTarget(&mock.HTTPClient{}, "http://192.0.2.42:60000", nil, TLSOptions{}, 0)
 	assertServiceURL(t, "http://192.0.2.42:60000", ct2, "deploy")
 	assertServiceURL(t, "http://192.0.2.42:60000", ct2, "")
+
+	// Clusters with their own URLs
+	ct3 := CustomTarget(&mock.HTTPClient{}, "http://192.0.2.42:19071", map[string]string{
+		"feed":  "http://192.0.2.43:8080",
+		"query": "https://192.0.2.44",
+	}, TLSOptions{}, 0)
+	assertServiceURL(t, "http://192.0.2.42:19071", ct3, "deploy")
+	assertServiceURL(t, "http://192.0.2.43:8080", ct3, "feed")
+	assertServiceURL(t, "https://192.0.2.44", ct3, "query")
+	services, err := ct3.ContainerServices(0)
+	require.Nil(t, err)
+	_, err = FindService("", services)
+	assert.Equal(t, `no service specified: known services: feed, query`, err.Error())
+}
+
+func TestK8sTarget(t *testing.T) {
+	configServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("config server at " + r.URL.Path))
+	}))
+	defer configServer.Close()
+	query := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("query at " + r.URL.Path))
+	}))
+	defer query.Close()
+	api := mock.NewKubernetesAPI(t)
+	release := map[string]string{"app.kubernetes.io/instance": "my-vespa"}
+	api.AddService("vespa", "my-vespa-configserver", release, map[string]string{"app": "configserver"},
+		mock.KubernetesPort{Name: "http", Port: 19071})
+	api.AddService("vespa", "my-vespa-feed", release, map[string]string{"app": "feed"},
+		mock.KubernetesPort{Port: 8080})
+	api.AddService("vespa", "my-vespa-search", map[string]string{"app.kubernetes.io/instance": "my-vespa", "vespa.ai/cluster": "query"},
+		map[string]string{"app": "query"}, mock.KubernetesPort{Name: "http", Port: 80, TargetPort: 8080})
+	api.AddService("vespa", "my-vespa-content", release, map[string]string{"app": "content"},
+		mock.KubernetesPort{Port: 19107})
+	api.AddService("vespa", "other-query", map[string]string{"app.kubernetes.io/instance": "other"}, map[string]string{"app": "query"},
+		mock.KubernetesPort{Port: 8080})
+	api.AddPod("vespa", "configserver-0", map[string]string{"app": "configserver"}, true,
+		map[int]string{19071: strings.TrimPrefix(configServer.URL, "http://")})
+	api.AddPod("vespa", "feed-0", map[string]string{"app": "feed"}, true, nil)
+	api.AddPod("vespa", "query-0", map[string]string{"app": "query"}, false, nil)
+	api.AddPod("vespa", "query-1", map[string]string{"app": "query"}, true,
+		map[int]string{8080: strings.TrimPrefix(query.URL, "http://")})
+	config, err := k8s.LoadConfig(api.WriteKubeconfig(t, "test"), "")
+	require.Nil(t, err)
+	client := k8s.NewClient(config)
+
+	target, err := K8sTarget(httputil.NewClient(10*time.Second), client, K8sOptions{Namespace: "vespa", Release: "my-vespa"}, TLSOptions{}, 0)
+	require.Nil(t, err)
+	defer target.(*k8sTarget).Close()
+	assert.Equal(t, TargetK8s, target.Type())
+	services, err := target.ContainerServices(0)
+	require.Nil(t, err)
+	require.Len(t, services, 2)
+	assert.Equal(t, "feed", services[0].Name)
+	assert.Equal(t, "query", services[1].Name)
+
+	assertK8sResponse(t, "config server at /state/v1/version", target, "deploy", "/state/v1/version")
+	assertK8sResponse(t, "query at /search/", target, "query", "/search/")
+
+	_, err = K8sTarget(httputil.NewClient(10*time.Second), client, K8sOptions{Namespace: "vespa", Release: "missing"}, TLSOptions{}, 0)
+	assert.Equal(t, "no services found for release missing in namespace vespa", err.Error())
+}
+
+func assertK8sResponse(t *testing.T, expected string, target Target, serviceName, path string) {
+	t.Helper()
+	service, err := getService(t, target, serviceName)
+	require.Nil(t, err)
+	req, err := http.NewRequest("GET", service.BaseURL+path, nil)
+	require.Nil(t, err)
+	resp, err := service.Do(req, 10*time.Second)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, expected, string(body))
 }
 
 func TestCustomTargetWait(t *testing.T) {
 	client := &mock.HTTPClient{}
-	target := CustomTarget(client, "http://192.0.2.42", TLSOptions{}, 0)
+	target := CustomTarget(client, "http://192.0.2.42", nil, TLSOptions{}, 0)
 	// Fails once
 	client.NextStatus(500)
 	assertService(t, true, target, "", 0)
@@ -94,7 +172,7 @@ func TestCustomTargetWait(t *testing.T) {
 
 func TestCustomTargetAwaitDeployment(t *testing.T) {
 	client := &mock.HTTPClient{}
-	target := CustomTarget(client, "http://192.0.2.42", TLSOptions{}, 0)
+	target := CustomTarget(client, "http://192.0.2.42", nil, TLSOptions{}, 0)
 
 	// Not converged initially
 	_, err := target.AwaitDeployment(42, 0)
@@ -119,7 +197,7 @@ func TestCustomTargetAwaitDeployment(t *testing.T) {
 
 func TestCustomTargetCompatibleWith(t *testing.T) {
 	client := &mock.HTTPClient{}
-	target := CustomTarget(client, "http://192.0.2.42", TLSOptions{}, 0)
+	target := CustomTarget(client, "http://192.0.2.42", nil, TLSOptions{}, 0)
 	for range 3 {
 		client.NextResponse(mock.HTTPResponse{
 			URI:    "/state/v1/version",
@@ -298,6 +376,25 @@ func assertServiceURL(t *testing.T, url string, target Target, serviceName strin
 	assert.Equal(t, url, service.BaseURL)
 }
 
+type tokenAuthenticator struct{ token string }
+
+func (a *tokenAuthenticator) Authenticate(request *http.Request) error {
+	request.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+func TestServiceAuthHeaders(t *testing.T) {
+	service := &Service{BaseURL: "https://example.com"}
+	headers, err := service.AuthHeaders()
+	require.Nil(t, err)
+	assert.Nil(t, headers)
+
+	service.auth = &tokenAuthenticator{token: "secret"}
+	headers, err = service.AuthHeaders()
+	require.Nil(t, err)
+	assert.Equal(t, "Bearer secret", headers.Get("Authorization"))
+}
+
 func assertService(t *testing.T, fail bool, target Target, serviceName string, timeout time.Duration) {
 	t.Helper()
 	service, err := getService(t, target, serviceName)
@@ -0,0 +1,198 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespa
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+var (
+	// uploadAttempts is the number of times an upload to a cloud target is attempted, when failing transiently.
+	uploadAttempts = 3
+	// uploadRetryInterval is the time to wait before the first retry of an upload. The wait doubles for every retry.
+	uploadRetryInterval = 2 * time.Second
+)
+
+// ProgressFunc is called with the number of bytes sent so far, and the total number of bytes to send.
+type ProgressFunc func(sent, total int64)
+
+// uploadBody is a request body holding application package zips, which are read from disk as the body is sent. The
+// body can be sent multiple times, e.g. when retrying a failed upload.
+type uploadBody struct {
+	contentType string
+	parts       []bodyPart
+	size        int64
+
+	form *multipart.Writer
+	buf  bytes.Buffer
+}
+
+type bodyPart struct {
+	data []byte
+	file *os.File
+	size int64
+}
+
+// newZipBody returns a body consisting of the zip file f.
+func newZipBody(f *os.File) (*uploadBody, error) {
+	b := &uploadBody{contentType: "application/zip"}
+	if err := b.addFile(f); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// newMultipartBody returns an empty multipart form body. Parts are added with addField and addFormFile, and the body
+// must be completed with finish.
+func newMultipartBody() *uploadBody {
+	b := &uploadBody{}
+	b.form = multipart.NewWriter(&b.buf)
+	b.contentType = b.form.FormDataContentType()
+	return b
+}
+
+func (b *uploadBody) addField(name string, value []byte) error {
+	w, err := b.form.CreateFormField(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(value)
+	return err
+}
+
+func (b *uploadBody) addFormFile(name, filename string, f *os.File) error {
+	if _, err := b.form.CreateFormFile(name, filename); err != nil {
+		return err
+	}
+	return b.addFile(f)
+}
+
+func (b *uploadBody) finish() error {
+	if err := b.form.Close(); err != nil {
+		return err
+	}
+	b.flush()
+	return nil
+}
+
+func (b *uploadBody) addFile(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	b.flush()
+	b.parts = append(b.parts, bodyPart{file: f, size: info.Size()})
+	b.size += info.Size()
+	return nil
+}
+
+// flush moves data written by the multipart writer to a part of its own
+func (b *uploadBody) flush() {
+	if b.buf.Len() == 0 {
+		return
+	}
+	data := bytes.Clone(b.buf.Bytes())
+	b.buf.Reset()
+	b.parts = append(b.parts, bodyPart{data: data, size: int64(len(data))})
+	b.size += int64(len(data))
+}
+
+// reader returns a reader for the complete body, reporting the number of bytes read to progress, if non-nil.
+func (b *uploadBody) reader(progress ProgressFunc) io.ReadCloser {
+	readers := make([]io.Reader, 0, len(b.parts))
+	for _, part := range b.parts {
+		if part.file != nil {
+			readers = append(readers, io.NewSectionReader(part.file, 0, part.size))
+		} else {
+			readers = append(readers, bytes.NewReader(part.data))
+		}
+	}
+	return &progressReader{r: io.MultiReader(readers...), total: b.size, progress: progress}
+}
+
+// newRequest returns a request posting this body to u.
+func (b *uploadBody) newRequest(u *url.URL, progress ProgressFunc) *http.Request {
+	req := &http.Request{
+		URL:           u,
+		Method:        "POST",
+		Header:        make(http.Header),
+		Body:          b.reader(progress),
+		ContentLength: b.size,
+		GetBody:       func() (io.ReadCloser, error) { return b.reader(nil), nil },
+	}
+	req.Header.Set("Content-Type", b.contentType)
+	return req
+}
+
+// Close closes the files of this body.
+func (b *uploadBody) Close() error {
+	var err error
+	for _, part := range b.parts {
+		if part.file != nil {
+			err = errors.Join(err, part.file.Close())
+		}
+	}
+	return err
+}
+
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.sent += int64(n)
+	if r.progress != nil && n > 0 {
+		r.progress(r.sent, r.total)
+	}
+	return n, err
+}
+
+func (r *progressReader) Close() error { return nil }
+
+// upload sends body to url. If retry is true, uploads to a cloud target are retried when failing transiently, reading
+// the body from disk again.
+func upload(url *url.URL, body *uploadBody, opts DeploymentOptions, timeout time.Duration, retry bool) (*http.Request, *http.Response, error) {
+	service, err := opts.Target.DeployService()
+	if err != nil {
+		return nil, nil, err
+	}
+	attempts := 1
+	if retry && opts.Target.IsCloud() {
+		attempts = uploadAttempts
+	}
+	wait := uploadRetryInterval
+	for attempt := 1; ; attempt++ {
+		request := body.newRequest(url, opts.Progress)
+		response, err := service.Do(request, timeout)
+		if attempt >= attempts || !isTransientFailure(response, err) {
+			return request, response, err
+		}
+		if response != nil {
+			response.Body.Close()
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// isTransientFailure returns whether a request which got response and err may succeed if retried. Only failures where
+// the request cannot have been processed are considered transient: failing to connect, and service unavailable.
+func isTransientFailure(response *http.Response, err error) bool {
+	if err != nil {
+		var opErr *net.OpError
+		return errors.Is(err, syscall.ECONNREFUSED) || (errors.As(err, &opErr) && opErr.Op == "dial")
+	}
+	return response.StatusCode == http.StatusServiceUnavailable
+}
@@ -0,0 +1,139 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespa
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vespa-engine/vespa/client/go/internal/mock"
+)
+
+func TestUploadBody(t *testing.T) {
+	zipFile := filepath.Join(t.TempDir(), "app.zip")
+	require.Nil(t, os.WriteFile(zipFile, []byte("zip contents"), 0644))
+	f, err := os.Open(zipFile)
+	require.Nil(t, err)
+
+	body := newMultipartBody()
+	require.Nil(t, body.addField("submitOptions", []byte("{}")))
+	require.Nil(t, body.addFormFile("applicationZip", "application.zip", f))
+	require.Nil(t, body.addField("deployOptions", []byte(`{"vespaVersion":"1.2.3"}`)))
+	require.Nil(t, body.finish())
+
+	var sent []int64
+	data, err := io.ReadAll(body.reader(func(n, total int64) {
+		assert.Equal(t, body.size, total)
+		sent = append(sent, n)
+	}))
+	require.Nil(t, err)
+	assert.Equal(t, int64(len(data)), body.size)
+	assert.Equal(t, body.size, sent[len(sent)-1])
+
+	// Body can be read again
+	data2, err := io.ReadAll(body.reader(nil))
+	require.Nil(t, err)
+	assert.Equal(t, data, data2)
+
+	_, params, err := mime.ParseMediaType(body.contentType)
+	require.Nil(t, err)
+	r := multipart.NewReader(body.reader(nil), params["boundary"])
+	parts := make(map[string]string)
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		value, err := io.ReadAll(p)
+		require.Nil(t, err)
+		parts[p.FormName()] = string(value)
+	}
+	assert.Equal(t, map[string]string{
+		"submitOptions":  "{}",
+		"applicationZip": "zip contents",
+		"deployOptions":  `{"vespaVersion":"1.2.3"}`,
+	}, parts)
+
+	require.Nil(t, body.Close())
+	_, err = f.Stat()
+	assert.NotNil(t, err)
+}
+
+func TestUploadRetry(t *testing.T) {
+	interval := uploadRetryInterval
+	uploadRetryInterval = 0
+	defer func() { uploadRetryInterval = interval }()
+
+	httpClient := mock.HTTPClient{ReadBody: true}
+	target, _ := createCloudTarget(t, io.Discard)
+	target.(*cloudTarget).httpClient = &httpClient
+	appDir, _ := mock.ApplicationPackageDir(t, false, true)
+	opts := DeploymentOptions{Target: target, ApplicationPackage: ApplicationPackage{Path: appDir}}
+
+	// Transient failures are retried with the same body
+	httpClient.NextResponseError(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})
+	httpClient.NextResponseString(503, "unavailable")
+	httpClient.NextResponseString(200, `{"run": 42}`)
+	var sent, total int64
+	opts.Progress = func(n, size int64) { sent, total = n, size }
+	result, err := Deploy(opts)
+	require.Nil(t, err)
+	assert.Equal(t, int64(42), result.ID)
+	require.Equal(t, 2, len(httpClient.Requests)) // Requests failing with an error are not recorded
+	first, err := io.ReadAll(httpClient.Requests[0].Body)
+	require.Nil(t, err)
+	second, err := io.ReadAll(httpClient.Requests[1].Body)
+	require.Nil(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, total, sent)
+	assert.Equal(t, int64(len(second)), total)
+
+	// Retries are exhausted
+	httpClient.Requests = nil
+	for i := 0; i < uploadAttempts; i++ {
+		httpClient.NextResponseString(503, "unavailable")
+	}
+	_, err = Deploy(opts)
+	require.NotNil(t, err)
+	assert.Equal(t, uploadAttempts, len(httpClient.Requests))
+
+	// Other failures are not retried, as the request may have been processed
+	for _, status := range []int{400, 502, 504} {
+		httpClient.Requests = nil
+		httpClient.NextResponseString(status, `{"error-code":"INVALID_APPLICATION_PACKAGE","message":"bad"}`)
+		_, err = Deploy(opts)
+		require.NotNil(t, err)
+		assert.Equal(t, 1, len(httpClient.Requests))
+	}
+	httpClient.NextResponseError(errors.New("connection reset by peer"))
+	httpClient.NextResponseString(200, `{"run": 42}`)
+	_, err = Deploy(opts)
+	require.NotNil(t, err)
+	assert.False(t, httpClient.Consumed())
+
+	// Submissions are not retried
+	httpClient = mock.HTTPClient{ReadBody: true}
+	target.(*cloudTarget).httpClient = &httpClient
+	httpClient.NextResponseString(503, "unavailable")
+	httpClient.NextResponseString(200, `{"build": 42}`)
+	_, err = Submit(opts, Submission{})
+	require.NotNil(t, err)
+	assert.Equal(t, 1, len(httpClient.Requests))
+
+	// Uploads to other targets are not retried
+	localClient := mock.HTTPClient{}
+	localClient.NextResponseString(503, "unavailable")
+	localOpts := DeploymentOptions{Target: LocalTarget(&localClient, TLSOptions{}, 0), ApplicationPackage: ApplicationPackage{Path: appDir}}
+	_, err = Deploy(localOpts)
+	require.NotNil(t, err)
+	assert.Equal(t, 1, len(localClient.Requests))
+}
@@ -75,18 +75,37 @@ type Services struct {
 }
 
 type Container struct {
-	Root  xml.Name `xml:"container"`
-	ID    string   `xml:"id,attr"`
-	Nodes Nodes    `xml:"nodes"`
+	Root        xml.Name    `xml:"container"`
+	ID          string      `xml:"id,attr"`
+	Search      *struct{}   `xml:"search"`
+	DocumentAPI *struct{}   `xml:"document-api"`
+	Components  []Component `xml:"component"`
+	Nodes       Nodes       `xml:"nodes"`
+}
+
+type Component struct {
+	ID     string `xml:"id,attr"`
+	Class  string `xml:"class,attr,omitempty"`
+	Bundle string `xml:"bundle,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
 }
 
 type Content struct {
-	ID    string `xml:"id,attr"`
-	Nodes Nodes  `xml:"nodes"`
+	ID            string     `xml:"id,attr"`
+	Redundancy    string     `xml:"redundancy"`
+	MinRedundancy string     `xml:"min-redundancy"`
+	Documents     []Document `xml:"documents>document"`
+	Nodes         Nodes      `xml:"nodes"`
+}
+
+type Document struct {
+	Type string `xml:"type,attr"`
+	Mode string `xml:"mode,attr"`
 }
 
 type Nodes struct {
 	Count     string     `xml:"count,attr"`
+	Groups    string     `xml:"groups,attr,omitempty"`
 	Resources *Resources `xml:"resources,omitempty"`
 }
 
@@ -357,6 +376,23 @@ func Replace(r io.Reader, parentName, name string, data interface{}) (string, er
 	return sb.String(), nil
 }
 
+// Format returns rawXML formatted as the output of Replace, i.e. with any whitespace between elements replaced by
+// indentation, and empty elements written with an end tag.
+func Format(rawXML string) (string, error) {
+	return Replace(strings.NewReader(rawXML), "", "", nil) // No element has an empty name, so nothing is replaced
+}
+
+// Equal returns whether XML documents a and b are equal, ignoring differences in formatting. Documents which cannot be
+// parsed are never equal.
+func Equal(a, b string) bool {
+	formattedA, err := Format(a)
+	if err != nil {
+		return false
+	}
+	formattedB, err := Format(b)
+	return err == nil && formattedA == formattedB
+}
+
 func joinNamespace(token xml.Token) xml.Token {
 	// Hack to work around the broken namespace support in Go
 	// https://github.com/golang/go/issues/13400
@@ -149,6 +149,25 @@ func TestReplaceRemovesElement(t *testing.T) {
 	assertReplace(t, in, out, "prod", "test", nil)
 }
 
+func TestEqual(t *testing.T) {
+	a := `<services version="1.0">
+    <container id="qrs" version="1.0">
+        <search/>
+    </container>
+</services>
+`
+	b := `<services version="1.0"><container id="qrs" version="1.0"><search></search></container></services>`
+	if !Equal(a, b) {
+		t.Errorf("want %q and %q to be equal", a, b)
+	}
+	if c := strings.ReplaceAll(a, "qrs", "default"); Equal(a, c) {
+		t.Errorf("want %q and %q to differ", a, c)
+	}
+	if Equal(a, "<services>") {
+		t.Errorf("want invalid XML to differ")
+	}
+}
+
 func TestReplaceRaw(t *testing.T) {
 	in := `
 <project xmlns="http://maven.apache.org/POM/4.0.0"
@@ -0,0 +1,353 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is a compact description of the clusters and production regions of an application, from which services.xml
+// and deployment.xml can be generated.
+type Spec struct {
+	Containers []ContainerSpec `yaml:"containers,omitempty"`
+	Content    []ContentSpec   `yaml:"content,omitempty"`
+	Regions    []string        `yaml:"regions,omitempty"`
+}
+
+// ContainerSpec describes a container cluster.
+type ContainerSpec struct {
+	ID          string          `yaml:"id"`
+	Search      bool            `yaml:"search,omitempty"`
+	DocumentAPI bool            `yaml:"document-api,omitempty"`
+	Components  []ComponentSpec `yaml:"components,omitempty"`
+	Nodes       string          `yaml:"nodes,omitempty"`
+	Resources   string          `yaml:"resources,omitempty"`
+}
+
+// ComponentSpec describes a component in a container cluster.
+type ComponentSpec struct {
+	ID     string `yaml:"id"`
+	Class  string `yaml:"class,omitempty"`
+	Bundle string `yaml:"bundle,omitempty"`
+	Type   string `yaml:"type,omitempty"`
+}
+
+// ContentSpec describes a content cluster.
+type ContentSpec struct {
+	ID         string         `yaml:"id"`
+	Redundancy int            `yaml:"redundancy,omitempty"`
+	Documents  []DocumentSpec `yaml:"documents"`
+	Nodes      string         `yaml:"nodes,omitempty"`
+	Groups     int            `yaml:"groups,omitempty"`
+	Resources  string         `yaml:"resources,omitempty"`
+}
+
+// DocumentSpec describes a document type stored in a content cluster. A document type using the default mode, index,
+// is written as its name only.
+type DocumentSpec struct {
+	Type string `yaml:"type"`
+	Mode string `yaml:"mode,omitempty"`
+}
+
+const defaultDocumentMode = "index"
+
+func (d *DocumentSpec) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		d.Type = value.Value
+		return nil
+	}
+	type plain DocumentSpec
+	return value.Decode((*plain)(d))
+}
+
+func (d DocumentSpec) MarshalYAML() (interface{}, error) {
+	if d.Mode == "" || d.Mode == defaultDocumentMode {
+		return d.Type, nil
+	}
+	type plain DocumentSpec
+	return plain(d), nil
+}
+
+// ReadSpec reads and validates a spec from reader r.
+func ReadSpec(r io.Reader) (Spec, error) {
+	var spec Spec
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Spec{}, fmt.Errorf("spec is empty")
+		}
+		return Spec{}, err
+	}
+	if err := spec.Validate(); err != nil {
+		return Spec{}, err
+	}
+	return spec, nil
+}
+
+// WriteSpec writes spec s to writer w.
+func WriteSpec(w io.Writer, s Spec) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Validate returns an error if this spec does not describe a valid application.
+func (s Spec) Validate() error {
+	if len(s.Containers) == 0 && len(s.Content) == 0 {
+		return fmt.Errorf("spec must declare at least one container or content cluster")
+	}
+	ids := make(map[string]bool)
+	checkID := func(kind, id string) error {
+		if id == "" {
+			return fmt.Errorf("%s cluster is missing id", kind)
+		}
+		if ids[id] {
+			return fmt.Errorf("duplicate cluster id: %s", id)
+		}
+		ids[id] = true
+		return nil
+	}
+	for _, c := range s.Containers {
+		if err := checkID("container", c.ID); err != nil {
+			return err
+		}
+		for _, component := range c.Components {
+			if component.ID == "" {
+				return fmt.Errorf("container cluster %s: component is missing id", c.ID)
+			}
+		}
+		if err := validateNodes(c.ID, c.Nodes, 0, c.Resources); err != nil {
+			return err
+		}
+	}
+	for _, c := range s.Content {
+		if err := checkID("content", c.ID); err != nil {
+			return err
+		}
+		if c.Redundancy < 0 {
+			return fmt.Errorf("content cluster %s: invalid redundancy: %d", c.ID, c.Redundancy)
+		}
+		if len(c.Documents) == 0 {
+			return fmt.Errorf("content cluster %s: at least one document type is required", c.ID)
+		}
+		for _, d := range c.Documents {
+			if d.Type == "" {
+				return fmt.Errorf("content cluster %s: document is missing type", c.ID)
+			}
+			switch d.Mode {
+			case "", "index", "streaming", "store-only":
+			default:
+				return fmt.Errorf("content cluster %s: invalid mode for document type %s: %q", c.ID, d.Type, d.Mode)
+			}
+		}
+		if err := validateNodes(c.ID, c.Nodes, c.Groups, c.Resources); err != nil {
+			return err
+		}
+	}
+	for _, r := range s.Regions {
+		if r == "" {
+			return fmt.Errorf("region name cannot be empty")
+		}
+	}
+	return nil
+}
+
+func validateNodes(clusterID, count string, groups int, resources string) error {
+	if count == "" {
+		if groups != 0 || resources != "" {
+			return fmt.Errorf("cluster %s: nodes must be set when specifying groups or resources", clusterID)
+		}
+		return nil
+	}
+	min, max, err := ParseNodeCount(count)
+	if err != nil {
+		return fmt.Errorf("cluster %s: %w", clusterID, err)
+	}
+	if groups < 0 {
+		return fmt.Errorf("cluster %s: invalid groups: %d", clusterID, groups)
+	}
+	if groups > 0 && min == max && min%groups != 0 {
+		return fmt.Errorf("cluster %s: node count %d is not divisible by %d groups", clusterID, min, groups)
+	}
+	if resources != "" {
+		if _, err := ParseResources(resources); err != nil {
+			return fmt.Errorf("cluster %s: %w", clusterID, err)
+		}
+	}
+	return nil
+}
+
+type servicesElement struct {
+	XMLName    xml.Name           `xml:"services"`
+	Version    string             `xml:"version,attr"`
+	Containers []containerElement `xml:"container"`
+	Content    []contentElement   `xml:"content"`
+}
+
+type containerElement struct {
+	ID          string      `xml:"id,attr"`
+	Version     string      `xml:"version,attr"`
+	DocumentAPI *struct{}   `xml:"document-api"`
+	Search      *struct{}   `xml:"search"`
+	Components  []Component `xml:"component"`
+	Nodes       *Nodes      `xml:"nodes"`
+}
+
+type contentElement struct {
+	ID            string     `xml:"id,attr"`
+	Version       string     `xml:"version,attr"`
+	MinRedundancy string     `xml:"min-redundancy,omitempty"`
+	Documents     []Document `xml:"documents>document"`
+	Nodes         *Nodes     `xml:"nodes"`
+}
+
+type deploymentElement struct {
+	XMLName xml.Name `xml:"deployment"`
+	Version string   `xml:"version,attr"`
+	Prod    Prod     `xml:"prod"`
+}
+
+// Services generates services.xml from this spec.
+func (s Spec) Services() (Services, error) {
+	if err := s.Validate(); err != nil {
+		return Services{}, err
+	}
+	services := servicesElement{Version: "1.0"}
+	for _, c := range s.Containers {
+		element := containerElement{ID: c.ID, Version: "1.0", Nodes: specNodes(c.Nodes, 0, c.Resources)}
+		if c.DocumentAPI {
+			element.DocumentAPI = &struct{}{}
+		}
+		if c.Search {
+			element.Search = &struct{}{}
+		}
+		for _, component := range c.Components {
+			element.Components = append(element.Components, Component(component))
+		}
+		services.Containers = append(services.Containers, element)
+	}
+	for _, c := range s.Content {
+		element := contentElement{ID: c.ID, Version: "1.0", Nodes: specNodes(c.Nodes, c.Groups, c.Resources)}
+		if c.Redundancy > 0 {
+			element.MinRedundancy = strconv.Itoa(c.Redundancy)
+		}
+		for _, d := range c.Documents {
+			mode := d.Mode
+			if mode == "" {
+				mode = defaultDocumentMode
+			}
+			element.Documents = append(element.Documents, Document{Type: d.Type, Mode: mode})
+		}
+		services.Content = append(services.Content, element)
+	}
+	data, err := marshalIndent(services)
+	if err != nil {
+		return Services{}, err
+	}
+	return ReadServices(bytes.NewReader(data))
+}
+
+// Deployment generates deployment.xml from this spec. At least one region must be specified.
+func (s Spec) Deployment() (Deployment, error) {
+	if err := s.Validate(); err != nil {
+		return Deployment{}, err
+	}
+	if len(s.Regions) == 0 {
+		return Deployment{}, fmt.Errorf("spec must declare at least one region")
+	}
+	data, err := marshalIndent(deploymentElement{Version: "1.0", Prod: Prod{Regions: Regions(s.Regions...)}})
+	if err != nil {
+		return Deployment{}, err
+	}
+	return ReadDeployment(bytes.NewReader(data))
+}
+
+func specNodes(count string, groups int, resources string) *Nodes {
+	if count == "" {
+		return nil
+	}
+	nodes := &Nodes{Count: count}
+	if groups > 0 {
+		nodes.Groups = strconv.Itoa(groups)
+	}
+	if resources != "" {
+		r, _ := ParseResources(resources) // Already validated
+		nodes.Resources = &r
+	}
+	return nodes
+}
+
+func marshalIndent(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.Write(data)
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// Describe converts services and deployment to a spec. Only the parts of services.xml and deployment.xml which can be
+// expressed in a spec are included. Regions are read from the prod element and the prod elements of all instances.
+func Describe(services Services, deployment Deployment) Spec {
+	var spec Spec
+	for _, c := range services.Container {
+		container := ContainerSpec{
+			ID:          c.ID,
+			Search:      c.Search != nil,
+			DocumentAPI: c.DocumentAPI != nil,
+			Nodes:       c.Nodes.Count,
+		}
+		if c.Nodes.Resources != nil {
+			container.Resources = c.Nodes.Resources.String()
+		}
+		for _, component := range c.Components {
+			container.Components = append(container.Components, ComponentSpec(component))
+		}
+		spec.Containers = append(spec.Containers, container)
+	}
+	for _, c := range services.Content {
+		content := ContentSpec{ID: c.ID, Nodes: c.Nodes.Count}
+		redundancy := c.MinRedundancy
+		if redundancy == "" {
+			redundancy = c.Redundancy
+		}
+		content.Redundancy, _ = strconv.Atoi(strings.TrimSpace(redundancy))
+		content.Groups, _ = strconv.Atoi(c.Nodes.Groups)
+		if c.Nodes.Resources != nil {
+			content.Resources = c.Nodes.Resources.String()
+		}
+		for _, d := range c.Documents {
+			content.Documents = append(content.Documents, DocumentSpec(d))
+		}
+		spec.Content = append(spec.Content, content)
+	}
+	seen := make(map[string]bool)
+	addRegions := func(regions []Region) {
+		for _, r := range regions {
+			name := strings.TrimSpace(r.Name)
+			if !seen[name] {
+				seen[name] = true
+				spec.Regions = append(spec.Regions, name)
+			}
+		}
+	}
+	addRegions(deployment.Prod.Regions)
+	for _, instance := range deployment.Instance {
+		addRegions(instance.Prod.Regions)
+	}
+	return spec
+}
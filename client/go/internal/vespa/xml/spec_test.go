@@ -0,0 +1,174 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package xml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSpec = `containers:
+  - id: default
+    search: true
+    document-api: true
+    components:
+      - id: e5
+        type: hugging-face-embedder
+      - id: com.example.Greeter
+        bundle: greeter
+    nodes: "2"
+    resources: vcpu=4,memory=16Gb,disk=125Gb
+content:
+  - id: music
+    redundancy: 2
+    documents:
+      - music
+      - type: lyrics
+        mode: streaming
+    nodes: "4"
+    groups: 2
+    resources: vcpu=8,memory=32Gb,disk=300Gb
+regions:
+  - aws-us-east-1c
+  - aws-eu-west-1a
+`
+
+func TestGenerateFromSpec(t *testing.T) {
+	spec, err := ReadSpec(strings.NewReader(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	services, err := spec.Services()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantServices := `<?xml version="1.0" encoding="UTF-8"?>
+<services version="1.0">
+  <container id="default" version="1.0">
+    <document-api></document-api>
+    <search></search>
+    <component id="e5" type="hugging-face-embedder"></component>
+    <component id="com.example.Greeter" bundle="greeter"></component>
+    <nodes count="2">
+      <resources vcpu="4" memory="16Gb" disk="125Gb"></resources>
+    </nodes>
+  </container>
+  <content id="music" version="1.0">
+    <min-redundancy>2</min-redundancy>
+    <documents>
+      <document type="music" mode="index"></document>
+      <document type="lyrics" mode="streaming"></document>
+    </documents>
+    <nodes count="4" groups="2">
+      <resources vcpu="8" memory="32Gb" disk="300Gb"></resources>
+    </nodes>
+  </content>
+</services>
+`
+	if got := services.String(); got != wantServices {
+		t.Errorf("got:\n%s\nwant:\n%s\n", got, wantServices)
+	}
+	deployment, err := spec.Deployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDeployment := `<?xml version="1.0" encoding="UTF-8"?>
+<deployment version="1.0">
+  <prod>
+    <region>aws-us-east-1c</region>
+    <region>aws-eu-west-1a</region>
+  </prod>
+</deployment>
+`
+	if got := deployment.String(); got != wantDeployment {
+		t.Errorf("got:\n%s\nwant:\n%s\n", got, wantDeployment)
+	}
+
+	// Describing the generated files gives the original spec
+	var sb strings.Builder
+	if err := WriteSpec(&sb, Describe(services, deployment)); err != nil {
+		t.Fatal(err)
+	}
+	if got := sb.String(); got != testSpec {
+		t.Errorf("got:\n%s\nwant:\n%s\n", got, testSpec)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	services, err := ReadServices(strings.NewReader(`
+<services xmlns:deploy="vespa" xmlns:preprocess="properties">
+  <container id="qrs">
+    <search/>
+    <nodes count="[2, 4]"/>
+  </container>
+  <content id="music">
+    <redundancy>2</redundancy>
+    <documents>
+      <document type="music"/>
+    </documents>
+  </content>
+</services>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment, err := ReadDeployment(strings.NewReader(`
+<deployment version="1.0">
+  <instance id="default">
+    <prod>
+      <region>aws-us-east-1c</region>
+    </prod>
+  </instance>
+  <instance id="beta">
+    <prod>
+      <region>aws-us-east-1c</region>
+      <region>aws-eu-west-1a</region>
+    </prod>
+  </instance>
+</deployment>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Spec{
+		Containers: []ContainerSpec{{ID: "qrs", Search: true, Nodes: "[2, 4]"}},
+		Content:    []ContentSpec{{ID: "music", Redundancy: 2, Documents: []DocumentSpec{{Type: "music"}}}},
+		Regions:    []string{"aws-us-east-1c", "aws-eu-west-1a"},
+	}
+	if got := Describe(services, deployment); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestInvalidSpec(t *testing.T) {
+	assertInvalidSpec(t, "", "spec is empty")
+	assertInvalidSpec(t, "regions: [aws-us-east-1c]", "spec must declare at least one container or content cluster")
+	assertInvalidSpec(t, "containers: [{id: foo, nodez: 2}]", "yaml: unmarshal errors:\n  line 1: field nodez not found in type xml.ContainerSpec")
+	assertInvalidSpec(t, "containers: [{search: true}]", "container cluster is missing id")
+	assertInvalidSpec(t, "containers: [{id: foo}]\ncontent: [{id: foo, documents: [foo]}]", "duplicate cluster id: foo")
+	assertInvalidSpec(t, "containers: [{id: foo, components: [{class: Foo}]}]", "container cluster foo: component is missing id")
+	assertInvalidSpec(t, "containers: [{id: foo, nodes: two}]", `cluster foo: invalid node count: "two"`)
+	assertInvalidSpec(t, "containers: [{id: foo, resources: vcpu=2}]", "cluster foo: nodes must be set when specifying groups or resources")
+	assertInvalidSpec(t, "containers: [{id: foo, nodes: 2, resources: vcpu=2}]", `cluster foo: invalid resources: "vcpu=2"`)
+	assertInvalidSpec(t, "content: [{id: foo}]", "content cluster foo: at least one document type is required")
+	assertInvalidSpec(t, "content: [{id: foo, documents: [{type: foo, mode: bar}]}]", `content cluster foo: invalid mode for document type foo: "bar"`)
+	assertInvalidSpec(t, "content: [{id: foo, documents: [foo], nodes: 3, groups: 2}]", "cluster foo: node count 3 is not divisible by 2 groups")
+
+	spec, err := ReadSpec(strings.NewReader("containers: [{id: foo}]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := spec.Deployment(); err == nil || err.Error() != "spec must declare at least one region" {
+		t.Errorf("got %v, want error about missing region", err)
+	}
+}
+
+func assertInvalidSpec(t *testing.T, spec, wantErr string) {
+	t.Helper()
+	_, err := ReadSpec(strings.NewReader(spec))
+	if err == nil {
+		t.Errorf("want error for spec %q", spec)
+		return
+	}
+	if err.Error() != wantErr {
+		t.Errorf("got error %q, want %q", err.Error(), wantErr)
+	}
+}
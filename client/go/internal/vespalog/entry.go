@@ -0,0 +1,184 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+// Package vespalog parses, filters and formats messages in the vespa.log format,
+// for both vespa-logfmt and the logs shown by the Vespa CLI.
+package vespalog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is one message in vespa.log format: tab-separated timestamp, host, pid,
+// service, component, level and message.
+type Entry struct {
+	Timestamp string // seconds, optional fractional seconds, as written
+	Time      time.Time
+	Host      string
+	Pid       string // pid, optional tid
+	Service   string
+	Component string
+	Level     string
+	Message   string // escaped, as written
+	Line      string // the complete line
+}
+
+// ParseError is returned for lines that are not in vespa.log format.
+type ParseError struct {
+	Line   string
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %q", e.Reason, e.Line)
+}
+
+// Parse parses a line in vespa.log format.
+func Parse(line string) (*Entry, error) {
+	return parse(line, false)
+}
+
+// ParseLenient parses a line like Parse, but also accepts lines whose timestamp
+// cannot be parsed. The Time of such entries is zero, so filters do not select
+// them by time.
+func ParseLenient(line string) (*Entry, error) {
+	return parse(line, true)
+}
+
+func parse(line string, lenient bool) (*Entry, error) {
+	parts := strings.SplitN(line, "\t", 7)
+	if len(parts) != 7 {
+		return nil, &ParseError{Line: line, Reason: fmt.Sprintf("invalid number of log parts: %d", len(parts))}
+	}
+	t, err := ParseTimestamp(parts[0])
+	if err != nil && !lenient {
+		return nil, &ParseError{Line: line, Reason: err.Error()}
+	}
+	return &Entry{
+		Timestamp: parts[0],
+		Time:      t,
+		Host:      parts[1],
+		Pid:       parts[2],
+		Service:   parts[3],
+		Component: parts[4],
+		Level:     parts[5],
+		Message:   parts[6],
+		Line:      line,
+	}, nil
+}
+
+// ParseTimestamp parses a vespa.log timestamp, "seconds.fraction", without going
+// through float64, so microsecond timestamps keep their exact value.
+func ParseTimestamp(timestamp string) (time.Time, error) {
+	secs, frac, _ := strings.Cut(timestamp, ".")
+	s, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp seconds: %s", timestamp)
+	}
+	var nanos int64
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		if nanos, err = strconv.ParseInt(frac, 10, 64); err != nil || nanos < 0 || frac[0] == '+' {
+			return time.Time{}, fmt.Errorf("invalid timestamp fraction: %s", timestamp)
+		}
+		for i := len(frac); i < 9; i++ {
+			nanos *= 10
+		}
+	}
+	return time.Unix(s, nanos), nil
+}
+
+// PidAndTid returns the process and (if present) thread id of the entry.
+func (e *Entry) PidAndTid() (pid, tid string) {
+	pid, tid, _ = strings.Cut(e.Pid, "/")
+	return
+}
+
+// UnquotedMessage returns the message with the escaping done when writing vespa.log undone.
+func (e *Entry) UnquotedMessage() string {
+	return unquoteMessage(e.Message)
+}
+
+func unquoteMessage(msg string) string {
+	if !strings.Contains(msg, "\\") {
+		return msg
+	}
+	var buf strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c != '\\' || i+1 == len(msg) {
+			buf.WriteByte(c)
+			continue
+		}
+		i++
+		switch msg[i] {
+		case 'n':
+			buf.WriteByte('\n')
+		case 't':
+			buf.WriteByte('\t')
+		case 'r':
+			buf.WriteByte('\r')
+		case '\\':
+			buf.WriteByte('\\')
+		case 'x':
+			if i+2 < len(msg) {
+				if b, err := strconv.ParseUint(msg[i+1:i+3], 16, 8); err == nil {
+					buf.WriteByte(byte(b))
+					i += 2
+					continue
+				}
+			}
+			fallthrough
+		default:
+			buf.WriteByte('\\')
+			buf.WriteByte(msg[i])
+		}
+	}
+	return buf.String()
+}
+
+// Scanner reads entries from a stream, one line at a time.
+type Scanner struct {
+	scanner *bufio.Scanner
+}
+
+// NewScanner returns a scanner reading from r.
+func NewScanner(r io.Reader) *Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	return &Scanner{scanner: scanner}
+}
+
+// Next returns the next entry. At the end of input the error is io.EOF;
+// for lines that cannot be parsed it is a *ParseError, and reading may continue.
+func (s *Scanner) Next() (*Entry, error) {
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return Parse(s.scanner.Text())
+}
+
+// ReadAll reads and parses all entries from r, failing on the first line that cannot be parsed.
+func ReadAll(r io.Reader) ([]*Entry, error) {
+	var entries []*Entry
+	scanner := NewScanner(r)
+	for {
+		entry, err := scanner.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
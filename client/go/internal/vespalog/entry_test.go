@@ -0,0 +1,91 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespalog
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	in := "1632738690.905535	host1a.dev.aws-us-east-1c	806/53	logserver-container	Container.com.yahoo.container.jdisc.ConfiguredApplication	info	Switching to the latest deployed set of configurations and components. Application config generation: 52532"
+	entry, err := Parse(in)
+	require.Nil(t, err)
+	assert.Equal(t, &Entry{
+		Timestamp: "1632738690.905535",
+		Time:      time.Date(2021, 9, 27, 10, 31, 30, 905535000, time.UTC).Local(),
+		Host:      "host1a.dev.aws-us-east-1c",
+		Pid:       "806/53",
+		Service:   "logserver-container",
+		Component: "Container.com.yahoo.container.jdisc.ConfiguredApplication",
+		Level:     "info",
+		Message:   "Switching to the latest deployed set of configurations and components. Application config generation: 52532",
+		Line:      in,
+	}, entry)
+	pid, tid := entry.PidAndTid()
+	assert.Equal(t, "806", pid)
+	assert.Equal(t, "53", tid)
+
+	entry, err = Parse("1632738690\thost\t806\tsvc\tcomp\tinfo\tmessage\twith\ttabs")
+	require.Nil(t, err)
+	assert.Equal(t, "message\twith\ttabs", entry.Message)
+	pid, tid = entry.PidAndTid()
+	assert.Equal(t, "806", pid)
+	assert.Equal(t, "", tid)
+
+	_, err = Parse("1632738690\thost\t806\tsvc\tcomp\tinfo")
+	assert.EqualError(t, err, `invalid number of log parts: 6: "1632738690\thost\t806\tsvc\tcomp\tinfo"`)
+	_, err = Parse("bad\thost\t806\tsvc\tcomp\tinfo\tmsg")
+	assert.IsType(t, &ParseError{}, err)
+
+	entry, err = ParseLenient("bad\thost\t806\tsvc\tcomp\tinfo\tmsg")
+	require.Nil(t, err)
+	assert.Equal(t, "bad", entry.Timestamp)
+	assert.True(t, entry.Time.IsZero())
+	assert.Equal(t, "msg", entry.Message)
+	_, err = ParseLenient("bad\thost\t806\tsvc\tcomp\tinfo")
+	assert.IsType(t, &ParseError{}, err)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	ts, err := ParseTimestamp("1700000000.000001")
+	require.Nil(t, err)
+	assert.Equal(t, int64(1700000000000001000), ts.UnixNano())
+	ts, err = ParseTimestamp("1700000000")
+	require.Nil(t, err)
+	assert.Equal(t, int64(1700000000), ts.Unix())
+	for _, bad := range []string{"1700000000.-1", "1700000000.+1", "now", ""} {
+		_, err = ParseTimestamp(bad)
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestUnquoteMessage(t *testing.T) {
+	assert.Equal(t, "a\nb\tc\\d", unquoteMessage(`a\nb\tc\\d`))
+	assert.Equal(t, "caf\xc3\xa9 \\q", unquoteMessage(`caf\xc3\xa9 \q`))
+	assert.Equal(t, "trailing\\", unquoteMessage(`trailing\`))
+}
+
+func TestScanner(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("1.0\th\t1\ts\tc\tinfo\tfirst\nnot a log line\n2.0\th\t1\ts\tc\tinfo\tsecond\n"))
+	entry, err := scanner.Next()
+	require.Nil(t, err)
+	assert.Equal(t, "first", entry.Message)
+	_, err = scanner.Next()
+	assert.IsType(t, &ParseError{}, err)
+	entry, err = scanner.Next()
+	require.Nil(t, err)
+	assert.Equal(t, "second", entry.Message)
+	_, err = scanner.Next()
+	assert.Equal(t, io.EOF, err)
+
+	entries, err := ReadAll(strings.NewReader("1.0\th\t1\ts\tc\tinfo\tfirst\n2.0\th\t1\ts\tc\tinfo\tsecond\n"))
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	_, err = ReadAll(strings.NewReader("1.0\th\t1\ts\tc\tinfo\tfirst\nnot a log line\n"))
+	assert.NotNil(t, err)
+}
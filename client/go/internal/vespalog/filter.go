@@ -0,0 +1,64 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+package vespalog
+
+import (
+	"regexp"
+	"time"
+)
+
+// Filter selects entries. The zero value selects everything.
+type Filter struct {
+	From         time.Time       // if set, only entries at or after this time, or without a time
+	To           time.Time       // if set, only entries before this time, or without a time
+	Levels       map[string]bool // if set, only entries with levels mapped to true
+	Host         string
+	Pid          string
+	Service      string
+	OnlyInternal bool
+	Component    *regexp.Regexp
+	Message      *regexp.Regexp
+
+	// called for levels missing in Levels; such entries are selected
+	UnknownLevel func(level string)
+}
+
+// Match returns whether the entry is selected by the filter.
+func (f *Filter) Match(e *Entry) bool {
+	if !e.Time.IsZero() {
+		if !f.From.IsZero() && e.Time.Before(f.From) {
+			return false
+		}
+		if !f.To.IsZero() && !e.Time.Before(f.To) {
+			return false
+		}
+	}
+	if f.Levels != nil {
+		show, known := f.Levels[e.Level]
+		if !known && f.UnknownLevel != nil {
+			f.UnknownLevel(e.Level)
+		}
+		if known && !show {
+			return false
+		}
+	}
+	if f.Host != "" && f.Host != e.Host {
+		return false
+	}
+	if f.Pid != "" && f.Pid != e.Pid {
+		return false
+	}
+	if f.Service != "" && f.Service != e.Service {
+		return false
+	}
+	if f.OnlyInternal && !IsInternal(e.Component) {
+		return false
+	}
+	if f.Component != nil && f.Component.FindStringIndex(e.Component) == nil {
+		return false
+	}
+	if f.Message != nil && f.Message.FindStringIndex(e.Message) == nil {
+		return false
+	}
+	return true
+}
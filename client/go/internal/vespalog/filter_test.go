@@ -0,0 +1,54 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespalog
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	entry, err := Parse("1700000010.5\thost1\t42/7\tcontainer\tContainer.com.example.Handler\twarning\tRequest failed")
+	require.Nil(t, err)
+	assert.True(t, (&Filter{}).Match(entry))
+
+	tests := []struct {
+		filter   Filter
+		expected bool
+	}{
+		{Filter{From: time.Unix(1700000010, 0)}, true},
+		{Filter{From: time.Unix(1700000011, 0)}, false},
+		{Filter{To: time.Unix(1700000011, 0)}, true},
+		{Filter{To: time.Unix(1700000010, 500000000)}, false},
+		{Filter{Levels: map[string]bool{"warning": true}}, true},
+		{Filter{Levels: map[string]bool{"warning": false}}, false},
+		{Filter{Levels: map[string]bool{"info": true}}, true},
+		{Filter{Host: "host1"}, true},
+		{Filter{Host: "host2"}, false},
+		{Filter{Pid: "42/7"}, true},
+		{Filter{Pid: "42"}, false},
+		{Filter{Service: "container"}, true},
+		{Filter{Service: "searchnode"}, false},
+		{Filter{OnlyInternal: true}, false},
+		{Filter{Component: regexp.MustCompile(`example`)}, true},
+		{Filter{Component: regexp.MustCompile(`^example`)}, false},
+		{Filter{Message: regexp.MustCompile(`fail`)}, true},
+		{Filter{Message: regexp.MustCompile(`timeout`)}, false},
+	}
+	for i, tt := range tests {
+		assert.Equal(t, tt.expected, tt.filter.Match(entry), "test %d", i)
+	}
+
+	untimed, err := ParseLenient("bad\thost1\t42/7\tcontainer\tContainer.com.example.Handler\twarning\tRequest failed")
+	require.Nil(t, err)
+	assert.True(t, (&Filter{From: time.Unix(1700000011, 0), To: time.Unix(1700000012, 0)}).Match(untimed))
+	assert.False(t, (&Filter{From: time.Unix(1700000011, 0), Host: "host2"}).Match(untimed))
+
+	var unknown []string
+	filter := Filter{Levels: map[string]bool{"info": true}, UnknownLevel: func(level string) { unknown = append(unknown, level) }}
+	assert.True(t, filter.Match(entry))
+	assert.Equal(t, []string{"warning"}, unknown)
+}
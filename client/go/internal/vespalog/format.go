@@ -0,0 +1,322 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+package vespalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is an output format for entries. It can be used as a CLI flag.
+type Format int
+
+const (
+	FormatVespa Format = iota // default is vespa
+	FormatRaw
+	FormatJSON // not stable, will change in the future
+	FormatJSONV1
+	FormatLogfmt
+	FormatCSV
+	FormatOTLP
+)
+
+var formatNames = []string{
+	"vespa",
+	"raw",
+	"json",
+	"json-v1",
+	"logfmt",
+	"csv",
+	"otlp",
+}
+
+// FormatNames returns the names of all formats.
+func FormatNames() []string {
+	return append([]string(nil), formatNames...)
+}
+
+func (v *Format) Type() string {
+	return "output format"
+}
+
+func (v *Format) String() string {
+	return formatNames[*v]
+}
+
+func (v *Format) Set(val string) error {
+	for i, name := range formatNames {
+		if strings.ToLower(val) == name {
+			*v = Format(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("'%s' is not a valid format argument", val)
+}
+
+// schema version of FormatJSONV1; unlike FormatJSON it is stable,
+// and changes must bump the version
+const jsonSchemaVersion = 1
+
+// DefaultShowFields returns the fields shown by default in FormatVespa.
+func DefaultShowFields() map[string]bool {
+	return map[string]bool{
+		"time":      true,
+		"fmttime":   true,
+		"msecs":     true,
+		"usecs":     false,
+		"host":      false,
+		"level":     true,
+		"pid":       false,
+		"service":   true,
+		"component": true,
+		"message":   true,
+	}
+}
+
+// Formatter renders entries in one of the formats.
+type Formatter struct {
+	Format            Format
+	Show              map[string]bool // fields shown in FormatVespa; DefaultShowFields if nil
+	TruncateService   bool
+	TruncateComponent bool
+	DequoteNewlines   bool
+	// Plain renders FormatVespa as shown by vespa log: levels as written, and escaped newlines and tabs in messages
+	// replaced by the characters themselves when DequoteNewlines is set, without indenting continuation lines.
+	Plain    bool
+	Location *time.Location // for formatted times in FormatVespa and FormatJSON; local time if nil
+}
+
+// Header returns what should be written before the first entry, if anything.
+func (f *Formatter) Header() string {
+	if f.Format == FormatCSV {
+		return csvLine(csvHeader)
+	}
+	return ""
+}
+
+// Render returns the entry in the selected format, ending with a newline.
+func (f *Formatter) Render(e *Entry) (string, error) {
+	switch f.Format {
+	case FormatRaw:
+		return e.Line + "\n", nil
+	case FormatJSON:
+		return f.renderJson(e)
+	case FormatJSONV1:
+		return renderJsonV1(e)
+	case FormatLogfmt:
+		return renderLogfmt(e), nil
+	case FormatCSV:
+		return renderCsv(e), nil
+	case FormatOTLP:
+		return renderOtlp(e)
+	case FormatVespa:
+		fallthrough
+	default:
+		return f.renderVespa(e), nil
+	}
+}
+
+func (f *Formatter) location() *time.Location {
+	if f.Location == nil {
+		return time.Local
+	}
+	return f.Location
+}
+
+func (f *Formatter) showField(field string) bool {
+	if f.Show == nil {
+		return DefaultShowFields()[field]
+	}
+	return f.Show[field]
+}
+
+type entryJson struct {
+	Timestamp string   `json:"timestamp"`
+	Host      string   `json:"host"`
+	Pid       string   `json:"pid"`
+	Service   string   `json:"service"`
+	Component string   `json:"component"`
+	Level     string   `json:"level"`
+	Messages  []string `json:"messages"`
+}
+
+func (f *Formatter) renderJson(e *Entry) (string, error) {
+	outputFields := entryJson{
+		Timestamp: e.Time.In(f.location()).Format(time.RFC3339Nano),
+		Host:      e.Host,
+		Pid:       e.Pid,
+		Service:   e.Service,
+		Component: e.Component,
+		Level:     e.Level,
+		Messages:  []string{e.Message},
+	}
+	buf := bytes.Buffer{}
+	if err := json.NewEncoder(&buf).Encode(&outputFields); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (f *Formatter) renderVespa(e *Entry) string {
+	var buf strings.Builder
+
+	if f.showField("fmttime") {
+		timestamp := e.Time.In(f.location())
+		if f.showField("usecs") {
+			buf.WriteString(timestamp.Format("[2006-01-02 15:04:05.000000] "))
+		} else if f.showField("msecs") {
+			buf.WriteString(timestamp.Format("[2006-01-02 15:04:05.000] "))
+		} else {
+			buf.WriteString(timestamp.Format("[2006-01-02 15:04:05] "))
+		}
+	} else if f.showField("time") {
+		buf.WriteString(e.Timestamp)
+		buf.WriteString(" ")
+	}
+	if f.showField("host") {
+		buf.WriteString(fmt.Sprintf("%-8s ", e.Host))
+	}
+	if f.showField("level") {
+		level := e.Level
+		if !f.Plain {
+			level = strings.ToUpper(level)
+		}
+		buf.WriteString(fmt.Sprintf("%-7s ", level))
+	}
+	if f.showField("pid") {
+		buf.WriteString(fmt.Sprintf("%6s ", e.Pid))
+	}
+	if f.showField("service") {
+		if f.TruncateService {
+			buf.WriteString(fmt.Sprintf("%-9.9s ", e.Service))
+		} else {
+			buf.WriteString(fmt.Sprintf("%-16s ", e.Service))
+		}
+	}
+	if f.showField("component") {
+		if f.TruncateComponent {
+			buf.WriteString(fmt.Sprintf("%-15.15s ", e.Component))
+		} else {
+			buf.WriteString(fmt.Sprintf("%s\t", e.Component))
+		}
+	}
+	if f.showField("message") {
+		message := e.Message
+		if f.Plain {
+			if f.DequoteNewlines {
+				message = plainDequoter.Replace(message)
+			}
+			buf.WriteString(message)
+		} else {
+			f.writeIndentedMessage(&buf, message)
+		}
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+var plainDequoter = strings.NewReplacer("\\n", "\n", "\\t", "\t")
+
+// writeIndentedMessage writes message as vespa-logfmt does, with continuation lines indented by a tab.
+func (f *Formatter) writeIndentedMessage(buf *strings.Builder, message string) {
+	if f.DequoteNewlines {
+		message = strings.ReplaceAll(message, "\\n\\t", "\n\t")
+		message = strings.ReplaceAll(message, "\\n", "\n\t")
+	}
+	if strings.Contains(message, "\n") {
+		buf.WriteString("\n\t")
+	}
+	buf.WriteString(message)
+}
+
+type entryJsonV1 struct {
+	Version   int     `json:"version"`
+	Time      string  `json:"time"`
+	Timestamp float64 `json:"timestamp"`
+	Host      string  `json:"host"`
+	Pid       int     `json:"pid"`
+	Tid       int     `json:"tid,omitempty"`
+	Service   string  `json:"service"`
+	Component string  `json:"component"`
+	Level     string  `json:"level"`
+	Message   string  `json:"message"`
+}
+
+func renderJsonV1(e *Entry) (string, error) {
+	secs, err := strconv.ParseFloat(e.Timestamp, 64)
+	if err != nil {
+		return "", err
+	}
+	pid, tid := e.PidAndTid()
+	record := entryJsonV1{
+		Version:   jsonSchemaVersion,
+		Time:      e.Time.UTC().Format(time.RFC3339Nano),
+		Timestamp: secs,
+		Host:      e.Host,
+		Service:   e.Service,
+		Component: e.Component,
+		Level:     e.Level,
+		Message:   e.UnquotedMessage(),
+	}
+	record.Pid, _ = strconv.Atoi(pid)
+	record.Tid, _ = strconv.Atoi(tid)
+	buf := bytes.Buffer{}
+	if err := json.NewEncoder(&buf).Encode(&record); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// quote a value for logfmt output if needed
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\\") || strings.ContainsFunc(value, func(r rune) bool { return r < ' ' }) {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func renderLogfmt(e *Entry) string {
+	pid, tid := e.PidAndTid()
+	var buf strings.Builder
+	buf.WriteString("time=" + e.Time.UTC().Format(time.RFC3339Nano))
+	buf.WriteString(" host=" + logfmtValue(e.Host))
+	buf.WriteString(" pid=" + logfmtValue(pid))
+	if tid != "" {
+		buf.WriteString(" tid=" + logfmtValue(tid))
+	}
+	buf.WriteString(" service=" + logfmtValue(e.Service))
+	buf.WriteString(" component=" + logfmtValue(e.Component))
+	buf.WriteString(" level=" + logfmtValue(e.Level))
+	buf.WriteString(" msg=" + logfmtValue(e.UnquotedMessage()))
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+var csvHeader = []string{"time", "host", "pid", "tid", "service", "component", "level", "message"}
+
+func csvLine(record []string) string {
+	var buf strings.Builder
+	w := csv.NewWriter(&buf)
+	w.Write(record)
+	w.Flush()
+	return buf.String()
+}
+
+func renderCsv(e *Entry) string {
+	pid, tid := e.PidAndTid()
+	return csvLine([]string{
+		e.Time.UTC().Format(time.RFC3339Nano),
+		e.Host,
+		pid,
+		tid,
+		e.Service,
+		e.Component,
+		e.Level,
+		e.UnquotedMessage(),
+	})
+}
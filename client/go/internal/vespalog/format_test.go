@@ -0,0 +1,151 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+package vespalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const formatTestLine = "1700000000.250000\thost1.example.com\t1234/5678\tcontainer\tContainer.com.example.Handler\twarning\tRequest failed:\\n\\tcaused by \"timeout\""
+
+func formatTestOutput(t *testing.T, format Format) string {
+	entry, err := Parse(formatTestLine)
+	require.Nil(t, err)
+	formatter := Formatter{Format: format, Location: time.UTC}
+	out, err := formatter.Render(entry)
+	require.Nil(t, err)
+	return out
+}
+
+func TestFormatFlag(t *testing.T) {
+	tests := []struct {
+		expected Format
+		arg      string
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{FormatVespa, "vespa", assert.NoError},
+		{FormatRaw, "raw", assert.NoError},
+		{FormatJSON, "json", assert.NoError},
+		{FormatJSONV1, "json-v1", assert.NoError},
+		{FormatLogfmt, "logfmt", assert.NoError},
+		{FormatCSV, "CSV", assert.NoError},
+		{FormatOTLP, "otlp", assert.NoError},
+		{-1, "foo", assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			var v Format = -1
+			tt.wantErr(t, v.Set(tt.arg), fmt.Sprintf("Set(%v)", tt.arg))
+			assert.Equal(t, v, tt.expected)
+		})
+	}
+}
+
+func TestFormatVespa(t *testing.T) {
+	assert.Equal(t,
+		"[2023-11-14 22:13:20.250] WARNING container        Container.com.example.Handler\tRequest failed:\\n\\tcaused by \"timeout\"\n",
+		formatTestOutput(t, FormatVespa))
+
+	entry, err := Parse(formatTestLine)
+	require.Nil(t, err)
+	show := DefaultShowFields()
+	show["usecs"] = true
+	show["host"] = true
+	show["pid"] = true
+	formatter := Formatter{Show: show, DequoteNewlines: true, TruncateService: true, TruncateComponent: true, Location: time.UTC}
+	out, err := formatter.Render(entry)
+	require.Nil(t, err)
+	assert.Equal(t,
+		"[2023-11-14 22:13:20.250000] host1.example.com WARNING 1234/5678 container Container.com.e \n\tRequest failed:\n\tcaused by \"timeout\"\n",
+		out)
+}
+
+func TestParseLogEntry(t *testing.T) {
+	show := DefaultShowFields()
+	show["usecs"] = true
+	show["host"] = true
+	plain := Formatter{Show: show, Plain: true, Location: time.UTC}
+	in := "1632738690.905535	host1a.dev.aws-us-east-1c	806/53	logserver-container	Container.com.yahoo.container.jdisc.ConfiguredApplication	info	Switching to the latest deployed set of configurations and components. Application config generation: 52532"
+	logEntry, err := Parse(in)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2021, 9, 27, 10, 31, 30, 905535000, time.UTC), logEntry.Time.UTC())
+	assert.Equal(t, "host1a.dev.aws-us-east-1c", logEntry.Host)
+	assert.Equal(t, "logserver-container", logEntry.Service)
+	assert.Equal(t, "Container.com.yahoo.container.jdisc.ConfiguredApplication", logEntry.Component)
+	assert.Equal(t, "info", logEntry.Level)
+	assert.Equal(t, "Switching to the latest deployed set of configurations and components. Application config generation: 52532", logEntry.Message)
+
+	formatted := "[2021-09-27 10:31:30.905535] host1a.dev.aws-us-east-1c info    logserver-container Container.com.yahoo.container.jdisc.ConfiguredApplication\tSwitching to the latest deployed set of configurations and components. Application config generation: 52532\n"
+	rendered, err := plain.Render(logEntry)
+	assert.Nil(t, err)
+	assert.Equal(t, formatted, rendered)
+
+	in = "1632738690.905535	host1a.dev.aws-us-east-1c	806/53	logserver-container	Container.com.yahoo.container.jdisc.ConfiguredApplication	info	message containing newline\\nand\\ttab"
+	logEntry, err = Parse(in)
+	assert.Nil(t, err)
+	plain.DequoteNewlines = true
+	rendered, err = plain.Render(logEntry)
+	assert.Nil(t, err)
+	assert.Equal(t, "[2021-09-27 10:31:30.905535] host1a.dev.aws-us-east-1c info    logserver-container Container.com.yahoo.container.jdisc.ConfiguredApplication\tmessage containing newline\nand\ttab\n", rendered)
+}
+
+func TestFormatRawAndJson(t *testing.T) {
+	assert.Equal(t, formatTestLine+"\n", formatTestOutput(t, FormatRaw))
+	assert.Equal(t,
+		`{"timestamp":"2023-11-14T22:13:20.25Z","host":"host1.example.com","pid":"1234/5678","service":"container",`+
+			`"component":"Container.com.example.Handler","level":"warning","messages":["Request failed:\\n\\tcaused by \"timeout\""]}`+"\n",
+		formatTestOutput(t, FormatJSON))
+}
+
+func TestFormatJsonV1(t *testing.T) {
+	assert.Equal(t,
+		`{"version":1,"time":"2023-11-14T22:13:20.25Z","timestamp":1700000000.25,"host":"host1.example.com","pid":1234,"tid":5678,`+
+			`"service":"container","component":"Container.com.example.Handler","level":"warning","message":"Request failed:\n\tcaused by \"timeout\""}`+"\n",
+		formatTestOutput(t, FormatJSONV1))
+}
+
+func TestFormatLogfmt(t *testing.T) {
+	assert.Equal(t,
+		`time=2023-11-14T22:13:20.25Z host=host1.example.com pid=1234 tid=5678 service=container component=Container.com.example.Handler level=warning msg="Request failed:\n\tcaused by \"timeout\""`+"\n",
+		formatTestOutput(t, FormatLogfmt))
+}
+
+func TestFormatCsv(t *testing.T) {
+	formatter := Formatter{Format: FormatCSV}
+	assert.Equal(t, "time,host,pid,tid,service,component,level,message\n", formatter.Header())
+	assert.Equal(t,
+		"2023-11-14T22:13:20.25Z,host1.example.com,1234,5678,container,Container.com.example.Handler,warning,\"Request failed:\n\tcaused by \"\"timeout\"\"\"\n",
+		formatTestOutput(t, FormatCSV))
+}
+
+func TestFormatOtlp(t *testing.T) {
+	var data map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(formatTestOutput(t, FormatOTLP)), &data))
+	resourceLogs := data["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "host.name", "value": map[string]interface{}{"stringValue": "host1.example.com"}},
+		map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "container"}},
+		map[string]interface{}{"key": "process.pid", "value": map[string]interface{}{"intValue": "1234"}},
+	}, resourceLogs["resource"].(map[string]interface{})["attributes"])
+	scopeLogs := resourceLogs["scopeLogs"].([]interface{})[0].(map[string]interface{})
+	record := scopeLogs["logRecords"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "1700000000250000000", record["timeUnixNano"])
+	assert.Equal(t, 13.0, record["severityNumber"])
+	assert.Equal(t, "WARN", record["severityText"])
+	assert.Equal(t, "Request failed:\n\tcaused by \"timeout\"", record["body"].(map[string]interface{})["stringValue"])
+}
+
+func TestOtlpSeverity(t *testing.T) {
+	for _, level := range []string{"fatal", "error", "warning", "info", "config", "event", "debug", "spam"} {
+		number, text := otlpSeverity(level)
+		assert.True(t, number >= 1 && number <= 24, level)
+		assert.NotEmpty(t, text)
+	}
+	number, _ := otlpSeverity("unknown")
+	assert.Equal(t, 0, number)
+}
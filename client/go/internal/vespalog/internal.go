@@ -1,16 +1,14 @@
 // Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
-// vespa logfmt command
 // Author: arnej
 
-package logfmt
+package vespalog
 
 import (
 	"strings"
 )
 
-// is componentName a vespa-internal name?
-
-func isInternal(componentName string) bool {
+// IsInternal returns whether componentName is a vespa-internal name.
+func IsInternal(componentName string) bool {
 	cs := strings.Split(componentName, ".")
 	if len(cs) == 0 || cs[0] != "Container" {
 		return true
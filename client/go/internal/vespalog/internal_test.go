@@ -1,5 +1,5 @@
 // Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
-package logfmt
+package vespalog
 
 import (
 	"bufio"
@@ -7,7 +7,7 @@ import (
 	"testing"
 )
 
-// tests: func isInternal(componentName string) bool
+// tests: func IsInternal(componentName string) bool
 
 func TestIsInternal(t *testing.T) {
 	f, err := os.Open("internal_names.txt")
@@ -16,7 +16,7 @@ func TestIsInternal(t *testing.T) {
 	}
 	defer f.Close()
 	for input := bufio.NewScanner(f); input.Scan(); {
-		if name := input.Text(); !isInternal(name) {
+		if name := input.Text(); !IsInternal(name) {
 			t.Logf("name '%s' should be internal but was not recognized", name)
 			t.Fail()
 		}
@@ -27,7 +27,7 @@ func TestIsInternal(t *testing.T) {
 	}
 	defer f.Close()
 	for input := bufio.NewScanner(f); input.Scan(); {
-		if name := input.Text(); isInternal(name) {
+		if name := input.Text(); IsInternal(name) {
 			t.Logf("name '%s' should not be internal but was recognized", name)
 			t.Fail()
 		}
@@ -0,0 +1,117 @@
+// Copyright Vespa.ai. Licensed under the terms of the Apache 2.0 license. See LICENSE in the project root.
+
+package vespalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// OpenTelemetry severity numbers and texts for vespa.log levels
+func otlpSeverity(level string) (int, string) {
+	switch level {
+	case "fatal":
+		return 21, "FATAL"
+	case "error":
+		return 17, "ERROR"
+	case "warning":
+		return 13, "WARN"
+	case "info":
+		return 9, "INFO"
+	case "config":
+		return 10, "INFO2"
+	case "event":
+		return 11, "INFO3"
+	case "debug":
+		return 5, "DEBUG"
+	case "spam":
+		return 1, "TRACE"
+	}
+	return 0, ""
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+// OTLP JSON encodes 64-bit integers as strings
+func otlpInt(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &value}}
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string          `json:"timeUnixNano"`
+	ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+	SeverityNumber       int             `json:"severityNumber,omitempty"`
+	SeverityText         string          `json:"severityText"`
+	Body                 otlpValue       `json:"body"`
+	Attributes           []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpLogsData struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+// one OTLP/JSON ExportLogsServiceRequest per entry
+func renderOtlp(e *Entry) (string, error) {
+	severityNumber, severityText := otlpSeverity(e.Level)
+	if severityText == "" {
+		severityText = strings.ToUpper(e.Level)
+	}
+	pid, tid := e.PidAndTid()
+	nanos := strconv.FormatInt(e.Time.UnixNano(), 10)
+	message := e.UnquotedMessage()
+	record := otlpLogRecord{
+		TimeUnixNano:         nanos,
+		ObservedTimeUnixNano: nanos,
+		SeverityNumber:       severityNumber,
+		SeverityText:         severityText,
+		Body:                 otlpValue{StringValue: &message},
+		Attributes:           []otlpAttribute{otlpString("vespa.component", e.Component)},
+	}
+	if _, err := strconv.Atoi(tid); err == nil {
+		record.Attributes = append(record.Attributes, otlpInt("thread.id", tid))
+	}
+	resource := otlpResourceLogs{}
+	resource.Resource.Attributes = []otlpAttribute{
+		otlpString("host.name", e.Host),
+		otlpString("service.name", e.Service),
+	}
+	if _, err := strconv.Atoi(pid); err == nil {
+		resource.Resource.Attributes = append(resource.Resource.Attributes, otlpInt("process.pid", pid))
+	}
+	scope := otlpScopeLogs{LogRecords: []otlpLogRecord{record}}
+	scope.Scope.Name = e.Component
+	resource.ScopeLogs = []otlpScopeLogs{scope}
+	buf := bytes.Buffer{}
+	if err := json.NewEncoder(&buf).Encode(&otlpLogsData{ResourceLogs: []otlpResourceLogs{resource}}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}